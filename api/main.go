@@ -17,44 +17,19 @@ limitations under the License.
 package main
 
 import (
-	"api/src/config"
-	"api/src/prommetrics"
-	"api/src/router"
-	"fmt"
+	"api/src/commands"
+	"context"
 	"log"
-	"net"
-	"net/http"
-	"time"
-
-	"github.com/prometheus/client_golang/prometheus"
+	"os"
+	"os/signal"
+	"syscall"
 )
 
 func main() {
-	r := router.Generate()
-	config.Load()
-
-	prommetrics.Load()
-	for _, metric := range prommetrics.Metrics {
-		prometheus.MustRegister(metric)
-	}
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGTERM, syscall.SIGINT, syscall.SIGQUIT)
+	defer stop()
 
-	s := &http.Server{
-		Addr:         fmt.Sprintf(":%d", config.APIPort),
-		Handler:      r,
-		ReadTimeout:  10 * time.Second,
-		WriteTimeout: 30 * time.Second,
-		ConnState: func(c net.Conn, s http.ConnState) {
-			switch s {
-			case http.StateNew:
-				prommetrics.PromOpenedConnections.Inc()
-			case http.StateHijacked:
-				prommetrics.PromOpenedConnections.Dec()
-			case http.StateClosed:
-				prommetrics.PromOpenedConnections.Dec()
-			}
-		},
+	if erro := commands.Run(ctx, os.Args[1:]); erro != nil {
+		log.Fatal(erro)
 	}
-
-	fmt.Printf("Serving on Port %d\n", config.APIPort)
-	log.Fatal(s.ListenAndServe())
 }
@@ -0,0 +1,332 @@
+/*
+Copyright 2022 Danilo S. Lopes.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at:
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package app
+
+import (
+	"api/src/certificates"
+	"api/src/compression"
+	"api/src/config"
+	"api/src/controllers"
+	"api/src/database"
+	"api/src/logging"
+	"api/src/migrations"
+	"api/src/prommetrics"
+	"api/src/ratelimit"
+	"api/src/repositories"
+	"api/src/router"
+	"api/src/router/routes"
+	"api/src/swagger"
+	"api/src/tracing"
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net"
+	"net/http"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/gorilla/mux"
+	"github.com/prometheus/client_golang/prometheus"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// How often the expired idempotency keys are dropped, they are ignored once expired anyway
+const idempotencyPurgeInterval = 10 * time.Minute
+
+// App owns everything one API instance needs to serve requests, so several
+// isolated instances can live in the same process
+type App struct {
+	Config   config.Config
+	DB       *database.DB
+	Store    repositories.Store
+	Registry *prometheus.Registry
+	Metrics  *prommetrics.Metrics
+	Logger   *slog.Logger
+	Tracing  *tracing.Tracing
+	Router   *mux.Router
+
+	controller *controllers.Controller
+
+	// serves the TLS certificates, nil when the API serves plain HTTP
+	certificates *certificates.Reloader
+
+	// background workers bound to the App lifetime
+	workers     sync.WaitGroup
+	workersCtx  context.Context
+	stopWorkers context.CancelFunc
+
+	closeOnce sync.Once
+	closeErro error
+}
+
+// New wires an App from the given configuration
+func New(cfg config.Config) (*App, error) {
+	if erro := cfg.Validate(); erro != nil {
+		return nil, fmt.Errorf("invalid configuration: %w", erro)
+	}
+
+	app := &App{
+		Config:   cfg,
+		Registry: prommetrics.NewRegistry(),
+		Logger:   logging.New(os.Stderr, cfg.Log),
+	}
+	app.Metrics = prommetrics.New(app.Registry)
+	app.workersCtx, app.stopWorkers = context.WithCancel(context.Background())
+
+	var erro error
+	if app.Tracing, erro = tracing.New(cfg.Tracing); erro != nil {
+		return nil, erro
+	}
+
+	if erro := app.openStore(); erro != nil {
+		app.Tracing.Shutdown(context.Background())
+		return nil, erro
+	}
+
+	var validator *swagger.Validator
+	if cfg.OpenAPIValidation != "off" {
+		if validator, erro = swagger.NewValidator(); erro != nil {
+			app.Close()
+			return nil, fmt.Errorf("loading openapi.json: %w", erro)
+		}
+	}
+
+	rateLimitStore, erro := ratelimit.New(cfg.RateLimit)
+	if erro != nil {
+		app.Close()
+		return nil, erro
+	}
+
+	compressor, erro := compression.New(cfg.Compression)
+	if erro != nil {
+		app.Close()
+		return nil, erro
+	}
+
+	if cfg.TLS.Enabled() {
+		if app.certificates, erro = certificates.New(cfg.TLS, app.Logger, app.Metrics); erro != nil {
+			app.Close()
+			return nil, erro
+		}
+	}
+
+	app.controller = controllers.New(cfg, app.Store, app.Metrics, app.Logger)
+	app.Router = router.Generate(routes.Options{
+		Controller: app.controller,
+		Logger:     app.Logger,
+		Metrics:    app.Metrics,
+		Gatherer:   app.Registry,
+		SecretKey:  cfg.SecretKey,
+		Tracer:     app.Tracing.Tracer(),
+
+		QueryTimeout: cfg.Database.QueryTimeoutFor,
+		BodyMaxSize:  cfg.Body.MaxSizeFor,
+
+		Users:          app.Store.Users,
+		Idempotency:    app.Store.Idempotency,
+		IdempotencyTTL: cfg.IdempotencyTTL,
+
+		Validator:         validator,
+		ValidateResponses: cfg.OpenAPIValidation == "full",
+
+		RateLimitStore: rateLimitStore,
+		RateLimit:      cfg.RateLimit,
+
+		CORS:         cfg.CORS,
+		Compressor:   compressor,
+		LegacyRoutes: cfg.LegacyRoutes,
+	})
+
+	return app, nil
+}
+
+// openStore creates the repositories store selected by the DB_DRIVER variable
+func (app *App) openStore() error {
+	store, db, erro := OpenStore(app.Config.Database, app.Logger, app.Tracing.Tracer())
+	if erro != nil {
+		return erro
+	}
+
+	if db != nil {
+		prommetrics.RegisterDatabase(app.Registry, db.DB)
+	}
+	app.DB, app.Store = db, store
+
+	return nil
+}
+
+// OpenStore opens the store of the configured driver, the database being nil with the memory driver
+func OpenStore(settings config.Database, logger *slog.Logger, tracer trace.Tracer) (repositories.Store, *database.DB, error) {
+	switch settings.Driver {
+	case "memory":
+		return repositories.NewMemoryStore(), nil, nil
+	case "mysql", "sqlite", "postgres":
+		db, erro := database.Connect(settings, logger, tracer)
+		if erro != nil {
+			return repositories.Store{}, nil, erro
+		}
+
+		if erro := checkSchema(db, settings); erro != nil {
+			db.Close()
+			return repositories.Store{}, nil, erro
+		}
+
+		return repositories.NewSQLStore(db), db, nil
+	default:
+		return repositories.Store{}, nil, fmt.Errorf("unknown database driver %q", settings.Driver)
+	}
+}
+
+// checkSchema refuses to serve against a database that misses migrations, unless DB_SKIP_SCHEMA_CHECK is set
+func checkSchema(db *database.DB, settings config.Database) error {
+	if settings.SkipSchemaCheck {
+		return nil
+	}
+
+	migrator, erro := migrations.New(db)
+	if erro != nil {
+		return erro
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	return migrator.Check(ctx)
+}
+
+// Server returns the http server that serves the App routes, over TLS when certificates are configured
+func (app *App) Server() *http.Server {
+	server := &http.Server{
+		Addr:         fmt.Sprintf(":%d", app.Config.APIPort),
+		Handler:      app.Router,
+		ReadTimeout:  10 * time.Second,
+		WriteTimeout: 30 * time.Second,
+		ErrorLog:     slog.NewLogLogger(app.Logger.Handler(), slog.LevelWarn),
+		ConnState: func(c net.Conn, s http.ConnState) {
+			switch s {
+			case http.StateNew:
+				app.Metrics.OpenedConnections.Inc()
+			case http.StateHijacked:
+				app.Metrics.OpenedConnections.Dec()
+			case http.StateClosed:
+				app.Metrics.OpenedConnections.Dec()
+			}
+		},
+	}
+
+	if app.certificates != nil {
+		server.TLSConfig = app.certificates.Config()
+	}
+
+	return server
+}
+
+// Go runs worker in background until the App is closed. The worker must return once ctx is done.
+func (app *App) Go(worker func(ctx context.Context)) {
+	app.workers.Add(1)
+	go func() {
+		defer app.workers.Done()
+		worker(app.workersCtx)
+	}()
+}
+
+// Run serves the API until ctx is done, then drains the in flight requests and closes the App
+func (app *App) Run(ctx context.Context) error {
+	server := app.Server()
+
+	if app.certificates != nil {
+		app.Go(app.certificates.Watch)
+	}
+	app.Go(app.purgeIdempotencyKeys)
+
+	serveErro := make(chan error, 1)
+	go func() {
+		app.Logger.Info("serving", "port", app.Config.APIPort, "tls", app.certificates != nil)
+		if app.certificates != nil {
+			// the certificates come from the TLS configuration
+			serveErro <- server.ListenAndServeTLS("", "")
+			return
+		}
+		serveErro <- server.ListenAndServe()
+	}()
+
+	select {
+	case erro := <-serveErro:
+		app.Close()
+		return erro
+	case <-ctx.Done():
+	}
+
+	app.Logger.Info("shutting down, draining the requests", "timeout", app.Config.ShutdownTimeout.String())
+	app.controller.StartDraining()
+	time.Sleep(app.Config.ShutdownDelay)
+
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), app.Config.ShutdownTimeout)
+	defer cancel()
+
+	shutdownErro := server.Shutdown(shutdownCtx)
+	if erro := <-serveErro; erro != nil && !errors.Is(erro, http.ErrServerClosed) && shutdownErro == nil {
+		shutdownErro = erro
+	}
+
+	if erro := app.Close(); erro != nil && shutdownErro == nil {
+		shutdownErro = erro
+	}
+
+	return shutdownErro
+}
+
+// purgeIdempotencyKeys drops the expired idempotency keys every idempotencyPurgeInterval until ctx is done
+func (app *App) purgeIdempotencyKeys(ctx context.Context) {
+	ticker := time.NewTicker(idempotencyPurgeInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if erro := app.Store.Idempotency.Purge(ctx); erro != nil {
+				app.Logger.WarnContext(ctx, "purging the expired idempotency keys failed", "error", erro)
+			}
+		}
+	}
+}
+
+// Close stops the background workers, releases the database pool and flushes the pending spans.
+// It is safe to call it more than once.
+func (app *App) Close() error {
+	app.closeOnce.Do(func() {
+		app.stopWorkers()
+		app.workers.Wait()
+
+		if app.DB != nil {
+			app.closeErro = app.DB.Close()
+		}
+
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+
+		if erro := app.Tracing.Shutdown(ctx); erro != nil && app.closeErro == nil {
+			app.closeErro = erro
+		}
+	})
+
+	return app.closeErro
+}
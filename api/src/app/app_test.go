@@ -0,0 +1,128 @@
+/*
+Copyright 2022 Danilo S. Lopes.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at:
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package app
+
+import (
+	"api/src/authentication"
+	"api/src/config"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+)
+
+// newApp returns an App over an empty in-memory store, closed at the end of the test
+func newApp(t *testing.T) *App {
+	t.Helper()
+
+	app, erro := New(config.Load())
+	if erro != nil {
+		t.Fatal(erro)
+	}
+	t.Cleanup(func() { app.Close() })
+
+	return app
+}
+
+// send sends a request to app, authenticated as userID when not zero, and returns the response status
+func send(t *testing.T, app *App, method, target, body string, userID uint64) int {
+	t.Helper()
+
+	request := httptest.NewRequest(method, target, strings.NewReader(body))
+	request.Header.Set("Content-Type", "application/json")
+	if userID != 0 {
+		token, erro := authentication.GenerateToken(app.Config.SecretKey, userID)
+		if erro != nil {
+			t.Fatal(erro)
+		}
+		request.Header.Set("Authorization", "Bearer "+token)
+	}
+
+	response := httptest.NewRecorder()
+	app.Router.ServeHTTP(response, request)
+
+	return response.Code
+}
+
+// Two Apps of the same process share neither their data, nor their metrics, nor their rate limits
+func TestAppsAreIsolated(t *testing.T) {
+	t.Setenv("DB_DRIVER", "memory")
+	t.Setenv("SECRET_KEY", "secret")
+	t.Setenv("LEGACY_ROUTES_DEPRECATION", "2026-10-18")
+	t.Setenv("LOG_LEVEL", "error")
+	t.Setenv("RATE_LIMIT_ROUTES", "POST /login=1/1h")
+
+	first, second := newApp(t), newApp(t)
+
+	if status := send(t, first, http.MethodPost, "/v2/users", `{"name":"user","nick":"user","email":"user@example.com","pass":"secret"}`, 0); status != http.StatusCreated {
+		t.Fatalf("creating a user got %d, want 201", status)
+	}
+
+	if status := send(t, first, http.MethodGet, "/v2/users/1", "", 1); status != http.StatusOK {
+		t.Fatalf("reading the user from its App got %d, want 200", status)
+	}
+	if status := send(t, second, http.MethodGet, "/v2/users/1", "", 1); status != http.StatusUnauthorized {
+		t.Fatalf("authenticating as the user on another App got %d, want 401", status)
+	}
+
+	if created := testutil.ToFloat64(first.Metrics.CountCreatedUsers); created != 1 {
+		t.Fatalf("the App creating the user counted %v users, want 1", created)
+	}
+	if created := testutil.ToFloat64(second.Metrics.CountCreatedUsers); created != 0 {
+		t.Fatalf("another App counted %v users, want 0", created)
+	}
+	if first.Registry == second.Registry {
+		t.Fatal("the Apps share their metrics registry")
+	}
+
+	login := `{"email":"user@example.com","pass":"wrong"}`
+	if status := send(t, first, http.MethodPost, "/v2/login", login, 0); status != http.StatusUnauthorized {
+		t.Fatalf("the first login got %d, want 401", status)
+	}
+	if status := send(t, first, http.MethodPost, "/v2/login", login, 0); status != http.StatusTooManyRequests {
+		t.Fatalf("the second login got %d, want 429", status)
+	}
+	if status := send(t, second, http.MethodPost, "/v2/login", login, 0); status != http.StatusUnauthorized {
+		t.Fatalf("the first login on another App got %d, want 401", status)
+	}
+}
+
+// New refuses the settings that would make the API run improperly
+func TestNewValidatesConfig(t *testing.T) {
+	settings := map[string]string{
+		"CORS_ALLOW_CREDENTIALS":   "true",
+		"PAGINATION_DEFAULT_LIMIT": "0",
+		"TLS_CLIENT_AUTH":          "sometimes",
+	}
+
+	for name, value := range settings {
+		t.Run(name, func(t *testing.T) {
+			t.Setenv("DB_DRIVER", "memory")
+			t.Setenv("SECRET_KEY", "secret")
+			t.Setenv("LEGACY_ROUTES_DEPRECATION", "2026-10-18")
+			t.Setenv("CORS_ALLOWED_ORIGINS", "*")
+			t.Setenv(name, value)
+
+			if app, erro := New(config.Load()); erro == nil {
+				app.Close()
+				t.Fatalf("New accepted %s=%s", name, value)
+			}
+		})
+	}
+}
@@ -17,7 +17,6 @@ limitations under the License.
 package authentication
 
 import (
-	"api/src/config"
 	"errors"
 	"fmt"
 	"net/http"
@@ -29,19 +28,19 @@ import (
 )
 
 // GenerateToken return a assigned token with user permissions
-func GenerateToken(userID uint64) (string, error) {
+func GenerateToken(secretKey []byte, userID uint64) (string, error) {
 	permissions := jwt.MapClaims{}
 	permissions["authorized"] = true
 	permissions["exp"] = time.Now().Add(time.Hour * 6).Unix() // 6 hours
 	permissions["userID"] = userID
 	token := jwt.NewWithClaims(jwt.SigningMethodHS256, permissions)
-	return token.SignedString([]byte(config.SecretKey))
+	return token.SignedString(secretKey)
 }
 
 // ValidateToken is valid
-func ValidateToken(r *http.Request) error {
+func ValidateToken(r *http.Request, secretKey []byte) error {
 	tokenString := extractToken(r)
-	token, erro := jwt.Parse(tokenString, returnVerificationKey)
+	token, erro := jwt.Parse(tokenString, returnVerificationKey(secretKey))
 	if erro != nil {
 		return erro
 	}
@@ -54,9 +53,9 @@ func ValidateToken(r *http.Request) error {
 }
 
 //ExtractUserID extracts the UserID from the Token
-func ExtractUserID(r *http.Request) (uint64, error) {
+func ExtractUserID(r *http.Request, secretKey []byte) (uint64, error) {
 	tokenString := extractToken(r)
-	token, erro := jwt.Parse(tokenString, returnVerificationKey)
+	token, erro := jwt.Parse(tokenString, returnVerificationKey(secretKey))
 	if erro != nil {
 		return 0, erro
 	}
@@ -83,10 +82,12 @@ func extractToken(r *http.Request) string {
 	return ""
 }
 
-func returnVerificationKey(token *jwt.Token) (interface{}, error) {
-	if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
-		return nil, fmt.Errorf("sign method unexpected ! %v", token.Header["alg"])
-	}
+func returnVerificationKey(secretKey []byte) jwt.Keyfunc {
+	return func(token *jwt.Token) (interface{}, error) {
+		if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
+			return nil, fmt.Errorf("sign method unexpected ! %v", token.Header["alg"])
+		}
 
-	return config.SecretKey, nil
+		return secretKey, nil
+	}
 }
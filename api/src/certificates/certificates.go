@@ -0,0 +1,236 @@
+/*
+Copyright 2022 Danilo S. Lopes.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at:
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package certificates
+
+import (
+	"api/src/config"
+	"api/src/prommetrics"
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/pem"
+	"fmt"
+	"log/slog"
+	"os"
+	"os/signal"
+	"sync"
+	"syscall"
+	"time"
+)
+
+// Reloader serves the certificates of the TLS settings, reloading them when their files change or on
+// SIGHUP, so they are renewed without restarting the API
+type Reloader struct {
+	settings config.TLS
+	logger   *slog.Logger
+	metrics  *prommetrics.Metrics
+
+	mutex       sync.RWMutex
+	certificate *tls.Certificate
+	clientCAs   *x509.CertPool
+
+	// modification time and size of the files when they were last loaded
+	stamps map[string]string
+}
+
+// New loads the certificates of settings, failing when they are invalid
+func New(settings config.TLS, logger *slog.Logger, metrics *prommetrics.Metrics) (*Reloader, error) {
+	reloader := &Reloader{settings: settings, logger: logger, metrics: metrics}
+	if _, erro := reloader.load(); erro != nil {
+		return nil, erro
+	}
+
+	return reloader, nil
+}
+
+// Config returns the TLS configuration of the server: TLS 1.2 at least, HTTP/2 offered and the client
+// certificates verified when a client CA is given
+func (reloader *Reloader) Config() *tls.Config {
+	config := &tls.Config{
+		MinVersion:     tls.VersionTLS12,
+		NextProtos:     []string{"h2", "http/1.1"},
+		GetCertificate: reloader.getCertificate,
+	}
+
+	if reloader.settings.ClientCAFile == "" {
+		return config
+	}
+
+	config.ClientAuth = tls.RequireAndVerifyClientCert
+	if reloader.settings.ClientAuth == "verify_if_given" {
+		config.ClientAuth = tls.VerifyClientCertIfGiven
+	}
+
+	// the client CAs are read at each handshake, so a reload applies to the next connections
+	base := config.Clone()
+	config.GetConfigForClient = func(*tls.ClientHelloInfo) (*tls.Config, error) {
+		reloader.mutex.RLock()
+		defer reloader.mutex.RUnlock()
+
+		perConnection := base.Clone()
+		perConnection.ClientCAs = reloader.clientCAs
+		return perConnection, nil
+	}
+
+	return config
+}
+
+// getCertificate returns the certificate currently served
+func (reloader *Reloader) getCertificate(*tls.ClientHelloInfo) (*tls.Certificate, error) {
+	reloader.mutex.RLock()
+	defer reloader.mutex.RUnlock()
+
+	return reloader.certificate, nil
+}
+
+// Watch reloads the certificates on SIGHUP and when their files change, until ctx is done
+func (reloader *Reloader) Watch(ctx context.Context) {
+	hangup := make(chan os.Signal, 1)
+	signal.Notify(hangup, syscall.SIGHUP)
+	defer signal.Stop(hangup)
+
+	ticker := time.NewTicker(reloader.settings.ReloadInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-hangup:
+			reloader.Reload()
+		case <-ticker.C:
+			if reloader.changed() {
+				reloader.Reload()
+			}
+		}
+	}
+}
+
+// Reload loads the certificates again, still serving the previous ones when the files are invalid
+func (reloader *Reloader) Reload() {
+	certificate, erro := reloader.load()
+	if erro != nil {
+		reloader.metrics.CertificateReloads.WithLabelValues("failure").Inc()
+		reloader.logger.Error("reloading the TLS certificates failed, serving the previous ones", "error", erro.Error())
+		return
+	}
+
+	reloader.metrics.CertificateReloads.WithLabelValues("success").Inc()
+	reloader.logger.Info("TLS certificates reloaded", "expires", certificate.Leaf.NotAfter)
+}
+
+// load reads the files, replacing the certificates served only when all of them are valid, and returns
+// the server certificate loaded. The stamps are only stored then, so invalid files are retried.
+func (reloader *Reloader) load() (*tls.Certificate, error) {
+	stamps := reloader.currentStamps()
+
+	certificate, erro := tls.LoadX509KeyPair(reloader.settings.CertFile, reloader.settings.KeyFile)
+	if erro != nil {
+		return nil, fmt.Errorf("loading the TLS certificate: %w", erro)
+	}
+
+	if certificate.Leaf, erro = x509.ParseCertificate(certificate.Certificate[0]); erro != nil {
+		return nil, fmt.Errorf("parsing the TLS certificate: %w", erro)
+	}
+
+	var clientCAs *x509.CertPool
+	var clientCAsExpiry time.Time
+	if reloader.settings.ClientCAFile != "" {
+		if clientCAs, clientCAsExpiry, erro = loadPool(reloader.settings.ClientCAFile); erro != nil {
+			return nil, erro
+		}
+	}
+
+	reloader.mutex.Lock()
+	reloader.certificate = &certificate
+	reloader.clientCAs = clientCAs
+	reloader.stamps = stamps
+	reloader.mutex.Unlock()
+
+	reloader.metrics.CertificateExpiry.WithLabelValues("server").Set(float64(certificate.Leaf.NotAfter.Unix()))
+	if clientCAs != nil {
+		reloader.metrics.CertificateExpiry.WithLabelValues("client_ca").Set(float64(clientCAsExpiry.Unix()))
+	}
+
+	return &certificate, nil
+}
+
+// loadPool reads the PEM certificates of file, returning them along with when the first of them expires
+func loadPool(file string) (*x509.CertPool, time.Time, error) {
+	content, erro := os.ReadFile(file)
+	if erro != nil {
+		return nil, time.Time{}, fmt.Errorf("loading the client CAs: %w", erro)
+	}
+
+	pool := x509.NewCertPool()
+	var expiry time.Time
+
+	for block, rest := pem.Decode(content); block != nil; block, rest = pem.Decode(rest) {
+		if block.Type != "CERTIFICATE" {
+			continue
+		}
+
+		certificate, erro := x509.ParseCertificate(block.Bytes)
+		if erro != nil {
+			return nil, time.Time{}, fmt.Errorf("parsing the client CAs of %s: %w", file, erro)
+		}
+
+		pool.AddCert(certificate)
+		if expiry.IsZero() || certificate.NotAfter.Before(expiry) {
+			expiry = certificate.NotAfter
+		}
+	}
+
+	if expiry.IsZero() {
+		return nil, time.Time{}, fmt.Errorf("no certificate in %s", file)
+	}
+
+	return pool, expiry, nil
+}
+
+// changed tells whether a file was modified since the certificates were last loaded
+func (reloader *Reloader) changed() bool {
+	stamps := reloader.currentStamps()
+
+	reloader.mutex.RLock()
+	defer reloader.mutex.RUnlock()
+
+	for file, stamp := range stamps {
+		if reloader.stamps[file] != stamp {
+			return true
+		}
+	}
+
+	return false
+}
+
+// currentStamps returns the modification time and size of the files, following the symbolic links
+// so the secrets mounted by Kubernetes, swapped through a link, are noticed
+func (reloader *Reloader) currentStamps() map[string]string {
+	stamps := make(map[string]string)
+	for _, file := range []string{reloader.settings.CertFile, reloader.settings.KeyFile, reloader.settings.ClientCAFile} {
+		if file == "" {
+			continue
+		}
+
+		if info, erro := os.Stat(file); erro == nil {
+			stamps[file] = fmt.Sprintf("%d %d", info.ModTime().UnixNano(), info.Size())
+		}
+	}
+
+	return stamps
+}
@@ -0,0 +1,244 @@
+/*
+Copyright 2022 Danilo S. Lopes.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at:
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package certificates
+
+import (
+	"api/src/config"
+	"api/src/prommetrics"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"io"
+	"log/slog"
+	"math/big"
+	"net"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+)
+
+// writeCertificate writes a self-signed certificate for localhost, also usable as a CA, and its key
+// into certFile and keyFile
+func writeCertificate(t *testing.T, certFile, keyFile string, serial int64) *x509.Certificate {
+	t.Helper()
+
+	key, erro := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if erro != nil {
+		t.Fatal(erro)
+	}
+
+	template := &x509.Certificate{
+		SerialNumber:          big.NewInt(serial),
+		Subject:               pkix.Name{CommonName: "localhost"},
+		DNSNames:              []string{"localhost"},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(24 * time.Hour),
+		KeyUsage:              x509.KeyUsageDigitalSignature | x509.KeyUsageCertSign,
+		ExtKeyUsage:           []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth, x509.ExtKeyUsageClientAuth},
+		BasicConstraintsValid: true,
+		IsCA:                  true,
+	}
+
+	der, erro := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if erro != nil {
+		t.Fatal(erro)
+	}
+	keyDER, erro := x509.MarshalECPrivateKey(key)
+	if erro != nil {
+		t.Fatal(erro)
+	}
+
+	if erro := os.WriteFile(certFile, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}), 0o600); erro != nil {
+		t.Fatal(erro)
+	}
+	if erro := os.WriteFile(keyFile, pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER}), 0o600); erro != nil {
+		t.Fatal(erro)
+	}
+
+	certificate, erro := x509.ParseCertificate(der)
+	if erro != nil {
+		t.Fatal(erro)
+	}
+
+	return certificate
+}
+
+// newReloader writes a certificate into a temporary directory and loads it
+func newReloader(t *testing.T, configure func(settings *config.TLS)) (*Reloader, *prommetrics.Metrics, config.TLS) {
+	t.Helper()
+
+	dir := t.TempDir()
+	settings := config.TLS{
+		CertFile:       filepath.Join(dir, "cert.pem"),
+		KeyFile:        filepath.Join(dir, "key.pem"),
+		ReloadInterval: time.Minute,
+	}
+	writeCertificate(t, settings.CertFile, settings.KeyFile, 1)
+	configure(&settings)
+
+	metrics := prommetrics.New(prommetrics.NewRegistry())
+	reloader, erro := New(settings, slog.New(slog.NewTextHandler(io.Discard, nil)), metrics)
+	if erro != nil {
+		t.Fatal(erro)
+	}
+
+	return reloader, metrics, settings
+}
+
+// served returns the serial number of the certificate currently served
+func served(t *testing.T, reloader *Reloader) int64 {
+	t.Helper()
+
+	certificate, erro := reloader.getCertificate(nil)
+	if erro != nil {
+		t.Fatal(erro)
+	}
+
+	return certificate.Leaf.SerialNumber.Int64()
+}
+
+// The renewed files are served once noticed, an invalid one keeping the previous certificate served
+// and being retried until it is fixed
+func TestReload(t *testing.T) {
+	reloader, metrics, settings := newReloader(t, func(settings *config.TLS) {})
+	if reloader.changed() {
+		t.Fatal("the files are reported changed right after being loaded")
+	}
+
+	// the modification times of the files written in the same tick may be equal, their size not
+	writeCertificate(t, settings.CertFile, settings.KeyFile, 1<<40)
+	if !reloader.changed() {
+		t.Fatal("the swapped files are not noticed")
+	}
+	reloader.Reload()
+	if serial := served(t, reloader); serial != 1<<40 {
+		t.Fatalf("serving the certificate %d, want the swapped one", serial)
+	}
+	if reloader.changed() {
+		t.Fatal("the files are reported changed right after being reloaded")
+	}
+	if got := testutil.ToFloat64(metrics.CertificateReloads.WithLabelValues("success")); got != 1 {
+		t.Fatalf("counted %v successful reloads, want 1", got)
+	}
+
+	if erro := os.WriteFile(settings.CertFile, []byte("not a certificate"), 0o600); erro != nil {
+		t.Fatal(erro)
+	}
+	reloader.Reload()
+	if serial := served(t, reloader); serial != 1<<40 {
+		t.Fatalf("serving the certificate %d after an invalid file, want the previous one", serial)
+	}
+	if got := testutil.ToFloat64(metrics.CertificateReloads.WithLabelValues("failure")); got != 1 {
+		t.Fatalf("counted %v failed reloads, want 1", got)
+	}
+	if !reloader.changed() {
+		t.Fatal("the invalid file is not retried")
+	}
+
+	writeCertificate(t, settings.CertFile, settings.KeyFile, 3)
+	reloader.Reload()
+	if serial := served(t, reloader); serial != 3 {
+		t.Fatalf("serving the certificate %d once the file is fixed, want the fixed one", serial)
+	}
+}
+
+// The client certificates are required, or only verified when given, by the CAs of the client CA file
+func TestClientAuth(t *testing.T) {
+	tests := []struct {
+		name           string
+		clientAuth     string
+		withClientCA   bool
+		withClientCert bool
+		wantAuth       tls.ClientAuthType
+		wantHandshake  bool
+	}{
+		{"no client CA", "", false, false, tls.NoClientCert, true},
+		{"required and given", "require", true, true, tls.RequireAndVerifyClientCert, true},
+		{"required but missing", "require", true, false, tls.RequireAndVerifyClientCert, false},
+		{"verified when given", "verify_if_given", true, true, tls.VerifyClientCertIfGiven, true},
+		{"verified but missing", "verify_if_given", true, false, tls.VerifyClientCertIfGiven, true},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			dir := t.TempDir()
+			clientCert, clientKey := filepath.Join(dir, "client.pem"), filepath.Join(dir, "client-key.pem")
+			writeCertificate(t, clientCert, clientKey, 2)
+
+			reloader, _, settings := newReloader(t, func(settings *config.TLS) {
+				settings.ClientAuth = test.clientAuth
+				if test.withClientCA {
+					settings.ClientCAFile = clientCert
+				}
+			})
+
+			serverConfig := reloader.Config()
+			if serverConfig.ClientAuth != test.wantAuth {
+				t.Fatalf("got the client auth %v, want %v", serverConfig.ClientAuth, test.wantAuth)
+			}
+
+			roots := x509.NewCertPool()
+			content, erro := os.ReadFile(settings.CertFile)
+			if erro != nil {
+				t.Fatal(erro)
+			}
+			roots.AppendCertsFromPEM(content)
+
+			clientConfig := &tls.Config{RootCAs: roots, ServerName: "localhost"}
+			if test.withClientCert {
+				certificate, erro := tls.LoadX509KeyPair(clientCert, clientKey)
+				if erro != nil {
+					t.Fatal(erro)
+				}
+				clientConfig.Certificates = []tls.Certificate{certificate}
+			}
+
+			if erro := handshake(serverConfig, clientConfig); (erro == nil) != test.wantHandshake {
+				t.Fatalf("the handshake got %v, want it to succeed: %v", erro, test.wantHandshake)
+			}
+		})
+	}
+}
+
+// handshake connects a client to a server over an in-memory connection, returning the error of the server
+func handshake(serverConfig, clientConfig *tls.Config) error {
+	serverConn, clientConn := net.Pipe()
+	defer serverConn.Close()
+	defer clientConn.Close()
+
+	client := make(chan struct{})
+	go func() {
+		defer close(client)
+		tls.Client(clientConn, clientConfig).Handshake()
+		// TLS 1.3 clients learn that their certificate was refused on their first read
+		clientConn.Close()
+	}()
+
+	server := tls.Server(serverConn, serverConfig)
+	erro := server.Handshake()
+	serverConn.Close()
+	<-client
+
+	return erro
+}
@@ -0,0 +1,123 @@
+/*
+Copyright 2022 Danilo S. Lopes.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at:
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package commands
+
+import (
+	"api/src/app"
+	"api/src/config"
+	"api/src/logging"
+	"api/src/repositories"
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"go.opentelemetry.io/otel/trace/noop"
+)
+
+// command represents one sm sub command
+type command struct {
+	name  string
+	usage string
+	run   func(ctx context.Context, args []string) error
+}
+
+func commands() []command {
+	return []command{
+		{name: "serve", usage: serveUsage, run: serve},
+		{name: "migrate", usage: migrateUsage, run: migrate},
+		{name: "seed", usage: seedUsage, run: seed},
+		{name: "user", usage: userUsage, run: user},
+		{name: "token", usage: tokenUsage, run: token},
+		{name: "config", usage: configUsage, run: configCommand},
+		{name: "openapi", usage: openapiUsage, run: openapi},
+	}
+}
+
+// Run executes the sub command named by args[0]. Without arguments the API is served.
+func Run(ctx context.Context, args []string) error {
+	if len(args) == 0 {
+		return serve(ctx, nil)
+	}
+
+	if args[0] == "help" || args[0] == "-h" || args[0] == "--help" {
+		printUsage(os.Stdout)
+		return nil
+	}
+
+	for _, cmd := range commands() {
+		if cmd.name == args[0] {
+			erro := cmd.run(ctx, args[1:])
+			if errors.Is(erro, flag.ErrHelp) {
+				return nil
+			}
+
+			return erro
+		}
+	}
+
+	printUsage(os.Stderr)
+	return fmt.Errorf("unknown command %q", args[0])
+}
+
+func printUsage(w io.Writer) {
+	fmt.Fprintln(w, "usage:")
+	for _, cmd := range commands() {
+		for _, line := range strings.Split(cmd.usage, "\n") {
+			fmt.Fprintf(w, "  %s\n", line)
+		}
+	}
+}
+
+// usageError reports a sub command called with wrong arguments
+func usageError(usage string) error {
+	return fmt.Errorf("usage: %s", strings.ReplaceAll(usage, "\n", "\n       "))
+}
+
+// newFlagSet creates the flags of a sub command, printing its usage on -h
+func newFlagSet(name, usage string) *flag.FlagSet {
+	flags := flag.NewFlagSet(name, flag.ContinueOnError)
+	flags.Usage = func() {
+		fmt.Fprintf(flags.Output(), "usage: %s\n", usage)
+		flags.PrintDefaults()
+	}
+
+	return flags
+}
+
+// openStore opens the store the administrative commands read and write through, and the function closing it.
+// The commands persisting data refuse the memory driver, which would lose it on exit.
+func openStore(cfg config.Config, persists bool) (repositories.Store, func() error, error) {
+	if persists && cfg.Database.Driver == "memory" {
+		return repositories.Store{}, nil, errors.New("DB_DRIVER=memory keeps no data once the command exits, use mysql, postgres or sqlite")
+	}
+
+	store, db, erro := app.OpenStore(cfg.Database, logging.New(os.Stderr, cfg.Log), noop.NewTracerProvider().Tracer(""))
+	if erro != nil {
+		return repositories.Store{}, nil, erro
+	}
+
+	return store, func() error {
+		if db == nil {
+			return nil
+		}
+		return db.Close()
+	}, nil
+}
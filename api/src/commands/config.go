@@ -0,0 +1,76 @@
+/*
+Copyright 2022 Danilo S. Lopes.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at:
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package commands
+
+import (
+	"api/src/config"
+	"api/src/database"
+	"api/src/logging"
+	"api/src/migrations"
+	"context"
+	"fmt"
+	"os"
+
+	"go.opentelemetry.io/otel/trace/noop"
+)
+
+const configUsage = "sm config check"
+
+// configCommand validates the configuration read from the environment
+func configCommand(ctx context.Context, args []string) error {
+	if len(args) != 1 || args[0] != "check" {
+		return usageError(configUsage)
+	}
+
+	cfg := config.Load()
+	if erro := cfg.Validate(); erro != nil {
+		return fmt.Errorf("invalid configuration: %w", erro)
+	}
+
+	fmt.Printf("api port: %d\n", cfg.APIPort)
+	fmt.Printf("database driver: %s\n", cfg.Database.Driver)
+
+	if cfg.Database.Driver != "memory" {
+		db, erro := database.Connect(cfg.Database, logging.New(os.Stderr, cfg.Log), noop.NewTracerProvider().Tracer(""))
+		if erro != nil {
+			return erro
+		}
+		defer db.Close()
+
+		if erro := db.PingContext(ctx); erro != nil {
+			return fmt.Errorf("database unreachable: %w", erro)
+		}
+		if cfg.Database.Driver == "sqlite" {
+			fmt.Printf("database: reachable at %s\n", cfg.Database.Path)
+		} else {
+			fmt.Printf("database: reachable at %s:%s\n", cfg.Database.Host, cfg.Database.Port)
+		}
+
+		migrator, erro := migrations.New(db)
+		if erro != nil {
+			return erro
+		}
+
+		if erro := migrator.Check(ctx); erro != nil {
+			return erro
+		}
+		fmt.Printf("database schema: up to date (version %d)\n", migrator.Latest())
+	}
+
+	fmt.Println("configuration ok")
+	return nil
+}
@@ -0,0 +1,90 @@
+/*
+Copyright 2022 Danilo S. Lopes.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at:
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package commands
+
+import (
+	"api/src/config"
+	"api/src/database"
+	"api/src/logging"
+	"api/src/migrations"
+	"context"
+	"fmt"
+	"os"
+	"strconv"
+	"text/tabwriter"
+
+	"go.opentelemetry.io/otel/trace/noop"
+)
+
+const migrateUsage = "sm migrate up|down|status|to <version>"
+
+// migrate runs the `sm migrate` command against the configured database
+func migrate(ctx context.Context, args []string) error {
+	if len(args) == 0 {
+		return usageError(migrateUsage)
+	}
+
+	cfg := config.Load()
+
+	db, erro := database.Connect(cfg.Database, logging.New(os.Stderr, cfg.Log), noop.NewTracerProvider().Tracer(""))
+	if erro != nil {
+		return erro
+	}
+	defer db.Close()
+
+	migrator, erro := migrations.New(db)
+	if erro != nil {
+		return erro
+	}
+
+	switch args[0] {
+	case "up":
+		return migrator.Up(ctx)
+	case "down":
+		return migrator.Down(ctx)
+	case "to":
+		if len(args) != 2 {
+			return usageError(migrateUsage)
+		}
+
+		version, erro := strconv.Atoi(args[1])
+		if erro != nil {
+			return fmt.Errorf("invalid version %q", args[1])
+		}
+
+		return migrator.To(ctx, version)
+	case "status":
+		status, erro := migrator.Status(ctx)
+		if erro != nil {
+			return erro
+		}
+
+		w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(w, "VERSION\tNAME\tAPPLIED AT")
+		for _, migration := range status {
+			appliedAt := "pending"
+			if migration.Applied {
+				appliedAt = migration.AppliedAt.Format("2006-01-02 15:04:05")
+			}
+			fmt.Fprintf(w, "%d\t%s\t%s\n", migration.Version, migration.Name, appliedAt)
+		}
+
+		return w.Flush()
+	default:
+		return usageError(migrateUsage)
+	}
+}
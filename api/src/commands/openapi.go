@@ -0,0 +1,73 @@
+/*
+Copyright 2022 Danilo S. Lopes.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at:
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package commands
+
+import (
+	"api/src/router/routes"
+	"api/src/swagger"
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"os"
+)
+
+const openapiUsage = "sm openapi [--output <file>] [--check]"
+
+// openapi generates the OpenAPI document from the route table. With --check it fails when the
+// document differs from --output, or from the one built into sm when --output is not given.
+func openapi(ctx context.Context, args []string) error {
+	flags := newFlagSet("openapi", openapiUsage)
+	output := flags.String("output", "", "file the document is written into, the standard output when empty")
+	check := flags.Bool("check", false, "compare the generated document instead of writing it")
+	if erro := flags.Parse(args); erro != nil {
+		return erro
+	}
+
+	if flags.NArg() != 0 {
+		return usageError(openapiUsage)
+	}
+
+	generated, erro := routes.OpenAPI()
+	if erro != nil {
+		return erro
+	}
+
+	if !*check {
+		if *output == "" {
+			_, erro = os.Stdout.Write(generated)
+			return erro
+		}
+
+		return os.WriteFile(*output, generated, 0o644)
+	}
+
+	committed, name := swagger.Spec(), "the document built into sm"
+	if *output != "" {
+		if committed, erro = os.ReadFile(*output); erro != nil {
+			return erro
+		}
+		name = *output
+	}
+
+	if !bytes.Equal(committed, generated) {
+		return errors.New(name + " is out of date with the routes, run: sm openapi --output src/swagger/openapi.json")
+	}
+
+	fmt.Println("openapi.json is up to date")
+	return nil
+}
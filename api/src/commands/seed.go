@@ -0,0 +1,117 @@
+/*
+Copyright 2022 Danilo S. Lopes.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at:
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package commands
+
+import (
+	"api/src/config"
+	"api/src/models"
+	"api/src/repositories"
+	"context"
+	"errors"
+	"fmt"
+)
+
+const seedUsage = "sm seed"
+
+// sampleUsers are the users of sql/mock-data.sql with their plain passwords
+var sampleUsers = []models.User{
+	{Name: "User1", Nick: "usr1", Email: "user1@gmail.com", Pass: "i7e8Iy9N5Y0yJ5VoK9Ej6bIA5Dgilm"},
+	{Name: "User2", Nick: "usr2", Email: "user2@gmail.com", Pass: "5A6CFCGYIheDUFI56fe6kTHF9AZqCP"},
+	{Name: "User3", Nick: "usr3", Email: "user3@gmail.com", Pass: "ZnbcCM5zLjccxW4E9Oy1Jvo0j8RDME"},
+	{Name: "User4", Nick: "usr4", Email: "user4@gmail.com", Pass: "8WPOaKok2C2UNUX4HxTEEpPm7XTI82"},
+}
+
+// sampleFollowers maps a user nick to the nicks following him
+var sampleFollowers = map[string][]string{
+	"usr1": {"usr2", "usr3"},
+	"usr2": {"usr1"},
+	"usr3": {"usr2", "usr4"},
+	"usr4": {"usr1"},
+}
+
+// samplePublications maps a user nick to the publication he authors
+var samplePublications = map[string]models.Publication{
+	"usr1": {Title: "User1 Publication", Content: "This is the publication of User 1 !, oooohh"},
+	"usr2": {Title: "User2 Publication", Content: "This is the publication of User 2 !, oooohh"},
+	"usr3": {Title: "User3 Publication", Content: "This is the publication of User 3 !, oooohh"},
+}
+
+// seed inserts the sample data into the configured database
+func seed(ctx context.Context, args []string) error {
+	if erro := newFlagSet("seed", seedUsage).Parse(args); erro != nil {
+		return erro
+	}
+
+	store, closeStore, erro := openStore(config.Load(), true)
+	if erro != nil {
+		return erro
+	}
+	defer closeStore()
+
+	return seedStore(ctx, store)
+}
+
+// seedStore inserts the sample users, followers and publications.
+// Users that already exist are left untouched, so it can run more than once.
+func seedStore(ctx context.Context, store repositories.Store) error {
+	IDs := make(map[string]uint64)
+	created := make(map[string]bool)
+
+	for _, sample := range sampleUsers {
+		existing, erro := store.Users.SearchByEmail(ctx, sample.Email)
+		if erro == nil {
+			IDs[sample.Nick] = existing.ID
+			continue
+		}
+
+		if !errors.Is(erro, models.ErrNotFound) {
+			return erro
+		}
+
+		user := sample
+		if erro := user.Prepare("registration"); erro != nil {
+			return erro
+		}
+
+		if IDs[user.Nick], erro = store.Users.Create(ctx, user); erro != nil {
+			return fmt.Errorf("creating user %s: %w", user.Nick, erro)
+		}
+		created[user.Nick] = true
+	}
+
+	for nick, followers := range sampleFollowers {
+		for _, follower := range followers {
+			if erro := store.Users.Follow(ctx, IDs[nick], IDs[follower]); erro != nil {
+				return erro
+			}
+		}
+	}
+
+	for _, sample := range sampleUsers {
+		publication, exists := samplePublications[sample.Nick]
+		if !exists || !created[sample.Nick] {
+			continue
+		}
+
+		publication.AuthorID = IDs[sample.Nick]
+		if _, erro := store.Publications.Create(ctx, publication); erro != nil {
+			return erro
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,48 @@
+/*
+Copyright 2022 Danilo S. Lopes.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at:
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package commands
+
+import (
+	"api/src/app"
+	"api/src/config"
+	"context"
+)
+
+const serveUsage = "sm serve [--seed]"
+
+// serve runs the API until a termination signal cancels ctx
+func serve(ctx context.Context, args []string) error {
+	flags := newFlagSet("serve", serveUsage)
+	withSeed := flags.Bool("seed", false, "insert the sample data before serving, handy with DB_DRIVER=memory")
+	if erro := flags.Parse(args); erro != nil {
+		return erro
+	}
+
+	application, erro := app.New(config.Load())
+	if erro != nil {
+		return erro
+	}
+
+	if *withSeed {
+		if erro := seedStore(ctx, application.Store); erro != nil {
+			application.Close()
+			return erro
+		}
+	}
+
+	return application.Run(ctx)
+}
@@ -0,0 +1,72 @@
+/*
+Copyright 2022 Danilo S. Lopes.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at:
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package commands
+
+import (
+	"api/src/authentication"
+	"api/src/config"
+	"context"
+	"errors"
+	"fmt"
+)
+
+const tokenUsage = "sm token issue --user <id>"
+
+// token runs the tokens administration commands
+func token(ctx context.Context, args []string) error {
+	if len(args) == 0 || args[0] != "issue" {
+		return usageError(tokenUsage)
+	}
+
+	flags := newFlagSet("token issue", tokenUsage)
+	ID := flags.Uint64("user", 0, "id of the user the token is issued for")
+	if erro := flags.Parse(args[1:]); erro != nil {
+		return erro
+	}
+
+	if *ID == 0 {
+		return usageError(tokenUsage)
+	}
+
+	cfg := config.Load()
+	if len(cfg.SecretKey) == 0 {
+		return errors.New("SECRET_KEY is not set")
+	}
+
+	store, closeStore, erro := openStore(cfg, false)
+	if erro != nil {
+		return erro
+	}
+	defer closeStore()
+
+	found, erro := findUser(ctx, store, *ID, "")
+	if erro != nil {
+		return erro
+	}
+
+	if found.Disabled {
+		return errors.New("the user is disabled")
+	}
+
+	signed, erro := authentication.GenerateToken(cfg.SecretKey, found.ID)
+	if erro != nil {
+		return erro
+	}
+
+	fmt.Println(signed)
+	return nil
+}
@@ -0,0 +1,193 @@
+/*
+Copyright 2022 Danilo S. Lopes.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at:
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package commands
+
+import (
+	"api/src/config"
+	"api/src/models"
+	"api/src/repositories"
+	"api/src/security"
+	"bufio"
+	"context"
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+)
+
+const userUsage = `sm user create --name <name> --nick <nick> --email <email> [--pass <pass>]
+sm user disable --id <id> | --email <email>
+sm user reset-password --id <id> | --email <email> [--pass <pass>]`
+
+// user runs the users administration commands
+func user(ctx context.Context, args []string) error {
+	if len(args) == 0 {
+		return usageError(userUsage)
+	}
+
+	switch args[0] {
+	case "create":
+		return userCreate(ctx, args[1:])
+	case "disable":
+		return userDisable(ctx, args[1:])
+	case "reset-password":
+		return userResetPassword(ctx, args[1:])
+	default:
+		return usageError(userUsage)
+	}
+}
+
+func userCreate(ctx context.Context, args []string) error {
+	flags := newFlagSet("user create", userUsage)
+	name := flags.String("name", "", "user name")
+	nick := flags.String("nick", "", "user nick")
+	email := flags.String("email", "", "user email")
+	pass := flags.String("pass", "", "user password, read from stdin when empty")
+	if erro := flags.Parse(args); erro != nil {
+		return erro
+	}
+
+	if *pass == "" {
+		var erro error
+		if *pass, erro = readPass(); erro != nil {
+			return erro
+		}
+	}
+
+	newUser := models.User{Name: *name, Nick: *nick, Email: *email, Pass: *pass}
+	if erro := newUser.Prepare("registration"); erro != nil {
+		return erro
+	}
+
+	store, closeStore, erro := openStore(config.Load(), true)
+	if erro != nil {
+		return erro
+	}
+	defer closeStore()
+
+	ID, erro := store.Users.Create(ctx, newUser)
+	if erro != nil {
+		return erro
+	}
+
+	fmt.Printf("created user %d (%s)\n", ID, newUser.Nick)
+	return nil
+}
+
+func userDisable(ctx context.Context, args []string) error {
+	flags := newFlagSet("user disable", userUsage)
+	ID := flags.Uint64("id", 0, "user id")
+	email := flags.String("email", "", "user email")
+	if erro := flags.Parse(args); erro != nil {
+		return erro
+	}
+
+	store, closeStore, erro := openStore(config.Load(), true)
+	if erro != nil {
+		return erro
+	}
+	defer closeStore()
+
+	found, erro := findUser(ctx, store, *ID, *email)
+	if erro != nil {
+		return erro
+	}
+
+	if erro := store.Users.Disable(ctx, found.ID); erro != nil {
+		return erro
+	}
+
+	fmt.Printf("disabled user %d (%s)\n", found.ID, found.Nick)
+	return nil
+}
+
+func userResetPassword(ctx context.Context, args []string) error {
+	flags := newFlagSet("user reset-password", userUsage)
+	ID := flags.Uint64("id", 0, "user id")
+	email := flags.String("email", "", "user email")
+	pass := flags.String("pass", "", "new password, a random one is generated and printed when empty")
+	if erro := flags.Parse(args); erro != nil {
+		return erro
+	}
+
+	generated := *pass == ""
+	if generated {
+		random := make([]byte, 18)
+		if _, erro := rand.Read(random); erro != nil {
+			return erro
+		}
+		*pass = base64.RawURLEncoding.EncodeToString(random)
+	}
+
+	hashedPass, erro := security.Hash(*pass)
+	if erro != nil {
+		return erro
+	}
+
+	store, closeStore, erro := openStore(config.Load(), true)
+	if erro != nil {
+		return erro
+	}
+	defer closeStore()
+
+	found, erro := findUser(ctx, store, *ID, *email)
+	if erro != nil {
+		return erro
+	}
+
+	if erro := store.Users.UpadateUserPass(ctx, found.ID, string(hashedPass)); erro != nil {
+		return erro
+	}
+
+	fmt.Printf("reset the password of user %d (%s)\n", found.ID, found.Nick)
+	if generated {
+		fmt.Printf("new password: %s\n", *pass)
+	}
+
+	return nil
+}
+
+// findUser search an user by id or by email, failing when he does not exist
+func findUser(ctx context.Context, store repositories.Store, ID uint64, email string) (models.User, error) {
+	if ID == 0 && email == "" {
+		return models.User{}, errors.New("either --id or --email is required")
+	}
+
+	if ID == 0 {
+		byEmail, erro := store.Users.SearchByEmail(ctx, email)
+		if erro != nil {
+			return models.User{}, erro
+		}
+		ID = byEmail.ID
+	}
+
+	return store.Users.SearchByID(ctx, ID)
+}
+
+// readPass reads the password from the first stdin line
+func readPass() (string, error) {
+	fmt.Fprint(os.Stderr, "password: ")
+
+	line, erro := bufio.NewReader(os.Stdin).ReadString('\n')
+	if erro != nil && line == "" {
+		return "", errors.New("no password given")
+	}
+
+	return strings.TrimRight(line, "\r\n"), nil
+}
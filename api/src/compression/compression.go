@@ -0,0 +1,170 @@
+/*
+Copyright 2022 Danilo S. Lopes.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at:
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package compression
+
+import (
+	"api/src/config"
+	"bytes"
+	"compress/gzip"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// Encoder wraps w into a writer compressing what it receives with one content coding
+type Encoder func(w io.Writer) (io.WriteCloser, error)
+
+var (
+	encodersMutex sync.RWMutex
+	encoders      = map[string]Encoder{
+		"gzip": func(w io.Writer) (io.WriteCloser, error) {
+			return gzip.NewWriter(w), nil
+		},
+	}
+)
+
+// RegisterEncoder makes a content coding selectable by COMPRESSION_ENCODINGS, e.g. "zstd" or "br"
+func RegisterEncoder(name string, encoder Encoder) {
+	encodersMutex.Lock()
+	defer encodersMutex.Unlock()
+
+	encoders[name] = encoder
+}
+
+// Encoders returns the names accepted by COMPRESSION_ENCODINGS
+func Encoders() []string {
+	encodersMutex.RLock()
+	defer encodersMutex.RUnlock()
+
+	var names []string
+	for name := range encoders {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	return names
+}
+
+// Compressor compresses the response bodies with the content coding the client prefers among the configured ones
+type Compressor struct {
+	encodings []string
+	encoders  map[string]Encoder
+	minSize   int
+}
+
+// New creates the Compressor offering the encodings of settings, failing on an unknown one. "off" offers none.
+func New(settings config.Compression) (*Compressor, error) {
+	compressor := &Compressor{encoders: make(map[string]Encoder), minSize: settings.MinSize}
+
+	encodersMutex.RLock()
+	defer encodersMutex.RUnlock()
+
+	for _, name := range settings.Encodings {
+		if name == "off" {
+			continue
+		}
+
+		encoder, exists := encoders[name]
+		if !exists {
+			return nil, fmt.Errorf("unknown compression encoding %q", name)
+		}
+
+		compressor.encodings = append(compressor.encodings, name)
+		compressor.encoders[name] = encoder
+	}
+
+	return compressor, nil
+}
+
+// Enabled tells whether any encoding is offered
+func (compressor *Compressor) Enabled() bool {
+	return compressor != nil && len(compressor.encodings) > 0
+}
+
+// Worth tells whether a body of size bytes and contentType is worth compressing
+func (compressor *Compressor) Worth(contentType string, size int) bool {
+	if size < compressor.minSize {
+		return false
+	}
+
+	mediaType, _, _ := strings.Cut(contentType, ";")
+	mediaType = strings.TrimSpace(strings.ToLower(mediaType))
+
+	return strings.HasPrefix(mediaType, "text/") ||
+		strings.HasSuffix(mediaType, "json") ||
+		strings.HasSuffix(mediaType, "javascript") ||
+		strings.HasSuffix(mediaType, "xml")
+}
+
+// Negotiate returns the encoding to answer an Accept-Encoding header with, "" for none. The client
+// preference, its q-value, wins and the configured order breaks the ties.
+func (compressor *Compressor) Negotiate(acceptEncoding string) string {
+	weights := make(map[string]float64)
+	for _, item := range strings.Split(acceptEncoding, ",") {
+		name, parameters, _ := strings.Cut(item, ";")
+		name = strings.ToLower(strings.TrimSpace(name))
+		if name == "" {
+			continue
+		}
+
+		weight := 1.0
+		if key, value, found := strings.Cut(strings.TrimSpace(parameters), "="); found && strings.TrimSpace(key) == "q" {
+			if parsed, erro := strconv.ParseFloat(strings.TrimSpace(value), 64); erro == nil {
+				weight = parsed
+			}
+		}
+		weights[name] = weight
+	}
+
+	chosen, best := "", 0.0
+	for _, name := range compressor.encodings {
+		weight, exists := weights[name]
+		if !exists {
+			weight = weights["*"]
+		}
+
+		if weight > best {
+			chosen, best = name, weight
+		}
+	}
+
+	return chosen
+}
+
+// Compress returns body compressed with encoding
+func (compressor *Compressor) Compress(encoding string, body []byte) ([]byte, error) {
+	var compressed bytes.Buffer
+
+	writer, erro := compressor.encoders[encoding](&compressed)
+	if erro != nil {
+		return nil, erro
+	}
+
+	if _, erro := writer.Write(body); erro != nil {
+		writer.Close()
+		return nil, erro
+	}
+
+	if erro := writer.Close(); erro != nil {
+		return nil, erro
+	}
+
+	return compressed.Bytes(), nil
+}
@@ -17,47 +17,739 @@ limitations under the License.
 package config
 
 import (
+	"errors"
 	"fmt"
+	"net"
+	"net/url"
 	"os"
 	"strconv"
+	"strings"
 	"time"
 )
 
-var (
-	// Application Variables
-
-	// Database information
-	DatabaseStringConnection string = ""
-	DatabaseHost             string = ""
-	DatabasePort             string = ""
-
+// Config holds every setting the application needs to run
+type Config struct {
 	// API Service Port
-	APIPort int = 0
+	APIPort int
 
 	// Used to assign the token
 	SecretKey []byte
 
-	Now time.Time
-)
+	// How long the API keeps serving after /ready starts failing, before it stops accepting connections
+	ShutdownDelay time.Duration
+
+	// How long the in flight requests have to finish once the API stops accepting connections
+	ShutdownTimeout time.Duration
+
+	// Checks the requests against the OpenAPI document: off, requests or full (requests and responses)
+	OpenAPIValidation string
+
+	Database Database
+
+	Log Log
+
+	Tracing Tracing
+
+	RateLimit RateLimit
+
+	CORS CORS
+
+	Compression Compression
+
+	Pagination Pagination
+
+	LegacyRoutes LegacyRoutes
+
+	TLS TLS
+
+	Body Body
+
+	// How long the responses of the requests sent with an Idempotency-Key are replayed to their retries
+	IdempotencyTTL time.Duration
+
+	// the variables holding a value that could not be parsed, reported by Validate
+	invalid []string
+}
+
+// Body holds the size limits of the request bodies
+type Body struct {
+	// Greatest body accepted, in bytes, the larger ones being answered with a 413
+	MaxSize int64
+
+	// Sizes overriding MaxSize for some routes, keyed by "<METHOD> <route>" (e.g. "POST /login")
+	RouteMaxSizes map[string]int64
+}
+
+// MaxSizeFor returns the greatest body accepted by the route matching method and uri
+func (body Body) MaxSizeFor(method, uri string) int64 {
+	if size, exists := body.RouteMaxSizes[method+" "+uri]; exists {
+		return size
+	}
+
+	return body.MaxSize
+}
+
+// TLS holds the certificates the API serves HTTPS with. Without them it serves plain HTTP,
+// behind a proxy terminating TLS.
+type TLS struct {
+	// PEM files of the certificate chain and of its private key
+	CertFile string
+	KeyFile  string
+
+	// PEM file of the CAs the client certificates are verified with, for mutual TLS
+	ClientCAFile string
+
+	// What is asked from the clients when ClientCAFile is given: a certificate signed by one of the CAs
+	// (require) or a verified one when they present any (verify_if_given)
+	ClientAuth string
+
+	// How often the files are checked for a change, they are also reloaded on SIGHUP
+	ReloadInterval time.Duration
+}
+
+// Enabled tells whether the API serves HTTPS
+func (tls TLS) Enabled() bool {
+	return tls.CertFile != ""
+}
+
+// LegacyRoutes holds the settings of the unversioned routes, deprecated aliases of the /v1 ones
+type LegacyRoutes struct {
+	// Serve the unversioned routes
+	Enabled bool
+
+	// When they were deprecated and when they stop being served, sent in the Deprecation and Sunset headers.
+	// The deprecation is required while they are served, the sunset defaulting to 6 months after it.
+	Deprecation time.Time
+	Sunset      time.Time
+}
+
+// Pagination holds the page sizes of the lists
+type Pagination struct {
+	// Items of a page when the client gives no limit
+	DefaultLimit int
+
+	// Greatest limit a client can ask for, a greater one being lowered to it
+	MaxLimit int
+}
+
+// Compression holds the response compression settings
+type Compression struct {
+	// Content codings offered to the clients by order of preference: gzip or the ones registered with
+	// compression.RegisterEncoder (e.g. zstd or br). None, or "off", disables the compression.
+	Encodings []string
+
+	// Bodies smaller than MinSize bytes are sent as they are
+	MinSize int
+}
+
+// CORS holds the cross-origin resource sharing settings, letting the browsers call the API from other origins
+type CORS struct {
+	// Origins allowed to call the API, e.g. "https://app.example.com", "https://*.example.com" or "*" for any.
+	// None disables CORS.
+	AllowedOrigins []string
+
+	// Methods and request headers the other origins may use
+	AllowedMethods []string
+	AllowedHeaders []string
+
+	// Response headers the browsers expose to the other origins
+	ExposedHeaders []string
+
+	// Let the browsers send the cookies and the Authorization header along
+	AllowCredentials bool
+
+	// How long the browsers may cache a preflight response
+	MaxAge time.Duration
+}
+
+// Enabled tells whether any origin is allowed
+func (cors CORS) Enabled() bool {
+	return len(cors.AllowedOrigins) > 0
+}
+
+// AllowsOrigin tells whether origin matches one of the allowed origins, a "*" in them matching any characters
+func (cors CORS) AllowsOrigin(origin string) bool {
+	if origin == "" {
+		return false
+	}
+
+	origin = strings.ToLower(origin)
+	for _, allowed := range cors.AllowedOrigins {
+		prefix, suffix, wildcard := strings.Cut(strings.ToLower(allowed), "*")
+		if !wildcard && origin == prefix {
+			return true
+		}
+
+		if wildcard && len(origin) > len(prefix)+len(suffix) &&
+			strings.HasPrefix(origin, prefix) && strings.HasSuffix(origin, suffix) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// RateLimit holds the token bucket policies bounding the requests of each client
+type RateLimit struct {
+	// Where the buckets are kept: memory (one set per API instance) or a store registered with ratelimit.RegisterStore
+	Store string
+
+	// Identify the anonymous clients by the first X-Forwarded-For address instead of the connection one.
+	// Only safe behind a proxy that overwrites the header.
+	TrustForwardedFor bool
+
+	// Policy of the anonymous routes, whose clients are told apart by IP
+	Anonymous RatePolicy
 
-// Load inicialize environment variables, configure database string connection and set which port the api will run.
-func Load() {
-	var erro error
+	// Policy of the authenticated routes, whose clients are told apart by user ID
+	Authenticated RatePolicy
+
+	// Policies overriding the above for some routes, keyed by "<METHOD> <route>" (e.g. "POST /login").
+	// Each of these routes has its own buckets.
+	Routes map[string]RatePolicy
+}
+
+// RatePolicy allows a client Limit requests per Window, refilled continuously, so it can burst up to Limit.
+// A zero Limit disables the rate limiting.
+type RatePolicy struct {
+	Limit  int
+	Window time.Duration
+}
+
+func (policy RatePolicy) String() string {
+	if policy.Limit == 0 {
+		return "off"
+	}
+
+	return fmt.Sprintf("%d/%s", policy.Limit, policy.Window)
+}
+
+// PolicyFor returns the policy of the route matching method and uri, along with the scope naming the
+// buckets it shares with other routes
+func (rateLimit RateLimit) PolicyFor(method, uri string, authenticated bool) (string, RatePolicy) {
+	if policy, exists := rateLimit.Routes[method+" "+uri]; exists {
+		return method + " " + uri, policy
+	}
+
+	if authenticated {
+		return "authenticated", rateLimit.Authenticated
+	}
+
+	return "anonymous", rateLimit.Anonymous
+}
+
+// Tracing holds the distributed tracing settings
+type Tracing struct {
+	// Where the spans are sent: none, stdout, file or an exporter registered with tracing.RegisterExporter
+	Exporter string
+
+	// File the spans are appended to by the file exporter
+	File string
+
+	// service.name attribute of the spans
+	ServiceName string
+
+	// Fraction of the traces started by the API that are sampled, from 0 to 1.
+	// Traces started upstream follow the sampling decision of the traceparent header.
+	SampleRatio float64
+}
+
+// Log holds the application logger settings
+type Log struct {
+	// Minimum level logged: debug, info, warn or error
+	Level string
+
+	// Records encoding: json or text
+	Format string
+}
+
+// Database holds the database connection information and pool tuning
+type Database struct {
+	Driver string
+	Host   string
+	Port   string
+	User   string
+	Pass   string
+	Name   string
+
+	// SQLite database file
+	Path string
+
+	// PostgreSQL sslmode (disable, require, verify-ca or verify-full)
+	SSLMode string
+
+	MaxOpenConns    int
+	MaxIdleConns    int
+	ConnMaxLifetime time.Duration
+	ConnMaxIdleTime time.Duration
+
+	// Serve even when the database misses schema migrations
+	SkipSchemaCheck bool
+
+	// Deadline of the queries run by a request, zero means no deadline
+	QueryTimeout time.Duration
+
+	// Deadlines overriding QueryTimeout for some routes, keyed by "<METHOD> <route>" (e.g. "GET /users")
+	RouteQueryTimeouts map[string]time.Duration
+}
+
+// QueryTimeoutFor returns the query deadline of the route matching method and uri
+func (database Database) QueryTimeoutFor(method, uri string) time.Duration {
+	if timeout, exists := database.RouteQueryTimeouts[method+" "+uri]; exists {
+		return timeout
+	}
+
+	return database.QueryTimeout
+}
+
+// StringConnection returns the data source name of the configured driver
+func (database Database) StringConnection() string {
+	switch database.Driver {
+	case "sqlite":
+		return fmt.Sprintf("file:%s?_pragma=foreign_keys(1)&_pragma=busy_timeout(5000)&_pragma=journal_mode(WAL)", database.Path)
+	case "postgres":
+		dsn := url.URL{
+			Scheme:   "postgres",
+			User:     url.UserPassword(database.User, database.Pass),
+			Host:     net.JoinHostPort(database.Host, database.Port),
+			Path:     "/" + database.Name,
+			RawQuery: url.Values{"sslmode": {database.SSLMode}}.Encode(),
+		}
+		return dsn.String()
+	}
+
+	// clientFoundRows makes an UPDATE report the matched rows, not only the changed ones, see repositories.affected
+	return fmt.Sprintf("%s:%s@tcp(%s:%s)/%s?tls=skip-verify&charset=utf8&parseTime=True&loc=Local&clientFoundRows=true",
+		database.User,
+		database.Pass,
+		database.Host,
+		database.Port,
+		database.Name,
+	)
+}
+
+// Load read the environment variables, configure database connection and set which port the api will run.
+func Load() Config {
+	var env environment
+
+	cfg := Config{
+		APIPort:   env.intFromEnv("API_PORT", 8080),
+		SecretKey: []byte(os.Getenv("SECRET_KEY")),
+
+		ShutdownDelay:   env.durationFromEnv("SHUTDOWN_DELAY", 0),
+		ShutdownTimeout: env.durationFromEnv("SHUTDOWN_TIMEOUT", 30*time.Second),
+
+		OpenAPIValidation: stringFromEnv("OPENAPI_VALIDATION", "off"),
+
+		Database: Database{
+			Driver: stringFromEnv("DB_DRIVER", "mysql"),
+			Host:   os.Getenv("DB_HOST"),
+			Port:   os.Getenv("DB_PORT"),
+			User:   os.Getenv("DB_USER"),
+			Pass:   os.Getenv("DB_PASS"),
+			Name:   os.Getenv("DB_NAME"),
+			Path:   stringFromEnv("DB_PATH", "sm.db"),
+
+			SSLMode: stringFromEnv("DB_SSLMODE", "require"),
+
+			MaxOpenConns:    env.intFromEnv("DB_MAX_OPEN_CONNS", 25),
+			MaxIdleConns:    env.intFromEnv("DB_MAX_IDLE_CONNS", 25),
+			ConnMaxLifetime: env.durationFromEnv("DB_CONN_MAX_LIFETIME", 5*time.Minute),
+			ConnMaxIdleTime: env.durationFromEnv("DB_CONN_MAX_IDLE_TIME", time.Minute),
+
+			SkipSchemaCheck: env.boolFromEnv("DB_SKIP_SCHEMA_CHECK", false),
+
+			QueryTimeout:       env.durationFromEnv("DB_QUERY_TIMEOUT", 10*time.Second),
+			RouteQueryTimeouts: env.durationsFromEnv("DB_ROUTE_QUERY_TIMEOUTS"),
+		},
+
+		Log: Log{
+			Level:  stringFromEnv("LOG_LEVEL", "info"),
+			Format: stringFromEnv("LOG_FORMAT", "json"),
+		},
+
+		Tracing: Tracing{
+			Exporter:    stringFromEnv("TRACING_EXPORTER", "none"),
+			File:        stringFromEnv("TRACING_FILE", "traces.json"),
+			ServiceName: stringFromEnv("TRACING_SERVICE_NAME", "sm"),
+			SampleRatio: env.floatFromEnv("TRACING_SAMPLE_RATIO", 1),
+		},
+
+		RateLimit: RateLimit{
+			Store:             stringFromEnv("RATE_LIMIT_STORE", "memory"),
+			TrustForwardedFor: env.boolFromEnv("RATE_LIMIT_TRUST_FORWARDED_FOR", false),
+			Anonymous:         env.policyFromEnv("RATE_LIMIT_ANONYMOUS", "60/1m"),
+			Authenticated:     env.policyFromEnv("RATE_LIMIT_AUTHENTICATED", "600/1m"),
+			Routes:            env.policiesFromEnv("RATE_LIMIT_ROUTES", "POST /login=10/1m,POST /users=5/1h,POST /publications=30/1m"),
+		},
+
+		CORS: CORS{
+			AllowedOrigins:   listFromEnv("CORS_ALLOWED_ORIGINS", ""),
+			AllowedMethods:   listFromEnv("CORS_ALLOWED_METHODS", "GET,POST,PUT,DELETE"),
+			AllowedHeaders:   listFromEnv("CORS_ALLOWED_HEADERS", "Authorization,Content-Type,X-Request-ID,Idempotency-Key"),
+			ExposedHeaders:   listFromEnv("CORS_EXPOSED_HEADERS", "X-Request-ID,RateLimit-Limit,RateLimit-Remaining,RateLimit-Reset,RateLimit-Policy,Retry-After,ETag,Link,Idempotent-Replayed"),
+			AllowCredentials: env.boolFromEnv("CORS_ALLOW_CREDENTIALS", false),
+			MaxAge:           env.durationFromEnv("CORS_MAX_AGE", 10*time.Minute),
+		},
+
+		Compression: Compression{
+			Encodings: listFromEnv("COMPRESSION_ENCODINGS", "gzip"),
+			MinSize:   env.intFromEnv("COMPRESSION_MIN_SIZE", 1024),
+		},
+
+		Pagination: Pagination{
+			DefaultLimit: env.intFromEnv("PAGINATION_DEFAULT_LIMIT", 20),
+			MaxLimit:     env.intFromEnv("PAGINATION_MAX_LIMIT", 100),
+		},
+
+		LegacyRoutes: LegacyRoutes{
+			Enabled:     env.boolFromEnv("LEGACY_ROUTES", true),
+			Deprecation: env.dateFromEnv("LEGACY_ROUTES_DEPRECATION", ""),
+			Sunset:      env.dateFromEnv("LEGACY_ROUTES_SUNSET", ""),
+		},
+
+		TLS: TLS{
+			CertFile:       os.Getenv("TLS_CERT_FILE"),
+			KeyFile:        os.Getenv("TLS_KEY_FILE"),
+			ClientCAFile:   os.Getenv("TLS_CLIENT_CA_FILE"),
+			ClientAuth:     stringFromEnv("TLS_CLIENT_AUTH", "require"),
+			ReloadInterval: env.durationFromEnv("TLS_RELOAD_INTERVAL", 30*time.Second),
+		},
+
+		Body: Body{
+			MaxSize:       int64(env.intFromEnv("BODY_MAX_SIZE", 64<<10)),
+			RouteMaxSizes: env.sizesFromEnv("BODY_ROUTE_MAX_SIZES", "POST /login=4096,POST /users/{userID}/updatepass=4096"),
+		},
+
+		IdempotencyTTL: env.durationFromEnv("IDEMPOTENCY_TTL", 24*time.Hour),
+	}
+	cfg.invalid = env.invalid
 
-	APIPort, erro = strconv.Atoi(os.Getenv("API_PORT"))
+	if cfg.LegacyRoutes.Sunset.IsZero() && !cfg.LegacyRoutes.Deprecation.IsZero() {
+		cfg.LegacyRoutes.Sunset = cfg.LegacyRoutes.Deprecation.AddDate(0, 6, 0)
+	}
+
+	return cfg
+}
+
+// Validate reports every setting that prevents the API from running properly
+func (cfg Config) Validate() error {
+	problems := append([]string{}, cfg.invalid...)
+
+	if cfg.APIPort <= 0 || cfg.APIPort > 65535 {
+		problems = append(problems, fmt.Sprintf("API_PORT %d is not a valid port", cfg.APIPort))
+	}
+
+	if len(cfg.SecretKey) == 0 {
+		problems = append(problems, "SECRET_KEY is empty")
+	}
+
+	if cfg.ShutdownTimeout <= 0 {
+		problems = append(problems, "SHUTDOWN_TIMEOUT must be positive")
+	}
+
+	switch cfg.OpenAPIValidation {
+	case "off", "requests", "full":
+	default:
+		problems = append(problems, fmt.Sprintf("OPENAPI_VALIDATION %q is unknown", cfg.OpenAPIValidation))
+	}
+
+	switch strings.ToLower(cfg.Log.Level) {
+	case "debug", "info", "warn", "error":
+	default:
+		problems = append(problems, fmt.Sprintf("LOG_LEVEL %q is unknown", cfg.Log.Level))
+	}
+
+	if cfg.Log.Format != "json" && cfg.Log.Format != "text" {
+		problems = append(problems, fmt.Sprintf("LOG_FORMAT %q is unknown", cfg.Log.Format))
+	}
+
+	if cfg.Tracing.SampleRatio < 0 || cfg.Tracing.SampleRatio > 1 {
+		problems = append(problems, "TRACING_SAMPLE_RATIO must be between 0 and 1")
+	}
+
+	if cfg.CORS.AllowCredentials && cfg.CORS.AllowsOrigin("*") {
+		problems = append(problems, "CORS_ALLOW_CREDENTIALS cannot be used along with any origin (*) in CORS_ALLOWED_ORIGINS")
+	}
+
+	if cfg.Pagination.DefaultLimit <= 0 || cfg.Pagination.DefaultLimit > cfg.Pagination.MaxLimit {
+		problems = append(problems, "PAGINATION_DEFAULT_LIMIT must be positive and not greater than PAGINATION_MAX_LIMIT")
+	}
+
+	switch {
+	case !cfg.LegacyRoutes.Enabled:
+	case cfg.LegacyRoutes.Deprecation.IsZero():
+		problems = append(problems, "LEGACY_ROUTES_DEPRECATION is required while LEGACY_ROUTES is on")
+	case !cfg.LegacyRoutes.Sunset.After(cfg.LegacyRoutes.Deprecation):
+		problems = append(problems, "LEGACY_ROUTES_SUNSET must come after LEGACY_ROUTES_DEPRECATION")
+	}
+
+	if (cfg.TLS.CertFile == "") != (cfg.TLS.KeyFile == "") {
+		problems = append(problems, "TLS_CERT_FILE and TLS_KEY_FILE must be given together")
+	}
+
+	if cfg.TLS.ClientCAFile != "" && !cfg.TLS.Enabled() {
+		problems = append(problems, "TLS_CLIENT_CA_FILE requires TLS_CERT_FILE and TLS_KEY_FILE")
+	}
+
+	if cfg.TLS.ClientAuth != "require" && cfg.TLS.ClientAuth != "verify_if_given" {
+		problems = append(problems, fmt.Sprintf("TLS_CLIENT_AUTH %q is unknown", cfg.TLS.ClientAuth))
+	}
+
+	if cfg.TLS.ReloadInterval <= 0 {
+		problems = append(problems, "TLS_RELOAD_INTERVAL must be positive")
+	}
+
+	if cfg.Body.MaxSize <= 0 {
+		problems = append(problems, "BODY_MAX_SIZE must be positive")
+	}
+
+	if cfg.IdempotencyTTL <= 0 {
+		problems = append(problems, "IDEMPOTENCY_TTL must be positive")
+	}
+
+	if cfg.Database.QueryTimeout < 0 {
+		problems = append(problems, "DB_QUERY_TIMEOUT must not be negative")
+	}
+
+	switch cfg.Database.Driver {
+	case "memory":
+	case "sqlite":
+		if cfg.Database.Path == "" {
+			problems = append(problems, "DB_PATH is empty")
+		}
+	case "mysql", "postgres":
+		for name, value := range map[string]string{
+			"DB_HOST": cfg.Database.Host,
+			"DB_PORT": cfg.Database.Port,
+			"DB_USER": cfg.Database.User,
+			"DB_NAME": cfg.Database.Name,
+		} {
+			if value == "" {
+				problems = append(problems, name+" is empty")
+			}
+		}
+	default:
+		problems = append(problems, fmt.Sprintf("DB_DRIVER %q is unknown", cfg.Database.Driver))
+	}
+
+	if len(problems) == 0 {
+		return nil
+	}
+
+	return errors.New(strings.Join(problems, "; "))
+}
+
+// stringFromEnv read a string environment variable, falling back to def when unset
+func stringFromEnv(name string, def string) string {
+	if value := os.Getenv(name); value != "" {
+		return value
+	}
+
+	return def
+}
+
+// environment reads the variables of the configuration. The *FromEnv methods fall back to their
+// default on an invalid value, recording the variable so Validate reports it.
+type environment struct {
+	invalid []string
+}
+
+// reject records the variable name holding value, which is not what it expects
+func (env *environment) reject(name, value, expected string) {
+	env.invalid = append(env.invalid, fmt.Sprintf("%s %q is not %s", name, value, expected))
+}
+
+// intFromEnv read an integer environment variable, falling back to def when unset or invalid
+func (env *environment) intFromEnv(name string, def int) int {
+	if os.Getenv(name) == "" {
+		return def
+	}
+
+	value, erro := strconv.Atoi(os.Getenv(name))
 	if erro != nil {
-		APIPort = 8080
+		env.reject(name, os.Getenv(name), "an integer")
+		return def
+	}
+
+	return value
+}
+
+// floatFromEnv read a decimal environment variable, falling back to def when unset or invalid
+func (env *environment) floatFromEnv(name string, def float64) float64 {
+	if os.Getenv(name) == "" {
+		return def
 	}
 
-	DatabaseHost = os.Getenv("DB_HOST")
-	DatabasePort = os.Getenv("DB_PORT")
-	DatabaseStringConnection = fmt.Sprintf("%s:%s@tcp(%s:%s)/%s?tls=skip-verify&charset=utf8&parseTime=True&loc=Local",
-		os.Getenv("DB_USER"),
-		os.Getenv("DB_PASS"),
-		DatabaseHost,
-		DatabasePort,
-		os.Getenv("DB_NAME"),
+	value, erro := strconv.ParseFloat(os.Getenv(name), 64)
+	if erro != nil {
+		env.reject(name, os.Getenv(name), "a decimal number")
+		return def
+	}
+
+	return value
+}
+
+// durationFromEnv read a duration environment variable (e.g. "30s", "5m"), falling back to def when unset or invalid
+func (env *environment) durationFromEnv(name string, def time.Duration) time.Duration {
+	if os.Getenv(name) == "" {
+		return def
+	}
+
+	value, erro := time.ParseDuration(os.Getenv(name))
+	if erro != nil {
+		env.reject(name, os.Getenv(name), "a duration")
+		return def
+	}
+
+	return value
+}
+
+// dateFromEnv read a date environment variable (e.g. "2027-04-18", midnight UTC), falling back to def when unset or invalid
+func (env *environment) dateFromEnv(name string, def string) time.Time {
+	if os.Getenv(name) != "" {
+		if value, erro := time.Parse(time.DateOnly, os.Getenv(name)); erro == nil {
+			return value
+		}
+		env.reject(name, os.Getenv(name), "a date")
+	}
+
+	value, _ := time.Parse(time.DateOnly, def)
+	return value
+}
+
+// durationsFromEnv read a comma separated list of key=duration pairs (e.g. "GET /users=2s,POST /users=5s"),
+// skipping the invalid pairs
+func (env *environment) durationsFromEnv(name string) map[string]time.Duration {
+	durations := make(map[string]time.Duration)
+
+	for _, pair := range strings.Split(os.Getenv(name), ",") {
+		if strings.TrimSpace(pair) == "" {
+			continue
+		}
+
+		key, value, found := strings.Cut(pair, "=")
+		duration, erro := time.ParseDuration(strings.TrimSpace(value))
+		if !found || erro != nil {
+			env.reject(name, pair, "a <route>=<duration> pair")
+			continue
+		}
+
+		durations[strings.TrimSpace(key)] = duration
+	}
+
+	return durations
+}
+
+// sizesFromEnv read a comma separated list of key=bytes pairs (e.g. "POST /login=4096"), skipping the
+// invalid pairs and falling back to def when unset
+func (env *environment) sizesFromEnv(name string, def string) map[string]int64 {
+	sizes := make(map[string]int64)
+
+	for _, pair := range strings.Split(stringFromEnv(name, def), ",") {
+		if strings.TrimSpace(pair) == "" {
+			continue
+		}
+
+		key, value, found := strings.Cut(pair, "=")
+		size, erro := strconv.ParseInt(strings.TrimSpace(value), 10, 64)
+		if !found || erro != nil || size <= 0 {
+			env.reject(name, pair, "a <route>=<bytes> pair")
+			continue
+		}
+
+		sizes[strings.TrimSpace(key)] = size
+	}
+
+	return sizes
+}
+
+// listFromEnv read a comma separated list environment variable, falling back to def when unset
+func listFromEnv(name string, def string) []string {
+	var list []string
+	for _, item := range strings.Split(stringFromEnv(name, def), ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			list = append(list, item)
+		}
+	}
+
+	return list
+}
+
+// policyFromEnv read a rate limit policy environment variable, "<limit>/<window>" (e.g. "60/1m") or "off",
+// falling back to def when unset or invalid
+func (env *environment) policyFromEnv(name string, def string) RatePolicy {
+	if os.Getenv(name) != "" {
+		if policy, erro := parsePolicy(os.Getenv(name)); erro == nil {
+			return policy
+		}
+		env.reject(name, os.Getenv(name), "a <limit>/<window> policy nor off")
+	}
+
+	policy, _ := parsePolicy(def)
+	return policy
+}
+
+// policiesFromEnv read a comma separated list of key=policy pairs (e.g. "POST /login=10/1m,GET /users=off"),
+// skipping the invalid pairs and falling back to def when unset
+func (env *environment) policiesFromEnv(name string, def string) map[string]RatePolicy {
+	value := stringFromEnv(name, def)
+	policies := make(map[string]RatePolicy)
+
+	for _, pair := range strings.Split(value, ",") {
+		if strings.TrimSpace(pair) == "" {
+			continue
+		}
+
+		key, value, found := strings.Cut(pair, "=")
+		policy, erro := parsePolicy(strings.TrimSpace(value))
+		if !found || erro != nil {
+			env.reject(name, pair, "a <route>=<limit>/<window> pair")
+			continue
+		}
+
+		policies[strings.TrimSpace(key)] = policy
+	}
+
+	return policies
+}
+
+// parsePolicy parses "<limit>/<window>" or "off"
+func parsePolicy(value string) (RatePolicy, error) {
+	if value == "off" {
+		return RatePolicy{}, nil
+	}
+
+	limit, window, found := strings.Cut(value, "/")
+	if !found {
+		return RatePolicy{}, fmt.Errorf("rate limit policy %q is not <limit>/<window>", value)
+	}
+
+	var (
+		policy RatePolicy
+		erro   error
 	)
+	if policy.Limit, erro = strconv.Atoi(limit); erro != nil || policy.Limit <= 0 {
+		return RatePolicy{}, fmt.Errorf("rate limit policy %q has an invalid limit", value)
+	}
+	if policy.Window, erro = time.ParseDuration(window); erro != nil || policy.Window <= 0 {
+		return RatePolicy{}, fmt.Errorf("rate limit policy %q has an invalid window", value)
+	}
+
+	return policy, nil
+}
+
+// boolFromEnv read a boolean environment variable (e.g. "true", "1"), falling back to def when unset or invalid
+func (env *environment) boolFromEnv(name string, def bool) bool {
+	if os.Getenv(name) == "" {
+		return def
+	}
+
+	value, erro := strconv.ParseBool(os.Getenv(name))
+	if erro != nil {
+		env.reject(name, os.Getenv(name), "a boolean")
+		return def
+	}
 
-	SecretKey = []byte(os.Getenv("SECRET_KEY"))
+	return value
 }
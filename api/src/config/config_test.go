@@ -0,0 +1,85 @@
+/*
+Copyright 2022 Danilo S. Lopes.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at:
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package config
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+// The variables holding a value that cannot be parsed are reported, not replaced by their default silently
+func TestValidateReportsInvalidVariables(t *testing.T) {
+	t.Setenv("SECRET_KEY", "secret")
+	t.Setenv("LEGACY_ROUTES_DEPRECATION", "2026-10-18")
+	t.Setenv("DB_DRIVER", "memory")
+	if erro := Load().Validate(); erro != nil {
+		t.Fatalf("the default configuration is invalid: %v", erro)
+	}
+
+	invalid := map[string]string{
+		"DB_MAX_OPEN_CONNS":       "abc",
+		"TRACING_SAMPLE_RATIO":    "half",
+		"SHUTDOWN_TIMEOUT":        "30",
+		"LEGACY_ROUTES_SUNSET":    "tomorrow",
+		"DB_ROUTE_QUERY_TIMEOUTS": "GET /users=fast",
+		"BODY_ROUTE_MAX_SIZES":    "POST /login",
+		"RATE_LIMIT_ANONYMOUS":    "garbage",
+		"RATE_LIMIT_ROUTES":       "POST /login=10",
+		"CORS_ALLOW_CREDENTIALS":  "yes please",
+	}
+	for name, value := range invalid {
+		t.Setenv(name, value)
+	}
+
+	erro := Load().Validate()
+	if erro == nil {
+		t.Fatal("the invalid variables were accepted")
+	}
+	for name := range invalid {
+		if !strings.Contains(erro.Error(), name) {
+			t.Errorf("%s is not reported in %q", name, erro)
+		}
+	}
+}
+
+// The deprecation date of the legacy routes is only required while they are served, their sunset
+// defaulting to 6 months after it
+func TestLegacyRoutesDeprecation(t *testing.T) {
+	t.Setenv("SECRET_KEY", "secret")
+	t.Setenv("DB_DRIVER", "memory")
+
+	t.Setenv("LEGACY_ROUTES_DEPRECATION", "")
+	if erro := Load().Validate(); erro == nil || !strings.Contains(erro.Error(), "LEGACY_ROUTES_DEPRECATION is required") {
+		t.Fatalf("got %v, want the missing LEGACY_ROUTES_DEPRECATION reported", erro)
+	}
+
+	t.Setenv("LEGACY_ROUTES", "false")
+	if erro := Load().Validate(); erro != nil {
+		t.Fatalf("without the legacy routes got %v, want no deprecation date needed", erro)
+	}
+
+	t.Setenv("LEGACY_ROUTES", "true")
+	t.Setenv("LEGACY_ROUTES_DEPRECATION", "2026-01-31")
+	cfg := Load()
+	if erro := cfg.Validate(); erro != nil {
+		t.Fatal(erro)
+	}
+	if sunset := cfg.LegacyRoutes.Sunset.Format(time.DateOnly); sunset != "2026-07-31" {
+		t.Fatalf("got the sunset %s, want 6 months after the deprecation", sunset)
+	}
+}
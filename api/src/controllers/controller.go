@@ -0,0 +1,121 @@
+/*
+Copyright 2022 Danilo S. Lopes.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at:
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"api/src/config"
+	"api/src/models"
+	"api/src/pagination"
+	"api/src/prommetrics"
+	"api/src/repositories"
+	"api/src/requests"
+	"api/src/responses"
+	"context"
+	"errors"
+	"log/slog"
+	"net/http"
+)
+
+// Controller holds the dependencies shared by the API handlers
+type Controller struct {
+	config  config.Config
+	store   repositories.Store
+	metrics *prommetrics.Metrics
+	logger  *slog.Logger
+
+	paginator *pagination.Paginator
+
+	// set to 1 once the API started its graceful shutdown
+	draining int32
+}
+
+// New creates a Controller that reads and writes through the given store
+func New(cfg config.Config, store repositories.Store, metrics *prommetrics.Metrics, logger *slog.Logger) *Controller {
+	return &Controller{
+		config:  cfg,
+		store:   store,
+		metrics: metrics,
+		logger:  logger,
+
+		paginator: pagination.New(cfg.SecretKey, cfg.Pagination),
+	}
+}
+
+type versionKey struct{}
+
+// WithVersion returns a copy of ctx telling the handlers the version of the API the request was routed to
+func WithVersion(ctx context.Context, version int) context.Context {
+	return context.WithValue(ctx, versionKey{}, version)
+}
+
+// apiVersion returns the version of the API the request was routed to, 1 by default
+func apiVersion(ctx context.Context) int {
+	if version, ok := ctx.Value(versionKey{}).(int); ok {
+		return version
+	}
+
+	return 1
+}
+
+// requestedPage returns the page of the list r asks for, answering a 400 when its limit or cursor is invalid
+func (controller *Controller) requestedPage(w http.ResponseWriter, r *http.Request) (pagination.Page, bool) {
+	page, erro := controller.paginator.Parse(r)
+	if erro != nil {
+		code := responses.CodeInvalidCursor
+		if errors.Is(erro, pagination.ErrInvalidLimit) {
+			code = responses.CodeInvalidParameter
+		}
+
+		responses.Problem(w, http.StatusBadRequest, code, erro)
+		return pagination.Page{}, false
+	}
+
+	return page, true
+}
+
+// decode reads the JSON body of r into target, answering a 400 when it is not a single object holding
+// the fields accepted by the route
+func decode(w http.ResponseWriter, r *http.Request, target interface{}) bool {
+	erro := requests.Decode(r, target)
+	if erro == nil {
+		return true
+	}
+
+	var unknownErro *requests.UnknownFieldError
+	if errors.As(erro, &unknownErro) {
+		responses.InvalidFields(w, http.StatusBadRequest, responses.CodeUnknownField, erro,
+			[]responses.FieldError{{In: "body", Field: unknownErro.Field, Message: "the field is not accepted"}})
+		return false
+	}
+
+	responses.Problem(w, http.StatusBadRequest, responses.CodeInvalidBody, erro)
+	return false
+}
+
+// respondPage answers r with the items fetched for page, linking the pages around it in the Link header.
+// The version 1 answers the items alone, the next ones a models.Page.
+func respondPage[T pagination.Item](controller *Controller, w http.ResponseWriter, r *http.Request, page pagination.Page, items []T) {
+	items, previous, next := pagination.Slice(page, items)
+	previousURL, nextURL := controller.paginator.Link(w, r, previous, next)
+
+	if apiVersion(r.Context()) == 1 {
+		responses.JSON(w, http.StatusOK, items)
+		return
+	}
+
+	responses.JSON(w, http.StatusOK, models.Page[T]{Items: items, Previous: previousURL, Next: nextURL})
+}
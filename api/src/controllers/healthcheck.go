@@ -17,64 +17,53 @@ limitations under the License.
 package controllers
 
 import (
-	"api/src/config"
-	"api/src/database"
-	"api/src/prommetrics"
-	"api/src/repositories"
 	"api/src/responses"
 	"errors"
 	"net/http"
-	"time"
+	"sync/atomic"
 )
 
-// Ready validates if our API is live and can process the requests received
-func Live(w http.ResponseWriter, r *http.Request) {
-	prommetrics.PromRequestsCurrent.Inc()
-	now := time.Now()
+// StartDraining makes the readiness probe fail so no new traffic is routed to the API while it shuts down
+func (controller *Controller) StartDraining() {
+	atomic.StoreInt32(&controller.draining, 1)
+}
 
-	db, erro := database.Connect()
-	if erro != nil {
-		responses.Erro(now, w, http.StatusInternalServerError, erro)
-		return
-	}
-	repository := repositories.NewHealthcheckRepository(db)
-	if erro := repository.SimulateDatabaseInsert(); erro != nil {
-		responses.Erro(now, w, http.StatusInternalServerError, erro)
+// Ready validates if our API is live and can process the requests received
+func (controller *Controller) Live(w http.ResponseWriter, r *http.Request) {
+	repository := controller.store.Healthcheck
+	if erro := repository.SimulateDatabaseInsert(r.Context()); erro != nil {
+		responses.Erro(w, http.StatusInternalServerError, erro)
 		return
 	}
-	defer db.Close()
 
-	responses.JSON(now, w, http.StatusOK, nil)
+	responses.JSON(w, http.StatusOK, nil)
 }
 
 // Ready validates if our API is ready to receive network connection and provide his main functionality
-func Ready(w http.ResponseWriter, r *http.Request) {
-	prommetrics.PromRequestsCurrent.Inc()
-	now := time.Now()
-
-	var hosts = []string{
-		config.DatabaseHost,
+func (controller *Controller) Ready(w http.ResponseWriter, r *http.Request) {
+	if atomic.LoadInt32(&controller.draining) == 1 {
+		responses.Problem(w, http.StatusServiceUnavailable, responses.CodeShuttingDown, errors.New("the api is shutting down"))
+		return
 	}
 
-	db, erro := database.Connect()
-	if erro != nil {
-		responses.Erro(now, w, http.StatusInternalServerError, erro)
-		return
+	var hosts []string
+	if controller.config.Database.Host != "" {
+		hosts = append(hosts, controller.config.Database.Host)
 	}
-	repository := repositories.NewHealthcheckRepository(db)
+
+	repository := controller.store.Healthcheck
 
 	for _, host := range hosts {
-		if erro := repository.DNSResolver(host); erro != nil {
-			responses.Erro(now, w, http.StatusInternalServerError, errors.New(host))
+		if erro := repository.DNSResolver(r.Context(), host); erro != nil {
+			responses.Erro(w, http.StatusInternalServerError, errors.New(host))
 			return
 		}
 	}
 
-	if erro := repository.PingDatabase(); erro != nil {
-		responses.Erro(now, w, http.StatusInternalServerError, erro)
+	if erro := repository.PingDatabase(r.Context()); erro != nil {
+		responses.Erro(w, http.StatusInternalServerError, erro)
 		return
 	}
-	defer db.Close()
 
-	responses.JSON(now, w, http.StatusOK, nil)
+	responses.JSON(w, http.StatusOK, nil)
 }
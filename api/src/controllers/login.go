@@ -18,60 +18,51 @@ package controllers
 
 import (
 	"api/src/authentication"
-	"api/src/database"
 	"api/src/models"
-	"api/src/prommetrics"
-	"api/src/repositories"
 	"api/src/responses"
 	"api/src/security"
-	"encoding/json"
 	"errors"
-	"io/ioutil"
 	"net/http"
-	"time"
 )
 
 // Login authenticate an User
-func Login(w http.ResponseWriter, r *http.Request) {
-	prommetrics.PromRequestsCurrent.Inc()
-	now := time.Now()
-
-	body, erro := ioutil.ReadAll(r.Body)
-	if erro != nil {
-		responses.Erro(now, w, http.StatusUnprocessableEntity, erro)
+func (controller *Controller) Login(w http.ResponseWriter, r *http.Request) {
+	var user models.User
+	if !decode(w, r, &user) {
 		return
 	}
 
-	var user models.User
-	if erro := json.Unmarshal(body, &user); erro != nil {
-		responses.Erro(now, w, http.StatusBadRequest, erro)
+	// an unknown email fails like a wrong password, as slowly, not telling which emails are registered
+	repository := controller.store.Users
+	userFromDB, erro := repository.SearchByEmail(r.Context(), user.Email)
+	if erro != nil && !errors.Is(erro, models.ErrNotFound) {
+		responses.Erro(w, http.StatusInternalServerError, erro)
 		return
 	}
 
-	db, erro := database.Connect()
-	if erro != nil {
-		responses.Erro(now, w, http.StatusInternalServerError, erro)
-		return
+	found := erro == nil
+	if !found {
+		userFromDB.Pass = security.UnknownUserHash
 	}
 
-	repository := repositories.NewUsersRepository(db)
-	userFromDB, erro := repository.SearchByEmail(user.Email)
-	defer db.Close()
-	if erro != nil {
-		responses.Erro(now, w, http.StatusInternalServerError, erro)
+	if erro := security.ValidatePass(userFromDB.Pass, user.Pass); erro != nil || !found {
+		controller.logger.WarnContext(r.Context(), "login failed", "email", user.Email)
+		responses.Problem(w, http.StatusUnauthorized, responses.CodeInvalidCredentials, errors.New("incorrect password"))
 		return
 	}
 
-	if erro := security.ValidatePass(userFromDB.Pass, user.Pass); erro != nil {
-		responses.Erro(now, w, http.StatusUnauthorized, errors.New("incorrect password"))
+	if userFromDB.Disabled {
+		controller.logger.WarnContext(r.Context(), "login refused, the user is disabled", "login_user_id", userFromDB.ID)
+		responses.Problem(w, http.StatusForbidden, responses.CodeUserDisabled, errors.New("the user is disabled"))
 		return
 	}
 
-	token, erro := authentication.GenerateToken(userFromDB.ID)
+	token, erro := authentication.GenerateToken(controller.config.SecretKey, userFromDB.ID)
 	if erro != nil {
-		responses.Erro(now, w, http.StatusInternalServerError, erro)
+		responses.Erro(w, http.StatusInternalServerError, erro)
 		return
 	}
 
+	w.Header().Set("Content-Type", "text/plain; charset=utf-8")
 	w.Write([]byte(token))
 }
@@ -18,15 +18,9 @@ package controllers
 
 import (
 	"api/src/authentication"
-	"api/src/database"
 	"api/src/models"
-	"api/src/prommetrics"
-	"api/src/repositories"
 	"api/src/responses"
-	"encoding/json"
-	"errors"
 	"fmt"
-	"io/ioutil"
 	"net/http"
 	"strconv"
 	"time"
@@ -35,345 +29,260 @@ import (
 )
 
 // CreatePublication create a Publication in database
-func CreatePublication(w http.ResponseWriter, r *http.Request) {
-	prommetrics.PromRequestsCurrent.Inc()
+func (controller *Controller) CreatePublication(w http.ResponseWriter, r *http.Request) {
 	now := time.Now()
-	httpDuration := time.Since(now)
 
-	userID, erro := authentication.ExtractUserID(r)
+	userID, erro := authentication.ExtractUserID(r, controller.config.SecretKey)
 	if erro != nil {
-		responses.Erro(now, w, http.StatusUnauthorized, erro)
-		return
-	}
-
-	body, erro := ioutil.ReadAll(r.Body)
-	if erro != nil {
-		responses.Erro(now, w, http.StatusUnprocessableEntity, erro)
+		responses.Problem(w, http.StatusUnauthorized, responses.CodeInvalidToken, erro)
 		return
 	}
 
 	var publication models.Publication
-	if erro := json.Unmarshal(body, &publication); erro != nil {
-		responses.Erro(now, w, http.StatusBadRequest, erro)
-		return
-	}
-
-	db, erro := database.Connect()
-	if erro != nil {
-		responses.Erro(now, w, http.StatusInternalServerError, erro)
+	if !decode(w, r, &publication) {
 		return
 	}
 
 	publication.AuthorID = userID
 
 	if erro := publication.Prepare(); erro != nil {
-		responses.Erro(now, w, http.StatusBadRequest, erro)
+		responses.Fail(w, erro)
 		return
 	}
 
-	repository := repositories.NewPublicationRepository(db)
-	publication.ID, erro = repository.Create(publication)
-	defer db.Close()
+	repository := controller.store.Publications
+	publication.ID, erro = repository.Create(r.Context(), publication)
 	if erro != nil {
-		responses.Erro(now, w, http.StatusInternalServerError, erro)
+		responses.Fail(w, erro)
 		return
 	}
-	prommetrics.PromCountNewPublication.Inc()
-	prommetrics.PromTimeTookToCreatePublication.WithLabelValues(fmt.Sprintf("%d", http.StatusOK)).Observe(httpDuration.Seconds())
+	controller.metrics.CountNewPublication.Inc()
+	controller.logger.InfoContext(r.Context(), "publication created", "publication_id", publication.ID)
+	controller.metrics.TimeTookToCreatePublication.WithLabelValues(fmt.Sprintf("%d", http.StatusOK)).Observe(time.Since(now).Seconds())
 
-	responses.JSON(now, w, http.StatusCreated, publication)
+	responses.JSON(w, http.StatusCreated, publication)
 }
 
-// GetPublications return publications in feed
-func GetPublications(w http.ResponseWriter, r *http.Request) {
-	prommetrics.PromRequestsCurrent.Inc()
-	now := time.Now()
-
-	userID, erro := authentication.ExtractUserID(r)
+// GetPublications return a page of the publications in feed
+func (controller *Controller) GetPublications(w http.ResponseWriter, r *http.Request) {
+	userID, erro := authentication.ExtractUserID(r, controller.config.SecretKey)
 	if erro != nil {
-		responses.Erro(now, w, http.StatusUnauthorized, erro)
+		responses.Problem(w, http.StatusUnauthorized, responses.CodeInvalidToken, erro)
 		return
 	}
 
-	db, erro := database.Connect()
-	if erro != nil {
-		responses.Erro(now, w, http.StatusInternalServerError, erro)
+	page, valid := controller.requestedPage(w, r)
+	if !valid {
 		return
 	}
 
-	repository := repositories.NewPublicationRepository(db)
-	publications, erro := repository.Get(userID)
-	defer db.Close()
+	repository := controller.store.Publications
+	publications, erro := repository.Get(r.Context(), userID, page)
 	if erro != nil {
-		responses.Erro(now, w, http.StatusInternalServerError, erro)
+		responses.Fail(w, erro)
 		return
 	}
 
-	responses.JSON(now, w, http.StatusOK, publications)
+	respondPage(controller, w, r, page, publications)
 }
 
 // GetPublication return one Publication
-func GetPublication(w http.ResponseWriter, r *http.Request) {
-	prommetrics.PromRequestsCurrent.Inc()
-	now := time.Now()
-
+func (controller *Controller) GetPublication(w http.ResponseWriter, r *http.Request) {
 	params := mux.Vars(r)
 	publicationID, erro := strconv.ParseUint(params["publicationID"], 10, 64)
 	if erro != nil {
-		responses.Erro(now, w, http.StatusBadRequest, erro)
+		responses.Problem(w, http.StatusBadRequest, responses.CodeInvalidParameter, erro)
 		return
 	}
 
-	db, erro := database.Connect()
+	repository := controller.store.Publications
+	publication, erro := repository.SearchByID(r.Context(), publicationID)
 	if erro != nil {
-		responses.Erro(now, w, http.StatusInternalServerError, erro)
+		responses.Fail(w, erro)
 		return
 	}
 
-	repository := repositories.NewPublicationRepository(db)
-	publication, erro := repository.SearchByID(publicationID)
-	if erro != nil {
-		responses.Erro(now, w, http.StatusInternalServerError, erro)
-		return
-	}
-	defer db.Close()
-
-	responses.JSON(now, w, http.StatusOK, publication)
+	responses.JSON(w, http.StatusOK, publication)
 }
 
 // UpdatePublication updates one Publication
-func UpdatePublication(w http.ResponseWriter, r *http.Request) {
-	prommetrics.PromRequestsCurrent.Inc()
-	now := time.Now()
-
-	userID, erro := authentication.ExtractUserID(r)
+func (controller *Controller) UpdatePublication(w http.ResponseWriter, r *http.Request) {
+	userID, erro := authentication.ExtractUserID(r, controller.config.SecretKey)
 	if erro != nil {
-		responses.Erro(now, w, http.StatusUnauthorized, erro)
+		responses.Problem(w, http.StatusUnauthorized, responses.CodeInvalidToken, erro)
 		return
 	}
 
 	params := mux.Vars(r)
 	publicationID, erro := strconv.ParseUint(params["publicationID"], 10, 64)
 	if erro != nil {
-		responses.Erro(now, w, http.StatusBadRequest, erro)
+		responses.Problem(w, http.StatusBadRequest, responses.CodeInvalidParameter, erro)
 		return
 	}
 
-	db, erro := database.Connect()
-	if erro != nil {
-		responses.Erro(now, w, http.StatusInternalServerError, erro)
-		return
-	}
+	repository := controller.store.Publications
 
-	repository := repositories.NewPublicationRepository(db)
-
-	publicationDatabase, erro := repository.SearchByID(publicationID)
-	defer db.Close()
+	publicationDatabase, erro := repository.SearchByID(r.Context(), publicationID)
 	if erro != nil {
-		responses.Erro(now, w, http.StatusInternalServerError, erro)
+		responses.Fail(w, erro)
 		return
 	}
 
 	if publicationDatabase.AuthorID != userID {
-		responses.Erro(now, w, http.StatusForbidden, errors.New("is not possible to update publications from another user"))
-		return
-	}
-
-	body, erro := ioutil.ReadAll(r.Body)
-	if erro != nil {
-		responses.Erro(now, w, http.StatusUnprocessableEntity, erro)
+		responses.Fail(w, models.Forbidden(models.CodePublicationForbidden, "is not possible to update publications from another user"))
 		return
 	}
 
 	var publication models.Publication
-	if erro := json.Unmarshal(body, &publication); erro != nil {
-		responses.Erro(now, w, http.StatusBadRequest, erro)
+	if !decode(w, r, &publication) {
 		return
 	}
 
 	if erro := publication.Prepare(); erro != nil {
-		responses.Erro(now, w, http.StatusBadRequest, erro)
+		responses.Fail(w, erro)
 		return
 	}
 
-	if erro := repository.Update(publication.ID, publication); erro != nil {
-		responses.Erro(now, w, http.StatusInternalServerError, erro)
+	if erro := repository.Update(r.Context(), publicationID, publication); erro != nil {
+		responses.Fail(w, erro)
 		return
 	}
 
-	responses.JSON(now, w, http.StatusNoContent, nil)
+	responses.JSON(w, http.StatusNoContent, nil)
 }
 
 // DeletePublication delete one Publication
-func DeletePublication(w http.ResponseWriter, r *http.Request) {
-	prommetrics.PromRequestsCurrent.Inc()
+func (controller *Controller) DeletePublication(w http.ResponseWriter, r *http.Request) {
 	now := time.Now()
-	httpDuration := time.Since(now)
 
-	userID, erro := authentication.ExtractUserID(r)
+	userID, erro := authentication.ExtractUserID(r, controller.config.SecretKey)
 	if erro != nil {
-		responses.Erro(now, w, http.StatusUnauthorized, erro)
+		responses.Problem(w, http.StatusUnauthorized, responses.CodeInvalidToken, erro)
 		return
 	}
 
 	params := mux.Vars(r)
 	publicationID, erro := strconv.ParseUint(params["publicationID"], 10, 64)
 	if erro != nil {
-		responses.Erro(now, w, http.StatusBadRequest, erro)
-		return
-	}
-
-	db, erro := database.Connect()
-	if erro != nil {
-		responses.Erro(now, w, http.StatusInternalServerError, erro)
+		responses.Problem(w, http.StatusBadRequest, responses.CodeInvalidParameter, erro)
 		return
 	}
 
-	repository := repositories.NewPublicationRepository(db)
-	publicationDatabase, erro := repository.SearchByID(publicationID)
-	defer db.Close()
+	repository := controller.store.Publications
+	publicationDatabase, erro := repository.SearchByID(r.Context(), publicationID)
 	if erro != nil {
-		responses.Erro(now, w, http.StatusInternalServerError, erro)
+		responses.Fail(w, erro)
 		return
 	}
 
 	if publicationDatabase.AuthorID != userID {
-		responses.Erro(now, w, http.StatusForbidden, errors.New("is not possible to delete publications from another user"))
+		responses.Fail(w, models.Forbidden(models.CodePublicationForbidden, "is not possible to delete publications from another user"))
 		return
 	}
 
-	if erro := repository.Delete(publicationID); erro != nil {
-		responses.Erro(now, w, http.StatusInternalServerError, erro)
+	if erro := repository.Delete(r.Context(), publicationID); erro != nil {
+		responses.Fail(w, erro)
 		return
 	}
-	prommetrics.PromCountDeletePublication.Inc()
-	prommetrics.PromTimeTookToDeletePublication.WithLabelValues(fmt.Sprintf("%d", http.StatusOK)).Observe(httpDuration.Seconds())
+	controller.metrics.CountDeletePublication.Inc()
+	controller.logger.InfoContext(r.Context(), "publication deleted", "publication_id", publicationID)
+	controller.metrics.TimeTookToDeletePublication.WithLabelValues(fmt.Sprintf("%d", http.StatusOK)).Observe(time.Since(now).Seconds())
 
-	responses.JSON(now, w, http.StatusNoContent, nil)
+	responses.JSON(w, http.StatusNoContent, nil)
 }
 
-// GetUserPublications return all user publications
-func GetUserPublications(w http.ResponseWriter, r *http.Request) {
-	prommetrics.PromRequestsCurrent.Inc()
-	now := time.Now()
-
+// GetUserPublications return a page of the user publications
+func (controller *Controller) GetUserPublications(w http.ResponseWriter, r *http.Request) {
 	params := mux.Vars(r)
 	userID, erro := strconv.ParseUint(params["userID"], 10, 64)
 	if erro != nil {
-		responses.Erro(now, w, http.StatusBadRequest, erro)
+		responses.Problem(w, http.StatusBadRequest, responses.CodeInvalidParameter, erro)
 		return
 	}
 
-	db, erro := database.Connect()
-	if erro != nil {
-		responses.Erro(now, w, http.StatusInternalServerError, erro)
+	page, valid := controller.requestedPage(w, r)
+	if !valid {
 		return
 	}
 
-	repository := repositories.NewPublicationRepository(db)
-	publications, erro := repository.GetByUser(userID)
-	defer db.Close()
+	repository := controller.store.Publications
+	publications, erro := repository.GetByUser(r.Context(), userID, page)
 	if erro != nil {
-		responses.Erro(now, w, http.StatusInternalServerError, erro)
+		responses.Fail(w, erro)
 		return
 	}
 
-	responses.JSON(now, w, http.StatusOK, publications)
+	respondPage(controller, w, r, page, publications)
 }
 
 // LikePublication likes an publication
-func LikePublication(w http.ResponseWriter, r *http.Request) {
-	prommetrics.PromRequestsCurrent.Inc()
-	now := time.Now()
-
+func (controller *Controller) LikePublication(w http.ResponseWriter, r *http.Request) {
 	params := mux.Vars(r)
 	publicationID, erro := strconv.ParseUint(params["publicationID"], 10, 64)
 	if erro != nil {
-		responses.Erro(now, w, http.StatusBadRequest, erro)
-		return
-	}
-
-	likerID, erro := authentication.ExtractUserID(r)
-	if erro != nil {
-		responses.Erro(now, w, http.StatusUnauthorized, erro)
+		responses.Problem(w, http.StatusBadRequest, responses.CodeInvalidParameter, erro)
 		return
 	}
 
-	db, erro := database.Connect()
+	likerID, erro := authentication.ExtractUserID(r, controller.config.SecretKey)
 	if erro != nil {
-		responses.Erro(now, w, http.StatusInternalServerError, erro)
+		responses.Problem(w, http.StatusUnauthorized, responses.CodeInvalidToken, erro)
 		return
 	}
 
-	repository := repositories.NewPublicationRepository(db)
-	if erro := repository.LikePublication(publicationID, likerID); erro != nil {
-		responses.Erro(now, w, http.StatusInternalServerError, erro)
+	repository := controller.store.Publications
+	if erro := repository.LikePublication(r.Context(), publicationID, likerID); erro != nil {
+		responses.Fail(w, erro)
 		return
 	}
-	defer db.Close()
 
-	responses.JSON(now, w, http.StatusNoContent, nil)
+	responses.JSON(w, http.StatusNoContent, nil)
 }
 
 // UnLikePublication unlikes an publication
-func UnLikePublication(w http.ResponseWriter, r *http.Request) {
-	prommetrics.PromRequestsCurrent.Inc()
-	now := time.Now()
-
+func (controller *Controller) UnLikePublication(w http.ResponseWriter, r *http.Request) {
 	params := mux.Vars(r)
 	publicationID, erro := strconv.ParseUint(params["publicationID"], 10, 64)
 	if erro != nil {
-		responses.Erro(now, w, http.StatusBadRequest, erro)
-		return
-	}
-
-	unLikerID, erro := authentication.ExtractUserID(r)
-	if erro != nil {
-		responses.Erro(now, w, http.StatusUnauthorized, erro)
+		responses.Problem(w, http.StatusBadRequest, responses.CodeInvalidParameter, erro)
 		return
 	}
 
-	db, erro := database.Connect()
+	unLikerID, erro := authentication.ExtractUserID(r, controller.config.SecretKey)
 	if erro != nil {
-		responses.Erro(now, w, http.StatusInternalServerError, erro)
+		responses.Problem(w, http.StatusUnauthorized, responses.CodeInvalidToken, erro)
 		return
 	}
 
-	repository := repositories.NewPublicationRepository(db)
-	if erro := repository.UnLikePublication(publicationID, unLikerID); erro != nil {
-		responses.Erro(now, w, http.StatusInternalServerError, erro)
+	repository := controller.store.Publications
+	if erro := repository.UnLikePublication(r.Context(), publicationID, unLikerID); erro != nil {
+		responses.Fail(w, erro)
 		return
 	}
-	defer db.Close()
 
-	responses.JSON(now, w, http.StatusNoContent, nil)
+	responses.JSON(w, http.StatusNoContent, nil)
 }
 
-// GetLikers return all users who liked an publication
-func GetLikers(w http.ResponseWriter, r *http.Request) {
-	prommetrics.PromRequestsCurrent.Inc()
-	now := time.Now()
-
+// GetLikers return a page of the users who liked an publication
+func (controller *Controller) GetLikers(w http.ResponseWriter, r *http.Request) {
 	params := mux.Vars(r)
 	publicationID, erro := strconv.ParseUint(params["publicationID"], 10, 64)
 	if erro != nil {
-		responses.Erro(now, w, http.StatusBadRequest, erro)
+		responses.Problem(w, http.StatusBadRequest, responses.CodeInvalidParameter, erro)
 		return
 	}
 
-	db, erro := database.Connect()
-	if erro != nil {
-		responses.Erro(now, w, http.StatusInternalServerError, erro)
+	page, valid := controller.requestedPage(w, r)
+	if !valid {
 		return
 	}
 
-	repository := repositories.NewPublicationRepository(db)
-	users, erro := repository.GetLikers(publicationID)
-	defer db.Close()
+	repository := controller.store.Publications
+	users, erro := repository.GetLikers(r.Context(), publicationID, page)
 	if erro != nil {
-		responses.Erro(now, w, http.StatusInternalServerError, erro)
+		responses.Fail(w, erro)
 		return
 	}
 
-	responses.JSON(now, w, http.StatusOK, users)
+	respondPage(controller, w, r, page, users)
 }
@@ -18,16 +18,11 @@ package controllers
 
 import (
 	"api/src/authentication"
-	"api/src/database"
 	"api/src/models"
-	"api/src/prommetrics"
-	"api/src/repositories"
 	"api/src/responses"
 	"api/src/security"
-	"encoding/json"
 	"errors"
 	"fmt"
-	"io/ioutil"
 	"net/http"
 	"strconv"
 	"strings"
@@ -37,427 +32,325 @@ import (
 )
 
 // CreateUser creates a new "User" in database
-func CreateUser(w http.ResponseWriter, r *http.Request) {
-	prommetrics.PromRequestsCurrent.Inc()
+func (controller *Controller) CreateUser(w http.ResponseWriter, r *http.Request) {
 	now := time.Now()
-	httpDuration := time.Since(now)
-
-	body, erro := ioutil.ReadAll(r.Body)
-	if erro != nil {
-		responses.Erro(now, w, http.StatusUnprocessableEntity, erro)
-		return
-	}
 
 	var user models.User
-	if erro = json.Unmarshal(body, &user); erro != nil {
-		responses.Erro(now, w, http.StatusBadRequest, erro)
+	if !decode(w, r, &user) {
 		return
 	}
 
 	if erro := user.Prepare("registration"); erro != nil {
-		responses.Erro(now, w, http.StatusBadRequest, erro)
-		return
-	}
-
-	db, erro := database.Connect()
-	if erro != nil {
-		responses.Erro(now, w, http.StatusInternalServerError, erro)
+		responses.Fail(w, erro)
 		return
 	}
 
-	repository := repositories.NewUsersRepository(db)
-	user.ID, erro = repository.Create(user)
-	defer db.Close()
+	repository := controller.store.Users
+	var erro error
+	user.ID, erro = repository.Create(r.Context(), user)
 	if erro != nil {
-		responses.Erro(now, w, http.StatusInternalServerError, erro)
+		responses.Fail(w, erro)
 		return
 	}
-	prommetrics.PromCountCreatedUsers.Inc()
-	prommetrics.PromTimeTookToCreateUser.WithLabelValues(fmt.Sprintf("%d", http.StatusOK)).Observe(httpDuration.Seconds())
+	controller.metrics.CountCreatedUsers.Inc()
+	controller.logger.InfoContext(r.Context(), "user created", "created_user_id", user.ID)
+	controller.metrics.TimeTookToCreateUser.WithLabelValues(fmt.Sprintf("%d", http.StatusOK)).Observe(time.Since(now).Seconds())
 
-	responses.JSON(now, w, http.StatusCreated, user)
+	// the password hash never leaves the API
+	user.Pass = ""
+	responses.JSON(w, http.StatusCreated, user)
 }
 
-// GetUsers with given "nick" or "email", will return all "Users" from database
-func GetUsers(w http.ResponseWriter, r *http.Request) {
-	prommetrics.PromRequestsCurrent.Inc()
-	now := time.Now()
-
+// GetUsers with given "nick" or "email", will return a page of the "Users" from database
+func (controller *Controller) GetUsers(w http.ResponseWriter, r *http.Request) {
 	nameOrNick := strings.ToLower(
 		r.URL.Query().Get("user"),
 	)
 
-	db, erro := database.Connect()
-	if erro != nil {
-		responses.Erro(now, w, http.StatusInternalServerError, erro)
+	page, valid := controller.requestedPage(w, r)
+	if !valid {
 		return
 	}
 
-	repository := repositories.NewUsersRepository(db)
-	users, erro := repository.Search(nameOrNick)
-	defer db.Close()
+	repository := controller.store.Users
+	users, erro := repository.Search(r.Context(), nameOrNick, page)
 	if erro != nil {
-		responses.Erro(now, w, http.StatusInternalServerError, erro)
+		responses.Fail(w, erro)
 		return
 	}
 
-	responses.JSON(now, w, http.StatusOK, users)
+	respondPage(controller, w, r, page, users)
 }
 
 // GetUser return specific "User" from database
-func GetUser(w http.ResponseWriter, r *http.Request) {
-	prommetrics.PromRequestsCurrent.Inc()
-	now := time.Now()
-
+func (controller *Controller) GetUser(w http.ResponseWriter, r *http.Request) {
 	params := mux.Vars(r)
 	userID, erro := strconv.ParseUint(params["userID"], 10, 64)
 	if erro != nil {
-		responses.Erro(now, w, http.StatusBadRequest, erro)
-		return
-	}
-
-	db, erro := database.Connect()
-	if erro != nil {
-		responses.Erro(now, w, http.StatusInternalServerError, erro)
+		responses.Problem(w, http.StatusBadRequest, responses.CodeInvalidParameter, erro)
 		return
 	}
 
-	repository := repositories.NewUsersRepository(db)
-	user, erro := repository.SearchByID(userID)
-	defer db.Close()
+	repository := controller.store.Users
+	user, erro := repository.SearchByID(r.Context(), userID)
 	if erro != nil {
-		responses.Erro(now, w, http.StatusInternalServerError, erro)
+		responses.Fail(w, erro)
 		return
 	}
 
-	responses.JSON(now, w, http.StatusOK, user)
+	responses.JSON(w, http.StatusOK, user)
 }
 
 // UpdateUser upadate "User" attributes in database
-func UpdateUser(w http.ResponseWriter, r *http.Request) {
-	prommetrics.PromRequestsCurrent.Inc()
-	now := time.Now()
-
+func (controller *Controller) UpdateUser(w http.ResponseWriter, r *http.Request) {
 	params := mux.Vars(r)
 	userID, erro := strconv.ParseUint(params["userID"], 10, 64)
 	if erro != nil {
-		responses.Erro(now, w, http.StatusBadRequest, erro)
+		responses.Problem(w, http.StatusBadRequest, responses.CodeInvalidParameter, erro)
 		return
 	}
 
-	userIDInsideToken, erro := authentication.ExtractUserID(r)
+	userIDInsideToken, erro := authentication.ExtractUserID(r, controller.config.SecretKey)
 	if erro != nil {
-		responses.Erro(now, w, http.StatusUnauthorized, erro)
+		responses.Problem(w, http.StatusUnauthorized, responses.CodeInvalidToken, erro)
 		return
 	}
 
 	if userID != userIDInsideToken {
-		responses.Erro(now, w, http.StatusForbidden, errors.New("permission denied"))
-		return
-	}
-
-	body, erro := ioutil.ReadAll(r.Body)
-	if erro != nil {
-		responses.Erro(now, w, http.StatusUnprocessableEntity, erro)
+		responses.Fail(w, models.Forbidden(models.CodeUserForbidden, "permission denied"))
 		return
 	}
 
 	var user models.User
-	if erro := json.Unmarshal(body, &user); erro != nil {
-		responses.Erro(now, w, http.StatusBadRequest, erro)
+	if !decode(w, r, &user) {
 		return
 	}
 
 	if erro := user.Prepare("edit"); erro != nil {
-		responses.Erro(now, w, http.StatusBadRequest, erro)
+		responses.Fail(w, erro)
 		return
 	}
 
-	db, erro := database.Connect()
-	if erro != nil {
-		responses.Erro(now, w, http.StatusInternalServerError, erro)
-		return
-	}
-
-	repository := repositories.NewUsersRepository(db)
-	if erro := repository.Update(userID, user); erro != nil {
-		responses.Erro(now, w, http.StatusInternalServerError, erro)
+	repository := controller.store.Users
+	if erro := repository.Update(r.Context(), userID, user); erro != nil {
+		responses.Fail(w, erro)
 		return
 	}
-	defer db.Close()
 
-	responses.JSON(now, w, http.StatusNoContent, nil)
+	responses.JSON(w, http.StatusNoContent, nil)
 }
 
 // DeleteUser deletes a "User" in database
-func DeleteUser(w http.ResponseWriter, r *http.Request) {
-	prommetrics.PromRequestsCurrent.Inc()
+func (controller *Controller) DeleteUser(w http.ResponseWriter, r *http.Request) {
 	now := time.Now()
-	httpDuration := time.Since(now)
 
 	params := mux.Vars(r)
 	userID, erro := strconv.ParseUint(params["userID"], 10, 64)
 	if erro != nil {
-		responses.Erro(now, w, http.StatusBadRequest, erro)
+		responses.Problem(w, http.StatusBadRequest, responses.CodeInvalidParameter, erro)
 		return
 	}
 
-	userIDInsideToken, erro := authentication.ExtractUserID(r)
+	userIDInsideToken, erro := authentication.ExtractUserID(r, controller.config.SecretKey)
 	if erro != nil {
-		responses.Erro(now, w, http.StatusUnauthorized, erro)
+		responses.Problem(w, http.StatusUnauthorized, responses.CodeInvalidToken, erro)
 		return
 	}
 
 	if userID != userIDInsideToken {
-		responses.Erro(now, w, http.StatusForbidden, errors.New("permission denied"))
-	}
-
-	db, erro := database.Connect()
-	if erro != nil {
-		responses.Erro(now, w, http.StatusInternalServerError, erro)
+		responses.Fail(w, models.Forbidden(models.CodeUserForbidden, "permission denied"))
 		return
 	}
 
-	repository := repositories.NewUsersRepository(db)
-	if erro := repository.Delete(userID); erro != nil {
-		responses.Erro(now, w, http.StatusInternalServerError, erro)
+	repository := controller.store.Users
+	if erro := repository.Delete(r.Context(), userID); erro != nil {
+		responses.Fail(w, erro)
 		return
 	}
-	defer db.Close()
-	prommetrics.PromCountDeletedUsers.Inc()
-	prommetrics.PromTimeTookToDeleteUser.WithLabelValues(fmt.Sprintf("%d", http.StatusOK)).Observe(httpDuration.Seconds())
+	controller.metrics.CountDeletedUsers.Inc()
+	controller.logger.InfoContext(r.Context(), "user deleted", "deleted_user_id", userID)
+	controller.metrics.TimeTookToDeleteUser.WithLabelValues(fmt.Sprintf("%d", http.StatusOK)).Observe(time.Since(now).Seconds())
 
-	responses.JSON(now, w, http.StatusNoContent, nil)
+	responses.JSON(w, http.StatusNoContent, nil)
 }
 
 // FollowUser permits an "User" to "Follow" another "User"
-func FollowUser(w http.ResponseWriter, r *http.Request) {
-	prommetrics.PromRequestsCurrent.Inc()
-	now := time.Now()
-
-	followerID, erro := authentication.ExtractUserID(r)
+func (controller *Controller) FollowUser(w http.ResponseWriter, r *http.Request) {
+	followerID, erro := authentication.ExtractUserID(r, controller.config.SecretKey)
 	if erro != nil {
-		responses.Erro(now, w, http.StatusUnauthorized, erro)
+		responses.Problem(w, http.StatusUnauthorized, responses.CodeInvalidToken, erro)
 		return
 	}
 
 	params := mux.Vars(r)
 	userID, erro := strconv.ParseUint(params["userID"], 10, 64)
 	if erro != nil {
-		responses.Erro(now, w, http.StatusBadRequest, erro)
+		responses.Problem(w, http.StatusBadRequest, responses.CodeInvalidParameter, erro)
 		return
 	}
 
 	if followerID == userID {
-		responses.Erro(now, w, http.StatusForbidden, errors.New("is not possible to follow itself"))
-		return
-	}
-
-	db, erro := database.Connect()
-	if erro != nil {
-		responses.Erro(now, w, http.StatusInternalServerError, erro)
+		responses.Fail(w, models.Forbidden(models.CodeUserFollowSelf, "is not possible to follow itself"))
 		return
 	}
 
-	repository := repositories.NewUsersRepository(db)
-	if erro := repository.Follow(userID, followerID); erro != nil {
-		responses.Erro(now, w, http.StatusInternalServerError, erro)
+	repository := controller.store.Users
+	if erro := repository.Follow(r.Context(), userID, followerID); erro != nil {
+		responses.Fail(w, erro)
 		return
 	}
-	defer db.Close()
 
-	responses.JSON(now, w, http.StatusNoContent, nil)
+	responses.JSON(w, http.StatusNoContent, nil)
 }
 
 // UnFollowUser permits an "User" to "Unfollow" another "User"
-func UnFollowUser(w http.ResponseWriter, r *http.Request) {
-	prommetrics.PromRequestsCurrent.Inc()
-	now := time.Now()
-
-	followerID, erro := authentication.ExtractUserID(r)
+func (controller *Controller) UnFollowUser(w http.ResponseWriter, r *http.Request) {
+	followerID, erro := authentication.ExtractUserID(r, controller.config.SecretKey)
 	if erro != nil {
-		responses.Erro(now, w, http.StatusUnauthorized, erro)
+		responses.Problem(w, http.StatusUnauthorized, responses.CodeInvalidToken, erro)
 		return
 	}
 
 	params := mux.Vars(r)
 	userID, erro := strconv.ParseUint(params["userID"], 10, 64)
 	if erro != nil {
-		responses.Erro(now, w, http.StatusBadRequest, erro)
+		responses.Problem(w, http.StatusBadRequest, responses.CodeInvalidParameter, erro)
 		return
 	}
 
 	if followerID == userID {
-		responses.Erro(now, w, http.StatusForbidden, errors.New("is not possible to unfollow itself"))
+		responses.Fail(w, models.Forbidden(models.CodeUserFollowSelf, "is not possible to unfollow itself"))
 		return
 	}
 
-	db, erro := database.Connect()
-	if erro != nil {
-		responses.Erro(now, w, http.StatusInternalServerError, erro)
-		return
-	}
-
-	repository := repositories.NewUsersRepository(db)
-	if erro := repository.UnFollow(userID, followerID); erro != nil {
-		responses.Erro(now, w, http.StatusInternalServerError, erro)
+	repository := controller.store.Users
+	if erro := repository.UnFollow(r.Context(), userID, followerID); erro != nil {
+		responses.Fail(w, erro)
 		return
 	}
-	defer db.Close()
 
-	responses.JSON(now, w, http.StatusNoContent, nil)
+	responses.JSON(w, http.StatusNoContent, nil)
 }
 
-// GetFollowers return all "Followers" from specific "User"
-func GetFollowers(w http.ResponseWriter, r *http.Request) {
-	prommetrics.PromRequestsCurrent.Inc()
-	now := time.Now()
-
+// GetFollowers return a page of the "Followers" from specific "User"
+func (controller *Controller) GetFollowers(w http.ResponseWriter, r *http.Request) {
 	params := mux.Vars(r)
 	userID, erro := strconv.ParseUint(params["userID"], 10, 64)
 	if erro != nil {
-		responses.Erro(now, w, http.StatusBadRequest, erro)
+		responses.Problem(w, http.StatusBadRequest, responses.CodeInvalidParameter, erro)
 		return
 	}
 
-	db, erro := database.Connect()
-	if erro != nil {
-		responses.Erro(now, w, http.StatusInternalServerError, erro)
+	page, valid := controller.requestedPage(w, r)
+	if !valid {
 		return
 	}
 
-	repository := repositories.NewUsersRepository(db)
-	followers, erro := repository.GetFollowers(userID)
-	defer db.Close()
+	repository := controller.store.Users
+	followers, erro := repository.GetFollowers(r.Context(), userID, page)
 	if erro != nil {
-		responses.Erro(now, w, http.StatusInternalServerError, erro)
+		responses.Fail(w, erro)
 		return
 	}
 
-	responses.JSON(now, w, http.StatusOK, followers)
+	respondPage(controller, w, r, page, followers)
 }
 
-// GetFollowing return all "Users" a "User" is "Following"
-func GetFollowing(w http.ResponseWriter, r *http.Request) {
-	prommetrics.PromRequestsCurrent.Inc()
-	now := time.Now()
-
+// GetFollowing return a page of the "Users" a "User" is "Following"
+func (controller *Controller) GetFollowing(w http.ResponseWriter, r *http.Request) {
 	params := mux.Vars(r)
 	userID, erro := strconv.ParseUint(params["userID"], 10, 64)
 	if erro != nil {
-		responses.Erro(now, w, http.StatusBadRequest, erro)
+		responses.Problem(w, http.StatusBadRequest, responses.CodeInvalidParameter, erro)
 		return
 	}
 
-	db, erro := database.Connect()
-	if erro != nil {
-		responses.Erro(now, w, http.StatusInternalServerError, erro)
+	page, valid := controller.requestedPage(w, r)
+	if !valid {
 		return
 	}
 
-	repository := repositories.NewUsersRepository(db)
-	users, erro := repository.GetFollowing(userID)
-	defer db.Close()
+	repository := controller.store.Users
+	users, erro := repository.GetFollowing(r.Context(), userID, page)
 	if erro != nil {
-		responses.Erro(now, w, http.StatusInternalServerError, erro)
+		responses.Fail(w, erro)
 		return
 	}
 
-	responses.JSON(now, w, http.StatusOK, users)
+	respondPage(controller, w, r, page, users)
 }
 
 // UpdatePass update an "User" password
-func UpdatePass(w http.ResponseWriter, r *http.Request) {
-	prommetrics.PromRequestsCurrent.Inc()
-	now := time.Now()
-
-	userIDInsideToken, erro := authentication.ExtractUserID(r)
+func (controller *Controller) UpdatePass(w http.ResponseWriter, r *http.Request) {
+	userIDInsideToken, erro := authentication.ExtractUserID(r, controller.config.SecretKey)
 	if erro != nil {
-		responses.Erro(now, w, http.StatusUnauthorized, erro)
+		responses.Problem(w, http.StatusUnauthorized, responses.CodeInvalidToken, erro)
 		return
 	}
 
 	params := mux.Vars(r)
 	userID, erro := strconv.ParseUint(params["userID"], 10, 64)
 	if erro != nil {
-		responses.Erro(now, w, http.StatusBadRequest, erro)
+		responses.Problem(w, http.StatusBadRequest, responses.CodeInvalidParameter, erro)
 		return
 	}
 
 	if userIDInsideToken != userID {
-		responses.Erro(now, w, http.StatusForbidden, errors.New("is only allowed to update your own password"))
-		return
-	}
-
-	body, erro := ioutil.ReadAll(r.Body)
-	if erro != nil {
-		responses.Erro(now, w, http.StatusBadRequest, erro)
+		responses.Fail(w, models.Forbidden(models.CodeUserForbidden, "is only allowed to update your own password"))
 		return
 	}
 
 	var pass models.Pass
-	if erro := json.Unmarshal(body, &pass); erro != nil {
-		responses.Erro(now, w, http.StatusBadRequest, erro)
+	if !decode(w, r, &pass) {
 		return
 	}
 
-	db, erro := database.Connect()
+	repository := controller.store.Users
+	userPassHash, erro := repository.GetUserPass(r.Context(), userID)
 	if erro != nil {
-		responses.Erro(now, w, http.StatusInternalServerError, erro)
+		responses.Fail(w, erro)
 		return
 	}
 
-	repository := repositories.NewUsersRepository(db)
-	userPassHash, erro := repository.GetUserPass(userID)
-	if erro != nil {
-		responses.Erro(now, w, http.StatusInternalServerError, erro)
-	}
-
 	if erro := security.ValidatePass(userPassHash, pass.Current); erro != nil {
-		responses.Erro(now, w, http.StatusUnauthorized, errors.New("the password is incorrect"))
+		responses.Problem(w, http.StatusUnauthorized, responses.CodeUserWrongPassword, errors.New("the password is incorrect"))
 		return
 	}
 
 	hashedPass, erro := security.Hash(pass.New)
 	if erro != nil {
-		responses.Erro(now, w, http.StatusBadRequest, erro)
+		responses.Fail(w, erro)
 		return
 	}
 
-	if erro := repository.UpadateUserPass(userID, string(hashedPass)); erro != nil {
-		responses.Erro(now, w, http.StatusInternalServerError, erro)
+	if erro := repository.UpadateUserPass(r.Context(), userID, string(hashedPass)); erro != nil {
+		responses.Fail(w, erro)
 		return
 	}
-	defer db.Close()
 
-	responses.JSON(now, w, http.StatusNoContent, nil)
+	responses.JSON(w, http.StatusNoContent, nil)
 }
 
-// LikedPublications return all publications a user liked
-func LikedPublications(w http.ResponseWriter, r *http.Request) {
-	prommetrics.PromRequestsCurrent.Inc()
-	now := time.Now()
-
+// LikedPublications return a page of the publications a user liked
+func (controller *Controller) LikedPublications(w http.ResponseWriter, r *http.Request) {
 	params := mux.Vars(r)
 	userID, erro := strconv.ParseUint(params["userID"], 10, 64)
 	if erro != nil {
-		responses.Erro(now, w, http.StatusBadRequest, erro)
+		responses.Problem(w, http.StatusBadRequest, responses.CodeInvalidParameter, erro)
 		return
 	}
 
-	db, erro := database.Connect()
-	if erro != nil {
-		responses.Erro(now, w, http.StatusInternalServerError, erro)
+	page, valid := controller.requestedPage(w, r)
+	if !valid {
 		return
 	}
 
-	repository := repositories.NewUsersRepository(db)
-	publications, erro := repository.LikedPublications(userID)
-	defer db.Close()
+	repository := controller.store.Users
+	publications, erro := repository.LikedPublications(r.Context(), userID, page)
 	if erro != nil {
-		responses.Erro(now, w, http.StatusInternalServerError, erro)
+		responses.Fail(w, erro)
 		return
 	}
 
-	responses.JSON(now, w, http.StatusOK, publications)
+	respondPage(controller, w, r, page, publications)
 }
@@ -19,16 +19,32 @@ package database
 import (
 	"api/src/config"
 	"database/sql"
+	"log/slog"
+
+	"go.opentelemetry.io/otel/trace"
 
 	_ "github.com/go-sql-driver/mysql"
+	_ "github.com/lib/pq"
+	_ "modernc.org/sqlite"
 )
 
-// Connect open mysql database tcp connection.
-func Connect() (*sql.DB, error) {
-	db, erro := sql.Open("mysql", config.DatabaseStringConnection)
+// Connect open the database connection pool shared by the whole application.
+// Every query gets a span, is logged at debug level and its failures at error level.
+func Connect(settings config.Database, logger *slog.Logger, tracer trace.Tracer) (*DB, error) {
+	db, erro := sql.Open(settings.Driver, settings.StringConnection())
 	if erro != nil {
 		return nil, erro
 	}
 
-	return db, nil
+	db.SetMaxOpenConns(settings.MaxOpenConns)
+	db.SetMaxIdleConns(settings.MaxIdleConns)
+	db.SetConnMaxLifetime(settings.ConnMaxLifetime)
+	db.SetConnMaxIdleTime(settings.ConnMaxIdleTime)
+
+	return &DB{
+		DB:      db,
+		Dialect: Dialect(settings.Driver),
+		Logger:  logger,
+		Tracer:  tracer,
+	}, nil
 }
@@ -0,0 +1,253 @@
+/*
+Copyright 2022 Danilo S. Lopes.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at:
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package database
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"log/slog"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// Dialect hides the SQL syntax differences between the supported database drivers
+type Dialect string
+
+const (
+	MySQL    Dialect = "mysql"
+	SQLite   Dialect = "sqlite"
+	Postgres Dialect = "postgres"
+)
+
+// Rebind translates the ? placeholders of query into the dialect syntax ($1, $2... on PostgreSQL)
+func (dialect Dialect) Rebind(query string) string {
+	if dialect != Postgres || !strings.Contains(query, "?") {
+		return query
+	}
+
+	var (
+		rebound  strings.Builder
+		position int
+		quoted   bool
+	)
+
+	for _, char := range query {
+		switch {
+		case char == '\'':
+			quoted = !quoted
+		case char == '?' && !quoted:
+			position++
+			rebound.WriteString("$" + strconv.Itoa(position))
+			continue
+		}
+
+		rebound.WriteRune(char)
+	}
+
+	return rebound.String()
+}
+
+// InsertIgnore returns an INSERT statement that silently skips the rows violating a unique constraint
+func (dialect Dialect) InsertIgnore(table, columns, values string) string {
+	if dialect == MySQL {
+		return fmt.Sprintf("INSERT IGNORE INTO %s (%s) VALUES (%s)", table, columns, values)
+	}
+
+	return fmt.Sprintf("INSERT INTO %s (%s) VALUES (%s) ON CONFLICT DO NOTHING", table, columns, values)
+}
+
+// Timestamp returns t as the dialect compares it with the TIMESTAMP columns. SQLite keeps them
+// as text, so t is formatted like CURRENT_TIMESTAMP stores them.
+func (dialect Dialect) Timestamp(t time.Time) interface{} {
+	if dialect == SQLite {
+		return t.UTC().Format("2006-01-02 15:04:05.999999999")
+	}
+
+	return t
+}
+
+// TransactionalDDL tells whether schema changes can be rolled back inside a transaction
+func (dialect Dialect) TransactionalDDL() bool {
+	return dialect != MySQL
+}
+
+// DB is the connection pool shared by the repositories. The queries are written
+// with ? placeholders and translated into the dialect of the driver. Only the
+// context aware methods are translated, so a cancelled request aborts its queries.
+type DB struct {
+	*sql.DB
+	Dialect Dialect
+	Logger  *slog.Logger
+	Tracer  trace.Tracer
+}
+
+// PrepareContext creates a prepared statement for query
+func (db *DB) PrepareContext(ctx context.Context, query string) (*sql.Stmt, error) {
+	ctx, done := startQuery(ctx, db.Logger, db.Tracer, db.Dialect, query)
+	statement, erro := db.DB.PrepareContext(ctx, db.Dialect.Rebind(query))
+	done(erro)
+	return statement, erro
+}
+
+// QueryContext executes a query that returns rows, its span ending once the rows are closed
+func (db *DB) QueryContext(ctx context.Context, query string, args ...interface{}) (*Rows, error) {
+	ctx, done := startQuery(ctx, db.Logger, db.Tracer, db.Dialect, query)
+	rows, erro := db.DB.QueryContext(ctx, db.Dialect.Rebind(query), args...)
+	if erro != nil {
+		done(erro)
+		return nil, erro
+	}
+
+	return &Rows{Rows: rows, done: done}, nil
+}
+
+// Rows are the rows of a query, whose span and log cover reading them until they are closed
+type Rows struct {
+	*sql.Rows
+
+	done   func(erro error)
+	closed sync.Once
+}
+
+// Close closes the rows and ends the query span, failed when the iteration did
+func (rows *Rows) Close() error {
+	erro := rows.Rows.Close()
+	rows.closed.Do(func() {
+		rows.done(rows.Rows.Err())
+	})
+
+	return erro
+}
+
+// QueryRowContext executes a query that is expected to return at most one row. Its span only
+// covers the round trip, the row being read by Scan afterwards.
+func (db *DB) QueryRowContext(ctx context.Context, query string, args ...interface{}) *sql.Row {
+	ctx, done := startQuery(ctx, db.Logger, db.Tracer, db.Dialect, query)
+	row := db.DB.QueryRowContext(ctx, db.Dialect.Rebind(query), args...)
+	done(row.Err())
+	return row
+}
+
+// ExecContext executes a query without returning any rows
+func (db *DB) ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error) {
+	ctx, done := startQuery(ctx, db.Logger, db.Tracer, db.Dialect, query)
+	result, erro := db.DB.ExecContext(ctx, db.Dialect.Rebind(query), args...)
+	done(erro)
+	return result, erro
+}
+
+// BeginTx starts a transaction whose queries are translated as well
+func (db *DB) BeginTx(ctx context.Context, opts *sql.TxOptions) (*Tx, error) {
+	tx, erro := db.DB.BeginTx(ctx, opts)
+	if erro != nil {
+		return nil, erro
+	}
+
+	return &Tx{tx, db.Dialect, db.Logger, db.Tracer}, nil
+}
+
+// InsertReturningID runs an INSERT statement and returns the id generated for the new row.
+// PostgreSQL has no LastInsertId, so the id is read back with RETURNING id.
+func (db *DB) InsertReturningID(ctx context.Context, query string, args ...interface{}) (uint64, error) {
+	if db.Dialect == Postgres {
+		var ID uint64
+		if erro := db.QueryRowContext(ctx, query+" RETURNING id", args...).Scan(&ID); erro != nil {
+			return 0, erro
+		}
+
+		return ID, nil
+	}
+
+	result, erro := db.ExecContext(ctx, query, args...)
+	if erro != nil {
+		return 0, erro
+	}
+
+	lastID, erro := result.LastInsertId()
+	if erro != nil {
+		return 0, erro
+	}
+
+	return uint64(lastID), nil
+}
+
+// Tx is a transaction translating its queries into the dialect of the driver
+type Tx struct {
+	*sql.Tx
+	Dialect Dialect
+	Logger  *slog.Logger
+	Tracer  trace.Tracer
+}
+
+// ExecContext executes a query without returning any rows
+func (tx *Tx) ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error) {
+	ctx, done := startQuery(ctx, tx.Logger, tx.Tracer, tx.Dialect, query)
+	result, erro := tx.Tx.ExecContext(ctx, tx.Dialect.Rebind(query), args...)
+	done(erro)
+	return result, erro
+}
+
+// startQuery starts the span of a query. The returned function ends it and logs the query
+// at debug level, or at error level when it failed for another reason than its context ending.
+func startQuery(ctx context.Context, logger *slog.Logger, tracer trace.Tracer, dialect Dialect, query string) (context.Context, func(erro error)) {
+	start := time.Now()
+	query = strings.Join(strings.Fields(query), " ")
+
+	ctx, span := tracer.Start(ctx, "db.query",
+		trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(
+			attribute.String("db.system", string(dialect)),
+			attribute.String("db.statement", query),
+		),
+	)
+
+	return ctx, func(erro error) {
+		defer span.End()
+
+		level, message := slog.LevelDebug, "query"
+		if erro != nil && !errors.Is(erro, context.Canceled) && !errors.Is(erro, context.DeadlineExceeded) {
+			level, message = slog.LevelError, "query failed"
+		}
+
+		if erro != nil {
+			span.RecordError(erro)
+			span.SetStatus(codes.Error, erro.Error())
+		}
+
+		if !logger.Enabled(ctx, level) {
+			return
+		}
+
+		attrs := []slog.Attr{
+			slog.String("query", query),
+			slog.Float64("duration_ms", float64(time.Since(start).Microseconds())/1000),
+		}
+		if erro != nil {
+			attrs = append(attrs, slog.String("error", erro.Error()))
+		}
+
+		logger.LogAttrs(ctx, level, message, attrs...)
+	}
+}
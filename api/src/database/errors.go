@@ -0,0 +1,78 @@
+/*
+Copyright 2022 Danilo S. Lopes.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at:
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package database
+
+import (
+	"errors"
+	"strings"
+
+	"github.com/go-sql-driver/mysql"
+	"github.com/lib/pq"
+	"modernc.org/sqlite"
+	sqlite3 "modernc.org/sqlite/lib"
+)
+
+// UniqueViolation tells whether erro reports a value duplicating a unique index or primary key
+// and returns the violated index, e.g. "users.email" or "users_email_key" depending on the driver
+func UniqueViolation(erro error) (string, bool) {
+	var mysqlErro *mysql.MySQLError
+	if errors.As(erro, &mysqlErro) && mysqlErro.Number == 1062 {
+		return after(mysqlErro.Message, "for key "), true
+	}
+
+	var pqErro *pq.Error
+	if errors.As(erro, &pqErro) && pqErro.Code == "23505" {
+		return pqErro.Constraint, true
+	}
+
+	var sqliteErro *sqlite.Error
+	if errors.As(erro, &sqliteErro) &&
+		(sqliteErro.Code() == sqlite3.SQLITE_CONSTRAINT_UNIQUE || sqliteErro.Code() == sqlite3.SQLITE_CONSTRAINT_PRIMARYKEY) {
+		return after(sqliteErro.Error(), "failed: "), true
+	}
+
+	return "", false
+}
+
+// ForeignKeyViolation tells whether erro reports a row referencing another one that does not exist
+func ForeignKeyViolation(erro error) bool {
+	var mysqlErro *mysql.MySQLError
+	if errors.As(erro, &mysqlErro) {
+		return mysqlErro.Number == 1452
+	}
+
+	var pqErro *pq.Error
+	if errors.As(erro, &pqErro) {
+		return pqErro.Code == "23503"
+	}
+
+	var sqliteErro *sqlite.Error
+	if errors.As(erro, &sqliteErro) {
+		return sqliteErro.Code() == sqlite3.SQLITE_CONSTRAINT_FOREIGNKEY
+	}
+
+	return false
+}
+
+// after returns what follows the last occurrence of separator in message, the whole message without it
+func after(message, separator string) string {
+	if index := strings.LastIndex(message, separator); index >= 0 {
+		return message[index+len(separator):]
+	}
+
+	return message
+}
@@ -0,0 +1,125 @@
+/*
+Copyright 2022 Danilo S. Lopes.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at:
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package logging
+
+import (
+	"api/src/config"
+	"context"
+	"io"
+	"log/slog"
+	"strings"
+
+	"go.opentelemetry.io/otel/trace"
+)
+
+// New creates the application logger writing to w with the configured level and format.
+// Records logged with a request context carry its request ID, authenticated user ID and trace IDs.
+func New(w io.Writer, settings config.Log) *slog.Logger {
+	options := &slog.HandlerOptions{Level: ParseLevel(settings.Level)}
+
+	var handler slog.Handler
+	if settings.Format == "text" {
+		handler = slog.NewTextHandler(w, options)
+	} else {
+		handler = slog.NewJSONHandler(w, options)
+	}
+
+	return slog.New(contextHandler{handler})
+}
+
+// ParseLevel converts a LOG_LEVEL value into a slog level, defaulting to info
+func ParseLevel(level string) slog.Level {
+	switch strings.ToLower(level) {
+	case "debug":
+		return slog.LevelDebug
+	case "warn":
+		return slog.LevelWarn
+	case "error":
+		return slog.LevelError
+	default:
+		return slog.LevelInfo
+	}
+}
+
+// request holds the request attributes added to every record logged with its context
+type request struct {
+	ID     string
+	UserID uint64
+}
+
+type requestKey struct{}
+
+// WithRequest returns a copy of ctx identified by requestID
+func WithRequest(ctx context.Context, requestID string) context.Context {
+	return context.WithValue(ctx, requestKey{}, &request{ID: requestID})
+}
+
+// RequestID returns the ID of the request ctx belongs to
+func RequestID(ctx context.Context) string {
+	if request, ok := ctx.Value(requestKey{}).(*request); ok {
+		return request.ID
+	}
+
+	return ""
+}
+
+// SetUserID records the authenticated user of the request ctx belongs to
+func SetUserID(ctx context.Context, userID uint64) {
+	if request, ok := ctx.Value(requestKey{}).(*request); ok {
+		request.UserID = userID
+	}
+}
+
+// UserID returns the authenticated user of the request ctx belongs to, zero when anonymous
+func UserID(ctx context.Context) uint64 {
+	if request, ok := ctx.Value(requestKey{}).(*request); ok {
+		return request.UserID
+	}
+
+	return 0
+}
+
+// contextHandler adds the request and trace attributes of the record context
+type contextHandler struct {
+	slog.Handler
+}
+
+func (handler contextHandler) Handle(ctx context.Context, record slog.Record) error {
+	if request, ok := ctx.Value(requestKey{}).(*request); ok {
+		record.AddAttrs(slog.String("request_id", request.ID))
+		if request.UserID != 0 {
+			record.AddAttrs(slog.Uint64("user_id", request.UserID))
+		}
+	}
+
+	if span := trace.SpanContextFromContext(ctx); span.IsValid() {
+		record.AddAttrs(
+			slog.String("trace_id", span.TraceID().String()),
+			slog.String("span_id", span.SpanID().String()),
+		)
+	}
+
+	return handler.Handler.Handle(ctx, record)
+}
+
+func (handler contextHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	return contextHandler{handler.Handler.WithAttrs(attrs)}
+}
+
+func (handler contextHandler) WithGroup(name string) slog.Handler {
+	return contextHandler{handler.Handler.WithGroup(name)}
+}
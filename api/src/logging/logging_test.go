@@ -0,0 +1,74 @@
+/*
+Copyright 2022 Danilo S. Lopes.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at:
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package logging
+
+import (
+	"api/src/config"
+	"bytes"
+	"context"
+	"encoding/json"
+	"strings"
+	"testing"
+)
+
+// The records logged with a request context carry its request ID, and its user ID once authenticated
+func TestRequestAttributes(t *testing.T) {
+	var output bytes.Buffer
+	logger := New(&output, config.Log{Level: "info", Format: "json"})
+
+	ctx := WithRequest(context.Background(), "abc123")
+	logger.InfoContext(ctx, "anonymous")
+	SetUserID(ctx, 7)
+	logger.InfoContext(ctx, "authenticated")
+	logger.InfoContext(context.Background(), "outside")
+
+	lines := strings.Split(strings.TrimSpace(output.String()), "\n")
+	if len(lines) != 3 {
+		t.Fatalf("got %d records, want 3: %s", len(lines), output.String())
+	}
+
+	var records []map[string]interface{}
+	for _, line := range lines {
+		var record map[string]interface{}
+		if erro := json.Unmarshal([]byte(line), &record); erro != nil {
+			t.Fatal(erro)
+		}
+		records = append(records, record)
+	}
+
+	if records[0]["request_id"] != "abc123" || records[0]["user_id"] != nil {
+		t.Fatalf("the anonymous record got %v, want the request ID alone", records[0])
+	}
+	if records[1]["request_id"] != "abc123" || records[1]["user_id"] != float64(7) {
+		t.Fatalf("the authenticated record got %v, want the request and user IDs", records[1])
+	}
+	if _, exists := records[2]["request_id"]; exists {
+		t.Fatalf("the record outside a request got %v, want no request ID", records[2])
+	}
+}
+
+func TestLevelAndFormat(t *testing.T) {
+	var output bytes.Buffer
+	logger := New(&output, config.Log{Level: "WARN", Format: "text"})
+
+	logger.Info("dropped")
+	logger.Warn("kept", "key", "value")
+
+	if got := output.String(); strings.Contains(got, "dropped") || !strings.Contains(got, "level=WARN msg=kept key=value") {
+		t.Fatalf("got %q, want the warning alone as text", got)
+	}
+}
@@ -0,0 +1,61 @@
+/*
+Copyright 2022 Danilo S. Lopes.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at:
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package middlewares
+
+import (
+	"api/src/requests"
+	"api/src/responses"
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+)
+
+// Body reads the JSON body of the requests, up to maxSize bytes, before nextFunction decodes it with
+// requests.Decode, which only accepts the members listed in fields. The bodies of another media type are
+// answered with a 415 and the larger ones with a 413, read no further than the limit.
+func Body(maxSize int64, fields []string, nextFunction http.HandlerFunc) http.HandlerFunc {
+	tooLarge := fmt.Errorf("the body must not exceed %d bytes", maxSize)
+
+	return func(w http.ResponseWriter, r *http.Request) {
+		if !requests.JSON(r.Header) {
+			responses.Problem(w, http.StatusUnsupportedMediaType, responses.CodeUnsupportedMedia, errors.New("the body must be application/json"))
+			return
+		}
+
+		if r.ContentLength > maxSize {
+			responses.Problem(w, http.StatusRequestEntityTooLarge, responses.CodeBodyTooLarge, tooLarge)
+			return
+		}
+
+		body, erro := io.ReadAll(http.MaxBytesReader(w, r.Body, maxSize))
+		if erro != nil {
+			var maxBytesErro *http.MaxBytesError
+			if errors.As(erro, &maxBytesErro) {
+				responses.Problem(w, http.StatusRequestEntityTooLarge, responses.CodeBodyTooLarge, tooLarge)
+				return
+			}
+
+			responses.Problem(w, http.StatusUnprocessableEntity, responses.CodeUnreadableBody, erro)
+			return
+		}
+
+		r.Body = io.NopCloser(bytes.NewReader(body))
+		nextFunction(w, r.WithContext(requests.WithFields(r.Context(), fields)))
+	}
+}
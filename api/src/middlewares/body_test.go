@@ -0,0 +1,98 @@
+/*
+Copyright 2022 Danilo S. Lopes.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at:
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package middlewares
+
+import (
+	"api/src/requests"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestBody(t *testing.T) {
+	type publication struct {
+		Title   string `json:"title"`
+		Content string `json:"content"`
+		Likes   uint64 `json:"likes"`
+	}
+
+	tests := []struct {
+		name          string
+		contentType   string
+		body          string
+		chunked       bool
+		wantStatus    int
+		wantDecodeErr error
+		wantUnknown   string
+	}{
+		{name: "object", contentType: "application/json", body: `{"title":"title","content":"content"}`, wantStatus: http.StatusOK},
+		{name: "utf-8 charset", contentType: "application/json; charset=UTF-8", body: `{"title":"title"}`, wantStatus: http.StatusOK},
+		{name: "other media type", contentType: "text/plain", body: `{"title":"title"}`, wantStatus: http.StatusUnsupportedMediaType},
+		{name: "other charset", contentType: "application/json; charset=latin1", body: `{"title":"title"}`, wantStatus: http.StatusUnsupportedMediaType},
+		{name: "no Content-Type", body: `{"title":"title"}`, wantStatus: http.StatusUnsupportedMediaType},
+		{name: "oversized", contentType: "application/json", body: `{"title":"` + strings.Repeat("a", 64) + `"}`, wantStatus: http.StatusRequestEntityTooLarge},
+		{name: "oversized without Content-Length", contentType: "application/json", body: `{"title":"` + strings.Repeat("a", 64) + `"}`, chunked: true, wantStatus: http.StatusRequestEntityTooLarge},
+		{name: "unknown field", contentType: "application/json", body: `{"title":"title","likes":10}`, wantStatus: http.StatusBadRequest, wantUnknown: "likes"},
+		{name: "field of another case", contentType: "application/json", body: `{"Title":"title"}`, wantStatus: http.StatusBadRequest, wantUnknown: "Title"},
+		{name: "trailing data", contentType: "application/json", body: `{"title":"title"} {}`, wantStatus: http.StatusBadRequest, wantDecodeErr: requests.ErrTrailingData},
+		{name: "null", contentType: "application/json", body: `null`, wantStatus: http.StatusBadRequest, wantDecodeErr: requests.ErrNotObject},
+		{name: "array", contentType: "application/json", body: `[]`, wantStatus: http.StatusBadRequest, wantDecodeErr: requests.ErrNotObject},
+		{name: "empty", contentType: "application/json", wantStatus: http.StatusBadRequest, wantDecodeErr: requests.ErrEmptyBody},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			var decodeErro error
+			handler := Body(48, []string{"title", "content"}, func(w http.ResponseWriter, r *http.Request) {
+				var target publication
+				if decodeErro = requests.Decode(r, &target); decodeErro != nil {
+					w.WriteHeader(http.StatusBadRequest)
+				}
+			})
+
+			request := httptest.NewRequest(http.MethodPost, "/publications", strings.NewReader(test.body))
+			if test.contentType != "" {
+				request.Header.Set("Content-Type", test.contentType)
+			}
+			if test.chunked {
+				request.ContentLength = -1
+			}
+
+			response := httptest.NewRecorder()
+			handler(response, request)
+
+			if response.Code != test.wantStatus {
+				t.Fatalf("got %d, want %d: %s", response.Code, test.wantStatus, response.Body)
+			}
+			if test.wantStatus != http.StatusOK && test.wantStatus != http.StatusBadRequest &&
+				response.Header().Get("Content-Type") != "application/problem+json" {
+				t.Fatalf("got Content-Type %q, want a problem", response.Header().Get("Content-Type"))
+			}
+
+			if test.wantDecodeErr != nil && !errors.Is(decodeErro, test.wantDecodeErr) {
+				t.Fatalf("decoding got %v, want %v", decodeErro, test.wantDecodeErr)
+			}
+
+			var unknownErro *requests.UnknownFieldError
+			if test.wantUnknown != "" && (!errors.As(decodeErro, &unknownErro) || unknownErro.Field != test.wantUnknown) {
+				t.Fatalf("decoding got %v, want the field %q refused", decodeErro, test.wantUnknown)
+			}
+		})
+	}
+}
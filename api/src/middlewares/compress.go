@@ -0,0 +1,70 @@
+/*
+Copyright 2022 Danilo S. Lopes.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at:
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package middlewares
+
+import (
+	"api/src/compression"
+	"fmt"
+	"net/http"
+	"strings"
+)
+
+// Compress compresses the response body with the content coding negotiated from Accept-Encoding.
+// A strong ETag is turned into a weak one, the bytes sent differing from the ones it identifies, and
+// so is the one of a 304 so the clients get the ETag of the compressed response they hold.
+func Compress(compressor *compression.Compressor, nextFunction http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		header := w.Header()
+		header.Add("Vary", "Accept-Encoding")
+
+		encoding := compressor.Negotiate(r.Header.Get("Accept-Encoding"))
+		if encoding == "" {
+			nextFunction(w, r)
+			return
+		}
+
+		buffer := &bufferedResponse{ResponseWriter: w, status: http.StatusOK}
+		nextFunction(buffer, r)
+
+		body := buffer.body.Bytes()
+		if buffer.status == http.StatusNotModified {
+			weakenETag(header)
+		}
+		if buffer.status != http.StatusNoContent && buffer.status != http.StatusNotModified &&
+			header.Get("Content-Encoding") == "" && compressor.Worth(header.Get("Content-Type"), len(body)) {
+			compressed, erro := compressor.Compress(encoding, body)
+			if erro != nil {
+				buffer.RecordError(fmt.Errorf("compressing the response: %w", erro))
+			} else {
+				body = compressed
+				header.Set("Content-Encoding", encoding)
+				header.Del("Content-Length")
+				weakenETag(header)
+			}
+		}
+
+		w.WriteHeader(buffer.status)
+		w.Write(body)
+	}
+}
+
+// weakenETag turns the strong ETag of header, if any, into a weak one
+func weakenETag(header http.Header) {
+	if etag := header.Get("ETag"); etag != "" && !strings.HasPrefix(etag, "W/") {
+		header.Set("ETag", "W/"+etag)
+	}
+}
@@ -0,0 +1,123 @@
+/*
+Copyright 2022 Danilo S. Lopes.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at:
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package middlewares
+
+import (
+	"api/src/compression"
+	"api/src/config"
+	"bytes"
+	"compress/gzip"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestCompress(t *testing.T) {
+	compressor, erro := compression.New(config.Compression{Encodings: []string{"gzip"}, MinSize: 64})
+	if erro != nil {
+		t.Fatal(erro)
+	}
+
+	large := `{"content":"` + strings.Repeat("a", 256) + `"}`
+
+	tests := []struct {
+		name            string
+		acceptEncoding  string
+		status          int
+		contentType     string
+		contentEncoding string
+		etag            string
+		body            string
+
+		wantEncoding string
+		wantETag     string
+	}{
+		{name: "gzip accepted", acceptEncoding: "gzip, deflate", status: http.StatusOK, contentType: "application/json",
+			etag: `"tag"`, body: large, wantEncoding: "gzip", wantETag: `W/"tag"`},
+		{name: "gzip refused", acceptEncoding: "gzip;q=0, identity", status: http.StatusOK, contentType: "application/json",
+			etag: `"tag"`, body: large, wantETag: `"tag"`},
+		{name: "any encoding accepted", acceptEncoding: "*", status: http.StatusOK, contentType: "application/json",
+			body: large, wantEncoding: "gzip"},
+		{name: "no Accept-Encoding", status: http.StatusOK, contentType: "application/json",
+			body: large},
+		{name: "small body", acceptEncoding: "gzip", status: http.StatusOK, contentType: "application/json",
+			etag: `"tag"`, body: `{"id":1}`, wantETag: `"tag"`},
+		{name: "already encoded body", acceptEncoding: "gzip", status: http.StatusOK, contentType: "application/json",
+			contentEncoding: "br", body: large, wantEncoding: "br"},
+		{name: "binary body", acceptEncoding: "gzip", status: http.StatusOK, contentType: "image/png",
+			body: large},
+		{name: "not modified", acceptEncoding: "gzip", status: http.StatusNotModified,
+			etag: `"tag"`, wantETag: `W/"tag"`},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			handler := Compress(compressor, func(w http.ResponseWriter, r *http.Request) {
+				header := w.Header()
+				if test.contentType != "" {
+					header.Set("Content-Type", test.contentType)
+				}
+				if test.contentEncoding != "" {
+					header.Set("Content-Encoding", test.contentEncoding)
+				}
+				if test.etag != "" {
+					header.Set("ETag", test.etag)
+				}
+				w.WriteHeader(test.status)
+				io.WriteString(w, test.body)
+			})
+
+			request := httptest.NewRequest(http.MethodGet, "/publications", nil)
+			if test.acceptEncoding != "" {
+				request.Header.Set("Accept-Encoding", test.acceptEncoding)
+			}
+
+			response := httptest.NewRecorder()
+			handler(response, request)
+
+			header := response.Header()
+			if response.Code != test.status {
+				t.Fatalf("got %d, want %d", response.Code, test.status)
+			}
+			if got := header.Get("Content-Encoding"); got != test.wantEncoding {
+				t.Fatalf("got Content-Encoding %q, want %q", got, test.wantEncoding)
+			}
+			if got := header.Get("ETag"); got != test.wantETag {
+				t.Fatalf("got ETag %q, want %q", got, test.wantETag)
+			}
+			if got := header.Get("Vary"); got != "Accept-Encoding" {
+				t.Fatalf("got Vary %q, want Accept-Encoding", got)
+			}
+
+			body := response.Body.Bytes()
+			if test.wantEncoding == "gzip" {
+				reader, erro := gzip.NewReader(bytes.NewReader(body))
+				if erro != nil {
+					t.Fatal(erro)
+				}
+				if body, erro = io.ReadAll(reader); erro != nil {
+					t.Fatal(erro)
+				}
+			}
+			if string(body) != test.body {
+				t.Fatalf("got the body %q, want %q", body, test.body)
+			}
+		})
+	}
+}
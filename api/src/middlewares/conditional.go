@@ -0,0 +1,123 @@
+/*
+Copyright 2022 Danilo S. Lopes.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at:
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package middlewares
+
+import (
+	"api/src/logging"
+	"crypto/sha256"
+	"encoding/base64"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Conditional lets the clients revalidate the responses of a read route: a 200 gets a strong ETag, the
+// hash of its body, and a Last-Modified, and is answered with a 304 without body when the client already
+// holds it, as told by If-None-Match or else If-Modified-Since.
+func Conditional(times *ModificationTimes, nextFunction http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		buffer := &bufferedResponse{ResponseWriter: w, status: http.StatusOK}
+		nextFunction(buffer, r)
+
+		if buffer.status != http.StatusOK {
+			w.WriteHeader(buffer.status)
+			w.Write(buffer.body.Bytes())
+			return
+		}
+
+		sum := sha256.Sum256(buffer.body.Bytes())
+		etag := `"` + base64.RawURLEncoding.EncodeToString(sum[:16]) + `"`
+
+		// the same URL answers each user with his own data
+		key := strconv.FormatUint(logging.UserID(r.Context()), 10) + " " + r.URL.RequestURI()
+		lastModified := times.Since(key, etag)
+
+		header := w.Header()
+		header.Set("ETag", etag)
+		header.Set("Last-Modified", lastModified.UTC().Format(http.TimeFormat))
+		header.Set("Cache-Control", "private, no-cache")
+
+		if notModified(r, etag, lastModified) {
+			header.Del("Content-Type")
+			header.Del("Content-Length")
+			w.WriteHeader(http.StatusNotModified)
+			return
+		}
+
+		w.WriteHeader(http.StatusOK)
+		w.Write(buffer.body.Bytes())
+	}
+}
+
+// notModified evaluates If-None-Match, comparing the ETags weakly, or If-Modified-Since when it is absent
+func notModified(r *http.Request, etag string, lastModified time.Time) bool {
+	if ifNoneMatch := r.Header.Get("If-None-Match"); ifNoneMatch != "" {
+		for _, candidate := range strings.Split(ifNoneMatch, ",") {
+			candidate = strings.TrimPrefix(strings.TrimSpace(candidate), "W/")
+			if candidate == "*" || candidate == etag {
+				return true
+			}
+		}
+
+		return false
+	}
+
+	since, erro := http.ParseTime(r.Header.Get("If-Modified-Since"))
+	return erro == nil && !lastModified.Truncate(time.Second).After(since)
+}
+
+// Above this many responses the modification times are forgotten, the next ones starting afresh
+const maxModificationTimes = 10000
+
+// ModificationTimes remembers since when each response carries its current ETag, reported as its
+// Last-Modified. The times are never earlier than the actual changes, so an API instance that saw a
+// response later than another one only makes its clients download it once more.
+type ModificationTimes struct {
+	mu    sync.Mutex
+	times map[string]modification
+}
+
+type modification struct {
+	etag  string
+	since time.Time
+}
+
+// NewModificationTimes creates an empty ModificationTimes
+func NewModificationTimes() *ModificationTimes {
+	return &ModificationTimes{times: make(map[string]modification)}
+}
+
+// Since returns when the response identified by key started carrying etag
+func (times *ModificationTimes) Since(key, etag string) time.Time {
+	times.mu.Lock()
+	defer times.mu.Unlock()
+
+	if current, exists := times.times[key]; exists && current.etag == etag {
+		return current.since
+	}
+
+	if len(times.times) >= maxModificationTimes {
+		times.times = make(map[string]modification)
+	}
+
+	current := modification{etag, time.Now()}
+	times.times[key] = current
+
+	return current.since
+}
@@ -0,0 +1,114 @@
+/*
+Copyright 2022 Danilo S. Lopes.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at:
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package middlewares
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestConditional(t *testing.T) {
+	body := `[{"id":1}]`
+	times := NewModificationTimes()
+	handler := Conditional(times, func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		io.WriteString(w, body)
+	})
+
+	send := func(header http.Header) *httptest.ResponseRecorder {
+		t.Helper()
+
+		request := httptest.NewRequest(http.MethodGet, "/publications", nil)
+		for name, values := range header {
+			request.Header[name] = values
+		}
+
+		response := httptest.NewRecorder()
+		handler(response, request)
+
+		return response
+	}
+
+	first := send(nil)
+	etag, lastModified := first.Header().Get("ETag"), first.Header().Get("Last-Modified")
+	if first.Code != http.StatusOK || first.Body.String() != body || etag == "" || lastModified == "" {
+		t.Fatalf("got %d %q with ETag %q and Last-Modified %q, want the body with both", first.Code, first.Body, etag, lastModified)
+	}
+	if first.Header().Get("Cache-Control") != "private, no-cache" {
+		t.Fatalf("got Cache-Control %q, want private, no-cache", first.Header().Get("Cache-Control"))
+	}
+
+	modified, _ := http.ParseTime(lastModified)
+	tests := []struct {
+		name       string
+		header     http.Header
+		wantStatus int
+	}{
+		{"matching ETag", http.Header{"If-None-Match": {etag}}, http.StatusNotModified},
+		{"matching weak ETag", http.Header{"If-None-Match": {`"other", W/` + etag}}, http.StatusNotModified},
+		{"any ETag", http.Header{"If-None-Match": {"*"}}, http.StatusNotModified},
+		{"other ETag", http.Header{"If-None-Match": {`"other"`}}, http.StatusOK},
+		{"If-None-Match winning over If-Modified-Since", http.Header{"If-None-Match": {`"other"`}, "If-Modified-Since": {lastModified}}, http.StatusOK},
+		{"not modified since", http.Header{"If-Modified-Since": {lastModified}}, http.StatusNotModified},
+		{"modified since", http.Header{"If-Modified-Since": {modified.Add(-time.Second).Format(http.TimeFormat)}}, http.StatusOK},
+		{"invalid If-Modified-Since", http.Header{"If-Modified-Since": {"yesterday"}}, http.StatusOK},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			response := send(test.header)
+			if response.Code != test.wantStatus {
+				t.Fatalf("got %d, want %d", response.Code, test.wantStatus)
+			}
+			if response.Header().Get("ETag") != etag || response.Header().Get("Last-Modified") != lastModified {
+				t.Fatalf("got ETag %q and Last-Modified %q, want the ones of the first response",
+					response.Header().Get("ETag"), response.Header().Get("Last-Modified"))
+			}
+
+			if test.wantStatus == http.StatusNotModified && (response.Body.Len() != 0 || response.Header().Get("Content-Type") != "") {
+				t.Fatalf("the 304 got the body %q and Content-Type %q, want none", response.Body, response.Header().Get("Content-Type"))
+			}
+		})
+	}
+
+	body = `[{"id":2}]`
+	changed := send(http.Header{"If-None-Match": {etag}})
+	if changed.Code != http.StatusOK || changed.Body.String() != body || changed.Header().Get("ETag") == etag {
+		t.Fatalf("once the body changed got %d %q with ETag %q, want the new body with another ETag",
+			changed.Code, changed.Body, changed.Header().Get("ETag"))
+	}
+}
+
+// The responses other than a 200 are passed on untouched
+func TestConditionalErrors(t *testing.T) {
+	handler := Conditional(NewModificationTimes(), func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNotFound)
+		io.WriteString(w, "missing")
+	})
+
+	request := httptest.NewRequest(http.MethodGet, "/publications/1", nil)
+	request.Header.Set("If-None-Match", "*")
+	response := httptest.NewRecorder()
+	handler(response, request)
+
+	if response.Code != http.StatusNotFound || response.Body.String() != "missing" || response.Header().Get("ETag") != "" {
+		t.Fatalf("got %d %q with ETag %q, want the 404 untouched", response.Code, response.Body, response.Header().Get("ETag"))
+	}
+}
@@ -0,0 +1,102 @@
+/*
+Copyright 2022 Danilo S. Lopes.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at:
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package middlewares
+
+import (
+	"api/src/config"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+// CORS lets the allowed origins read the response, exposing them the configured headers.
+// The requests of the other origins are served as usual, the browser hiding the response from them.
+func CORS(settings config.CORS, nextFunction http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		header := w.Header()
+		header.Add("Vary", "Origin")
+
+		if origin := r.Header.Get("Origin"); settings.AllowsOrigin(origin) {
+			allowOrigin(header, settings, origin)
+			if len(settings.ExposedHeaders) > 0 {
+				header.Set("Access-Control-Expose-Headers", strings.Join(settings.ExposedHeaders, ", "))
+			}
+		}
+
+		nextFunction(w, r)
+	}
+}
+
+// Preflight answers the OPTIONS requests of a route served with methods. The browsers are told the
+// allowed methods and headers when the origin and the requested method are allowed, nothing otherwise.
+func Preflight(settings config.CORS, methods []string) http.HandlerFunc {
+	var allowed []string
+	for _, method := range methods {
+		for _, allowedMethod := range settings.AllowedMethods {
+			if strings.EqualFold(method, allowedMethod) {
+				allowed = append(allowed, method)
+				break
+			}
+		}
+	}
+
+	return func(w http.ResponseWriter, r *http.Request) {
+		header := w.Header()
+		header.Set("Allow", strings.Join(append([]string{http.MethodOptions}, methods...), ", "))
+		header.Add("Vary", "Origin")
+		header.Add("Vary", "Access-Control-Request-Method")
+		header.Add("Vary", "Access-Control-Request-Headers")
+
+		origin := r.Header.Get("Origin")
+		method := r.Header.Get("Access-Control-Request-Method")
+		if settings.AllowsOrigin(origin) && contains(allowed, method) {
+			allowOrigin(header, settings, origin)
+			header.Set("Access-Control-Allow-Methods", strings.Join(allowed, ", "))
+			if len(settings.AllowedHeaders) > 0 {
+				header.Set("Access-Control-Allow-Headers", strings.Join(settings.AllowedHeaders, ", "))
+			}
+			if settings.MaxAge > 0 {
+				header.Set("Access-Control-Max-Age", strconv.Itoa(int(settings.MaxAge.Seconds())))
+			}
+		}
+
+		w.WriteHeader(http.StatusNoContent)
+	}
+}
+
+// allowOrigin lets origin read the response, echoing it unless any origin is allowed without credentials
+func allowOrigin(header http.Header, settings config.CORS, origin string) {
+	if settings.AllowsOrigin("*") && !settings.AllowCredentials {
+		header.Set("Access-Control-Allow-Origin", "*")
+		return
+	}
+
+	header.Set("Access-Control-Allow-Origin", origin)
+	if settings.AllowCredentials {
+		header.Set("Access-Control-Allow-Credentials", "true")
+	}
+}
+
+func contains(list []string, item string) bool {
+	for _, element := range list {
+		if element == item {
+			return true
+		}
+	}
+
+	return false
+}
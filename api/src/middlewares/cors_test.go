@@ -0,0 +1,126 @@
+/*
+Copyright 2022 Danilo S. Lopes.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at:
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package middlewares
+
+import (
+	"api/src/config"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gorilla/mux"
+)
+
+func TestCORS(t *testing.T) {
+	exact := config.CORS{
+		AllowedOrigins: []string{"https://app.example.com", "https://*.example.org"},
+		AllowedMethods: []string{"GET", "POST"},
+		AllowedHeaders: []string{"Authorization", "Content-Type"},
+		ExposedHeaders: []string{"X-Request-ID"},
+		MaxAge:         time.Hour,
+	}
+	anyOrigin := config.CORS{AllowedOrigins: []string{"*"}, AllowedMethods: []string{"GET"}}
+	anyWithCredentials := config.CORS{AllowedOrigins: []string{"*"}, AllowedMethods: []string{"GET"}, AllowCredentials: true}
+
+	tests := []struct {
+		name          string
+		settings      config.CORS
+		method        string
+		origin        string
+		requestMethod string
+
+		wantStatus      int
+		wantOrigin      string
+		wantCredentials string
+		wantMethods     string
+		wantExposed     string
+		wantMaxAge      string
+	}{
+		{name: "allowed origin", settings: exact, method: http.MethodGet, origin: "https://app.example.com",
+			wantStatus: http.StatusOK, wantOrigin: "https://app.example.com", wantExposed: "X-Request-ID"},
+		{name: "origin matching a wildcard", settings: exact, method: http.MethodGet, origin: "https://api.example.org",
+			wantStatus: http.StatusOK, wantOrigin: "https://api.example.org", wantExposed: "X-Request-ID"},
+		{name: "disallowed origin", settings: exact, method: http.MethodGet, origin: "https://evil.example.com",
+			wantStatus: http.StatusOK},
+		{name: "without origin", settings: exact, method: http.MethodGet,
+			wantStatus: http.StatusOK},
+		{name: "any origin", settings: anyOrigin, method: http.MethodGet, origin: "https://app.example.com",
+			wantStatus: http.StatusOK, wantOrigin: "*"},
+		{name: "any origin with credentials", settings: anyWithCredentials, method: http.MethodGet, origin: "https://app.example.com",
+			wantStatus: http.StatusOK, wantOrigin: "https://app.example.com", wantCredentials: "true"},
+		{name: "preflight", settings: exact, method: http.MethodOptions, origin: "https://app.example.com", requestMethod: http.MethodPost,
+			wantStatus: http.StatusNoContent, wantOrigin: "https://app.example.com", wantMethods: "GET, POST", wantMaxAge: "3600"},
+		{name: "preflight of a method not allowed", settings: exact, method: http.MethodOptions, origin: "https://app.example.com", requestMethod: http.MethodDelete,
+			wantStatus: http.StatusNoContent},
+		{name: "preflight of a disallowed origin", settings: exact, method: http.MethodOptions, origin: "https://evil.example.com", requestMethod: http.MethodGet,
+			wantStatus: http.StatusNoContent},
+		{name: "preflight of any origin with credentials", settings: anyWithCredentials, method: http.MethodOptions, origin: "https://app.example.com", requestMethod: http.MethodGet,
+			wantStatus: http.StatusNoContent, wantOrigin: "https://app.example.com", wantCredentials: "true", wantMethods: "GET"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			ok := func(w http.ResponseWriter, r *http.Request) { w.WriteHeader(http.StatusOK) }
+
+			router := mux.NewRouter()
+			router.HandleFunc("/users/{userId}", CORS(test.settings, ok)).Methods(http.MethodGet)
+			router.HandleFunc("/users/{userId}", CORS(test.settings, ok)).Methods(http.MethodPost)
+			router.HandleFunc("/users/{userId}", Preflight(test.settings, []string{http.MethodGet, http.MethodPost})).Methods(http.MethodOptions)
+
+			request := httptest.NewRequest(test.method, "/users/1", nil)
+			if test.origin != "" {
+				request.Header.Set("Origin", test.origin)
+			}
+			if test.requestMethod != "" {
+				request.Header.Set("Access-Control-Request-Method", test.requestMethod)
+			}
+
+			response := httptest.NewRecorder()
+			router.ServeHTTP(response, request)
+
+			header := response.Header()
+			if response.Code != test.wantStatus {
+				t.Fatalf("got %d, want %d", response.Code, test.wantStatus)
+			}
+			if got := header.Get("Access-Control-Allow-Origin"); got != test.wantOrigin {
+				t.Fatalf("got Access-Control-Allow-Origin %q, want %q", got, test.wantOrigin)
+			}
+			if got := header.Get("Access-Control-Allow-Credentials"); got != test.wantCredentials {
+				t.Fatalf("got Access-Control-Allow-Credentials %q, want %q", got, test.wantCredentials)
+			}
+			if got := header.Get("Access-Control-Allow-Methods"); got != test.wantMethods {
+				t.Fatalf("got Access-Control-Allow-Methods %q, want %q", got, test.wantMethods)
+			}
+			if got := header.Get("Access-Control-Expose-Headers"); got != test.wantExposed {
+				t.Fatalf("got Access-Control-Expose-Headers %q, want %q", got, test.wantExposed)
+			}
+			if got := header.Get("Access-Control-Max-Age"); got != test.wantMaxAge {
+				t.Fatalf("got Access-Control-Max-Age %q, want %q", got, test.wantMaxAge)
+			}
+
+			// the responses depend on the origin whether it is allowed or not, so the caches must key them by it
+			if vary := strings.Join(header.Values("Vary"), ", "); !strings.Contains(vary, "Origin") {
+				t.Fatalf("got Vary %q, want it to hold Origin", vary)
+			}
+			if test.method == http.MethodOptions && header.Get("Allow") != "OPTIONS, GET, POST" {
+				t.Fatalf("got Allow %q, want the methods of the route", header.Get("Allow"))
+			}
+		})
+	}
+}
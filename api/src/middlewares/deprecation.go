@@ -0,0 +1,48 @@
+/*
+Copyright 2022 Danilo S. Lopes.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at:
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package middlewares
+
+import (
+	"api/src/config"
+	"api/src/prommetrics"
+	"fmt"
+	"net/http"
+)
+
+// Deprecated serves a legacy route, alias of the one under successor (e.g. "/v1"). The responses tell
+// when it was deprecated (RFC 9745), when it stops being served (RFC 8594) and link the route
+// replacing it. The requests are counted, so the remaining clients can be watched.
+func Deprecated(settings config.LegacyRoutes, metrics *prommetrics.Metrics, uri, successor string, nextFunction http.HandlerFunc) http.HandlerFunc {
+	deprecation := fmt.Sprintf("@%d", settings.Deprecation.Unix())
+	sunset := settings.Sunset.UTC().Format(http.TimeFormat)
+
+	return func(w http.ResponseWriter, r *http.Request) {
+		metrics.LegacyRequests.WithLabelValues(uri).Inc()
+
+		header := w.Header()
+		header.Set("Deprecation", deprecation)
+		header.Set("Sunset", sunset)
+
+		replacement := successor + r.URL.Path
+		if r.URL.RawQuery != "" {
+			replacement += "?" + r.URL.RawQuery
+		}
+		header.Add("Link", fmt.Sprintf(`<%s>; rel="successor-version"`, replacement))
+
+		nextFunction(w, r)
+	}
+}
@@ -0,0 +1,156 @@
+/*
+Copyright 2022 Danilo S. Lopes.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at:
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package middlewares
+
+import (
+	"api/src/logging"
+	"api/src/models"
+	"api/src/prommetrics"
+	"api/src/repositories"
+	"api/src/responses"
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"time"
+)
+
+const (
+	// IdempotencyKeyHeader carries the key a client sends along a request it may retry
+	IdempotencyKeyHeader = "Idempotency-Key"
+
+	// IdempotentReplayedHeader tells a response is the one stored for the key
+	IdempotentReplayedHeader = "Idempotent-Replayed"
+
+	maxIdempotencyKeyLength = 255
+
+	// idempotencyLease is how long a request holds its key before a retry takes it over, in case its
+	// process died. It outlasts the WriteTimeout of the server.
+	idempotencyLease = time.Minute
+)
+
+// Idempotent lets the clients retry the requests sent with an Idempotency-Key without repeating their
+// effects: the first response sent for a key of the user is stored for ttl and replayed to the retries.
+// The anonymous requests share no user, so their keys are scoped by the request they come along with.
+// The key is refused with a 422 along another request and with a 409 while the first one is served,
+// for idempotencyLease at most, a retry taking the key over afterwards. The 5xx responses are not
+// stored, the key being released so the request can be retried. prefix turns the path of a legacy alias
+// into its canonical versioned one, so the alias and its version share the keys.
+func Idempotent(repository repositories.IdempotencyRepository, ttl time.Duration, logger *slog.Logger, metrics *prommetrics.Metrics, path, prefix string, nextFunction http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		key := r.Header.Get(IdempotencyKeyHeader)
+		if key == "" {
+			nextFunction(w, r)
+			return
+		}
+
+		if len(key) > maxIdempotencyKeyLength {
+			responses.Problem(w, http.StatusBadRequest, responses.CodeInvalidIdempotency,
+				fmt.Errorf("the %s must not exceed %d characters", IdempotencyKeyHeader, maxIdempotencyKeyLength))
+			return
+		}
+
+		body, erro := io.ReadAll(r.Body)
+		if erro != nil {
+			responses.Problem(w, http.StatusUnprocessableEntity, responses.CodeUnreadableBody, erro)
+			return
+		}
+		r.Body = io.NopCloser(bytes.NewReader(body))
+
+		hash := sha256.New()
+		fmt.Fprintf(hash, "%s %s\n", r.Method, prefix+r.URL.Path)
+		hash.Write(body)
+		fingerprint := hex.EncodeToString(hash.Sum(nil))
+
+		// two anonymous clients may send the same key, told apart by their requests
+		userID := logging.UserID(r.Context())
+		if userID == 0 {
+			scoped := sha256.Sum256([]byte(key + "\n" + fingerprint))
+			key = hex.EncodeToString(scoped[:])
+		}
+
+		record := models.IdempotencyRecord{
+			UserID:      userID,
+			Key:         key,
+			Fingerprint: fingerprint,
+			ExpiresAt:   time.Now().Add(idempotencyLease),
+		}
+
+		stored, reserved, erro := repository.Reserve(r.Context(), record)
+		if erro != nil {
+			responses.Erro(w, http.StatusInternalServerError, fmt.Errorf("reserving the idempotency key: %w", erro))
+			return
+		}
+
+		if !reserved {
+			switch {
+			case stored.Fingerprint != record.Fingerprint:
+				metrics.IdempotentRequests.WithLabelValues(path, "reused").Inc()
+				responses.Problem(w, http.StatusUnprocessableEntity, responses.CodeIdempotencyReused,
+					errors.New("the idempotency key was already sent along another request"))
+
+			case stored.Status == 0:
+				metrics.IdempotentRequests.WithLabelValues(path, "in_progress").Inc()
+				w.Header().Set("Retry-After", "1")
+				responses.Problem(w, http.StatusConflict, responses.CodeIdempotencyPending,
+					errors.New("the request sent with the idempotency key is still being served"))
+
+			default:
+				metrics.IdempotentRequests.WithLabelValues(path, "replayed").Inc()
+				w.Header().Set("Content-Type", stored.ContentType)
+				w.Header().Set(IdempotentReplayedHeader, "true")
+				w.WriteHeader(stored.Status)
+				w.Write(stored.Body)
+			}
+			return
+		}
+
+		// the key is released when the request fails, panics included, so the client can retry it
+		ctx := context.WithoutCancel(r.Context())
+		completed := false
+		defer func() {
+			if completed {
+				return
+			}
+			if erro := repository.Release(ctx, record); erro != nil {
+				logger.ErrorContext(ctx, "releasing the idempotency key failed", "error", erro)
+			}
+		}()
+
+		buffer := &bufferedResponse{ResponseWriter: w, status: http.StatusOK}
+		nextFunction(buffer, r)
+
+		if buffer.status < http.StatusInternalServerError {
+			record.Status, record.ContentType, record.Body = buffer.status, w.Header().Get("Content-Type"), buffer.body.Bytes()
+			record.ExpiresAt = time.Now().Add(ttl)
+			if erro := repository.Complete(ctx, record); erro != nil {
+				logger.ErrorContext(ctx, "storing the idempotent response failed", "error", erro)
+			} else {
+				completed = true
+				metrics.IdempotentRequests.WithLabelValues(path, "stored").Inc()
+			}
+		}
+
+		w.WriteHeader(buffer.status)
+		w.Write(buffer.body.Bytes())
+	}
+}
@@ -18,35 +18,380 @@ package middlewares
 
 import (
 	"api/src/authentication"
+	"api/src/config"
+	"api/src/logging"
+	"api/src/models"
 	"api/src/prommetrics"
+	"api/src/ratelimit"
+	"api/src/repositories"
 	"api/src/responses"
-	"log"
+	"api/src/swagger"
+	"api/src/tracing"
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"log/slog"
 	"net/http"
+	"regexp"
+	"runtime/debug"
+	"strconv"
 	"time"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/trace"
 )
 
-// Logger log the request information in terminal
-func Logger(path string, nextFunction http.HandlerFunc) http.HandlerFunc {
-	return handler(path, nextFunction)
+// RequestIDHeader carries the request ID, accepted from the client and always returned in the response
+const RequestIDHeader = responses.RequestIDHeader
+
+var validRequestID = regexp.MustCompile(`^[A-Za-z0-9._-]{1,128}$`)
+
+// Logger identifies the request, starts its server span continuing the traceparent header
+// and writes its access log once it is served
+func Logger(logger *slog.Logger, metrics *prommetrics.Metrics, tracer trace.Tracer, path string, nextFunction http.HandlerFunc) http.HandlerFunc {
+	return handler(logger, metrics, tracer, path, nextFunction)
+}
+
+// Trace wraps the handler in a span named name, child of the request span
+func Trace(tracer trace.Tracer, name string, nextFunction http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		ctx, span := tracer.Start(r.Context(), name)
+		defer span.End()
+
+		nextFunction(w, r.WithContext(ctx))
+	}
 }
 
-// Authenticate validates if the User is authenticated
-func Authenticate(nextFunction http.HandlerFunc) http.HandlerFunc {
-	now := time.Now()
+// Authenticate validates if the User is authenticated, refusing the tokens of the users disabled or deleted since
+func Authenticate(secretKey []byte, users repositories.UsersRepository, nextFunction http.HandlerFunc) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
-		if erro := authentication.ValidateToken(r); erro != nil {
-			responses.Erro(now, w, http.StatusUnauthorized, erro)
+		if erro := authentication.ValidateToken(r, secretKey); erro != nil {
+			responses.Problem(w, http.StatusUnauthorized, responses.CodeInvalidToken, erro)
+			return
+		}
+
+		userID, erro := authentication.ExtractUserID(r, secretKey)
+		if erro != nil {
+			responses.Problem(w, http.StatusUnauthorized, responses.CodeInvalidToken, erro)
+			return
+		}
+
+		user, erro := users.SearchByID(r.Context(), userID)
+		switch {
+		case errors.Is(erro, models.ErrNotFound):
+			responses.Problem(w, http.StatusUnauthorized, responses.CodeInvalidToken, errors.New("the user of the token does not exist"))
+			return
+		case erro != nil:
+			responses.Erro(w, http.StatusInternalServerError, erro)
+			return
+		case user.Disabled:
+			responses.Problem(w, http.StatusUnauthorized, responses.CodeInvalidToken, errors.New("the user of the token is disabled"))
+			return
+		}
+
+		logging.SetUserID(r.Context(), userID)
+		nextFunction(w, r)
+	}
+}
+
+// RateLimit refuses with a 429 the requests of a client that used up the tokens policy grants it,
+// telling every client its quota in the RateLimit-* headers. It runs before Authenticate, so the requests
+// with a bad token are throttled too: the clients are told apart by the user ID of a valid token when
+// secretKey is set (the authenticated routes), by IP otherwise, and share their bucket with the other
+// routes of the same scope. The requests are let through when the store fails.
+func RateLimit(store ratelimit.Store, settings config.RateLimit, secretKey []byte, logger *slog.Logger, metrics *prommetrics.Metrics, path, scope string, policy config.RatePolicy, nextFunction http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		kind, client := "ip", ratelimit.ClientIP(r, settings.TrustForwardedFor)
+		if secretKey != nil {
+			if userID, erro := authentication.ExtractUserID(r, secretKey); erro == nil {
+				kind, client = "user", strconv.FormatUint(userID, 10)
+			}
+		}
+
+		decision, erro := store.Take(r.Context(), scope+"|"+kind+":"+client, policy)
+		if erro != nil {
+			logger.WarnContext(r.Context(), "rate limit store failed, letting the request through", "error", erro)
+			nextFunction(w, r)
+			return
+		}
+
+		header := w.Header()
+		header.Set("RateLimit-Limit", strconv.Itoa(policy.Limit))
+		header.Set("RateLimit-Remaining", strconv.Itoa(decision.Remaining))
+		header.Set("RateLimit-Reset", ceilSeconds(decision.Reset))
+		header.Set("RateLimit-Policy", fmt.Sprintf("%d;w=%s", policy.Limit, ceilSeconds(policy.Window)))
+
+		if !decision.Allowed {
+			metrics.RateLimited.WithLabelValues(path, kind).Inc()
+			header.Set("Retry-After", ceilSeconds(decision.RetryAfter))
+			responses.Problem(w, http.StatusTooManyRequests, responses.CodeRateLimited, errors.New("too many requests, retry later"))
 			return
 		}
+
 		nextFunction(w, r)
 	}
 }
 
-func handler(pattern string, next http.HandlerFunc) http.HandlerFunc {
+// ceilSeconds formats duration as a number of seconds, rounded up
+func ceilSeconds(duration time.Duration) string {
+	return strconv.FormatInt(int64((duration+time.Second-1)/time.Second), 10)
+}
+
+// Recover turns a panic of the handler into a 500, logging it with its stack trace and counting it by path.
+// http.ErrAbortHandler is left to net/http, which aborts the response on purpose.
+func Recover(logger *slog.Logger, metrics *prommetrics.Metrics, path string, nextFunction http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		guard := &panicGuard{ResponseWriter: w}
+
+		defer func() {
+			recovered := recover()
+			if recovered == nil {
+				return
+			}
+			if recovered == http.ErrAbortHandler {
+				panic(recovered)
+			}
+
+			metrics.Panics.WithLabelValues(path).Inc()
+			logger.ErrorContext(r.Context(), "panic serving the request",
+				"panic", fmt.Sprint(recovered),
+				"stack", string(debug.Stack()),
+			)
+
+			if !guard.wroteHeader {
+				responses.Erro(guard, http.StatusInternalServerError, errors.New("internal server error"))
+			}
+			guard.RecordError(fmt.Errorf("panic: %v", recovered))
+		}()
+
+		nextFunction(guard, r)
+	}
+}
+
+// Deadline bounds the request context, and so every query the handler runs, to timeout.
+// Requests whose context ends before the handler returns are counted by reason.
+func Deadline(metrics *prommetrics.Metrics, path string, timeout time.Duration, nextFunction http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		ctx := r.Context()
+		if timeout > 0 {
+			var cancel context.CancelFunc
+			ctx, cancel = context.WithTimeout(ctx, timeout)
+			defer cancel()
+		}
+
+		nextFunction(w, r.WithContext(ctx))
+
+		if erro := ctx.Err(); erro != nil {
+			reason := "canceled"
+			if errors.Is(erro, context.DeadlineExceeded) {
+				reason = "deadline_exceeded"
+			}
+			metrics.CanceledRequests.WithLabelValues(path, reason).Inc()
+		}
+	}
+}
+
+// Validate rejects with a 400 the requests whose parameters or body do not match the OpenAPI document.
+// With validateResponses the responses are checked as well and replaced by a 500 when they drift from the document,
+// which is meant for development and tests since the whole response is buffered.
+func Validate(validator *swagger.Validator, validateResponses bool, nextFunction http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		request, fields, erro := validator.ValidateRequest(r)
+		if erro != nil {
+			if validateResponses {
+				responses.Problem(w, http.StatusInternalServerError, responses.CodeUndocumented, erro)
+				return
+			}
+
+			nextFunction(w, r)
+			return
+		}
+
+		if len(fields) > 0 {
+			responses.InvalidFields(w, http.StatusBadRequest, responses.CodeInvalidRequest, errors.New("the request does not match the API specification"), fields)
+			return
+		}
+
+		if !validateResponses {
+			nextFunction(w, r)
+			return
+		}
+
+		buffer := &bufferedResponse{ResponseWriter: w, status: http.StatusOK}
+		nextFunction(buffer, r)
+
+		if fields := validator.ValidateResponse(r.Context(), request, buffer.status, w.Header(), buffer.body.Bytes()); len(fields) > 0 {
+			responses.InvalidFields(w, http.StatusInternalServerError, responses.CodeInvalidResponse, errors.New("the response does not match the API specification"), fields)
+			return
+		}
+
+		w.WriteHeader(buffer.status)
+		w.Write(buffer.body.Bytes())
+	}
+}
+
+// bufferedResponse holds a response until it is validated, hashed or compressed. The headers are shared with the real response writer.
+type bufferedResponse struct {
+	http.ResponseWriter
+	status int
+	body   bytes.Buffer
+}
+
+func (response *bufferedResponse) WriteHeader(statusCode int) {
+	response.status = statusCode
+}
+
+func (response *bufferedResponse) Write(body []byte) (int, error) {
+	return response.body.Write(body)
+}
+
+// RecordError forwards the error sent to the client to the access log, see responses.Erro
+func (response *bufferedResponse) RecordError(erro error) {
+	if recorder, ok := response.ResponseWriter.(interface{ RecordError(error) }); ok {
+		recorder.RecordError(erro)
+	}
+}
+
+// panicGuard tells Recover whether the response was already started when the handler panicked
+type panicGuard struct {
+	http.ResponseWriter
+	wroteHeader bool
+}
+
+func (guard *panicGuard) WriteHeader(statusCode int) {
+	guard.wroteHeader = true
+	guard.ResponseWriter.WriteHeader(statusCode)
+}
+
+func (guard *panicGuard) Write(body []byte) (int, error) {
+	guard.wroteHeader = true
+	return guard.ResponseWriter.Write(body)
+}
+
+// RecordError forwards the error sent to the client to the access log, see responses.Erro
+func (guard *panicGuard) RecordError(erro error) {
+	if recorder, ok := guard.ResponseWriter.(interface{ RecordError(error) }); ok {
+		recorder.RecordError(erro)
+	}
+}
+
+func (guard *panicGuard) Unwrap() http.ResponseWriter {
+	return guard.ResponseWriter
+}
+
+// statusRecorder keeps the status code, the body size and the error written by the handlers
+type statusRecorder struct {
+	http.ResponseWriter
+	status int
+	size   int
+	erro   error
+}
+
+func (recorder *statusRecorder) WriteHeader(statusCode int) {
+	recorder.status = statusCode
+	recorder.ResponseWriter.WriteHeader(statusCode)
+}
+
+func (recorder *statusRecorder) Write(body []byte) (int, error) {
+	written, erro := recorder.ResponseWriter.Write(body)
+	recorder.size += written
+	return written, erro
+}
+
+// RecordError keeps the error sent to the client, see responses.Erro
+func (recorder *statusRecorder) RecordError(erro error) {
+	recorder.erro = erro
+}
+
+func (recorder *statusRecorder) Unwrap() http.ResponseWriter {
+	return recorder.ResponseWriter
+}
+
+// requestID returns the ID sent by the client when it is valid, otherwise a new random one
+func requestID(r *http.Request) string {
+	if ID := r.Header.Get(RequestIDHeader); validRequestID.MatchString(ID) {
+		return ID
+	}
+
+	random := make([]byte, 16)
+	rand.Read(random)
+	return hex.EncodeToString(random)
+}
+
+func handler(logger *slog.Logger, metrics *prommetrics.Metrics, tracer trace.Tracer, pattern string, next http.HandlerFunc) http.HandlerFunc {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		metrics.RequestsCurrent.Inc()
+		defer metrics.RequestsCurrent.Dec()
+
+		ID := requestID(r)
+		w.Header().Set(RequestIDHeader, ID)
+
+		ctx := tracing.Propagator.Extract(r.Context(), propagation.HeaderCarrier(r.Header))
+		ctx, span := tracer.Start(ctx, r.Method+" "+pattern,
+			trace.WithSpanKind(trace.SpanKindServer),
+			trace.WithAttributes(
+				attribute.String("http.method", r.Method),
+				attribute.String("http.route", pattern),
+				attribute.String("http.target", r.RequestURI),
+				attribute.String("request.id", ID),
+			),
+		)
+		defer span.End()
+
+		r = r.WithContext(logging.WithRequest(ctx, ID))
+
 		now := time.Now()
-		next(w, r)
-		log.Printf("%s - - '%s %s %s' -", r.RemoteAddr, r.Method, r.RequestURI, r.Proto)
-		prommetrics.PromHandlerDuration.WithLabelValues(pattern).Observe(time.Since(now).Seconds())
+		recorder := &statusRecorder{ResponseWriter: w, status: http.StatusOK}
+		next(recorder, r)
+		elapsed := time.Since(now)
+
+		level := slog.LevelInfo
+		switch {
+		case recorder.status >= http.StatusInternalServerError:
+			level = slog.LevelError
+		case recorder.status >= http.StatusBadRequest:
+			level = slog.LevelWarn
+		}
+
+		attrs := []slog.Attr{
+			slog.String("method", r.Method),
+			slog.String("route", pattern),
+			slog.String("uri", r.RequestURI),
+			slog.Int("status", recorder.status),
+			slog.Float64("duration_ms", float64(elapsed.Microseconds())/1000),
+			slog.Int("size", recorder.size),
+			slog.String("remote_addr", r.RemoteAddr),
+		}
+		if recorder.erro != nil {
+			attrs = append(attrs, slog.String("error", recorder.erro.Error()))
+		}
+		logger.LogAttrs(r.Context(), level, "request", attrs...)
+
+		span.SetAttributes(
+			attribute.Int("http.status_code", recorder.status),
+			attribute.Int("http.response_content_length", recorder.size),
+		)
+		if userID := logging.UserID(r.Context()); userID != 0 {
+			span.SetAttributes(attribute.Int64("enduser.id", int64(userID)))
+		}
+		if recorder.status >= http.StatusInternalServerError {
+			if recorder.erro != nil {
+				span.RecordError(recorder.erro)
+			}
+			span.SetStatus(codes.Error, http.StatusText(recorder.status))
+		}
+
+		duration := elapsed.Seconds()
+		metrics.HandlerDuration.WithLabelValues(pattern).Observe(duration)
+		metrics.RequestsDuration.Observe(duration)
+		metrics.RequestStatus.WithLabelValues(strconv.Itoa(recorder.status)).Inc()
+		if recorder.status >= http.StatusBadRequest {
+			metrics.ClientErrors.Inc()
+		}
 	})
 }
@@ -0,0 +1,144 @@
+/*
+Copyright 2022 Danilo S. Lopes.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at:
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package middlewares
+
+import (
+	"api/src/config"
+	"api/src/logging"
+	"api/src/prommetrics"
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"go.opentelemetry.io/otel/trace/noop"
+)
+
+// The access log tells the route, status and size of the response along with the request and user IDs
+func TestLoggerAccessLog(t *testing.T) {
+	var output bytes.Buffer
+	logger := logging.New(&output, config.Log{Level: "info", Format: "json"})
+	metrics := prommetrics.New(prommetrics.NewRegistry())
+
+	handler := Logger(logger, metrics, noop.NewTracerProvider().Tracer(""), "/users/{userID}", func(w http.ResponseWriter, r *http.Request) {
+		logging.SetUserID(r.Context(), 7)
+		w.WriteHeader(http.StatusCreated)
+		w.Write([]byte("created"))
+	})
+
+	request := httptest.NewRequest(http.MethodPost, "/users/7", nil)
+	request.Header.Set(RequestIDHeader, "abc123")
+	response := httptest.NewRecorder()
+	handler(response, request)
+
+	if got := response.Header().Get(RequestIDHeader); got != "abc123" {
+		t.Fatalf("got the request ID %q, want the one of the client", got)
+	}
+
+	var record map[string]interface{}
+	if erro := json.Unmarshal(output.Bytes(), &record); erro != nil {
+		t.Fatalf("the access log %q is not a single JSON record: %v", output.String(), erro)
+	}
+
+	want := map[string]interface{}{
+		"msg":        "request",
+		"method":     http.MethodPost,
+		"route":      "/users/{userID}",
+		"status":     float64(http.StatusCreated),
+		"size":       float64(len("created")),
+		"request_id": "abc123",
+		"user_id":    float64(7),
+	}
+	for name, value := range want {
+		if record[name] != value {
+			t.Errorf("got %s %v, want %v", name, record[name], value)
+		}
+	}
+	if _, exists := record["duration_ms"]; !exists {
+		t.Errorf("the access log %v has no duration", record)
+	}
+}
+
+// A panicking handler is answered with a 500 problem, logged with its stack and counted
+func TestRecover(t *testing.T) {
+	var output bytes.Buffer
+	logger := logging.New(&output, config.Log{Level: "info", Format: "json"})
+	metrics := prommetrics.New(prommetrics.NewRegistry())
+
+	handler := Logger(logger, metrics, noop.NewTracerProvider().Tracer(""), "/publications",
+		Recover(logger, metrics, "/publications", func(w http.ResponseWriter, r *http.Request) {
+			panic("boom")
+		}),
+	)
+
+	response := httptest.NewRecorder()
+	handler(response, httptest.NewRequest(http.MethodGet, "/publications", nil))
+
+	if response.Code != http.StatusInternalServerError || response.Header().Get("Content-Type") != "application/problem+json" {
+		t.Fatalf("got %d %q, want a 500 problem", response.Code, response.Header().Get("Content-Type"))
+	}
+	if strings.Contains(response.Body.String(), "boom") {
+		t.Fatalf("the response tells the panic to the client: %s", response.Body)
+	}
+	if got := testutil.ToFloat64(metrics.Panics.WithLabelValues("/publications")); got != 1 {
+		t.Fatalf("counted %v panics, want 1", got)
+	}
+
+	logs := output.String()
+	if !strings.Contains(logs, `"msg":"panic serving the request"`) || !strings.Contains(logs, `"panic":"boom"`) || !strings.Contains(logs, "TestRecover") {
+		t.Fatalf("got the logs %s, want the panic logged with its stack", logs)
+	}
+	if !strings.Contains(logs, `"status":500`) || !strings.Contains(logs, `"error":"panic: boom"`) {
+		t.Fatalf("got the logs %s, want the access log of the 500 telling the panic", logs)
+	}
+}
+
+// A handler panicking after writing its response keeps it, the connection of http.ErrAbortHandler being
+// aborted by the server as usual
+func TestRecoverAfterWriting(t *testing.T) {
+	logger := logging.New(&bytes.Buffer{}, config.Log{Level: "info", Format: "json"})
+	metrics := prommetrics.New(prommetrics.NewRegistry())
+
+	written := Recover(logger, metrics, "/publications", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+		w.Write([]byte("partial"))
+		panic("boom")
+	})
+
+	response := httptest.NewRecorder()
+	written(response, httptest.NewRequest(http.MethodGet, "/publications", nil))
+	if response.Code != http.StatusOK || response.Body.String() != "partial" {
+		t.Fatalf("got %d %q, want the response written before the panic", response.Code, response.Body)
+	}
+
+	aborted := Recover(logger, metrics, "/publications", func(w http.ResponseWriter, r *http.Request) {
+		panic(http.ErrAbortHandler)
+	})
+
+	defer func() {
+		if recovered := recover(); recovered != http.ErrAbortHandler {
+			t.Fatalf("got the panic %v, want http.ErrAbortHandler passed on", recovered)
+		}
+		if got := testutil.ToFloat64(metrics.Panics.WithLabelValues("/publications")); got != 1 {
+			t.Fatalf("counted %v panics, want the aborted request left out", got)
+		}
+	}()
+	aborted(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/publications", nil))
+}
@@ -0,0 +1,114 @@
+/*
+Copyright 2022 Danilo S. Lopes.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at:
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package migrations
+
+import (
+	"embed"
+	"fmt"
+	"io/fs"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// The schema migrations embedded in the binary, one directory per database driver.
+// Files are named <version>_<name>.up.sql and <version>_<name>.down.sql
+//
+//go:embed mysql/*.sql sqlite/*.sql postgres/*.sql
+var files embed.FS
+
+var fileName = regexp.MustCompile(`^(\d+)_(\w+)\.(up|down)\.sql$`)
+
+// Migration represents one numbered schema change
+type Migration struct {
+	Version int
+	Name    string
+	Up      string
+	Down    string
+}
+
+// Load return the migrations of the given driver sorted by version
+func Load(driver string) ([]Migration, error) {
+	entries, erro := fs.ReadDir(files, driver)
+	if erro != nil {
+		return nil, fmt.Errorf("no migrations for database driver %q", driver)
+	}
+
+	byVersion := make(map[int]*Migration)
+
+	for _, entry := range entries {
+		parts := fileName.FindStringSubmatch(entry.Name())
+		if parts == nil {
+			return nil, fmt.Errorf("invalid migration file name %q", entry.Name())
+		}
+
+		version, erro := strconv.Atoi(parts[1])
+		if erro != nil {
+			return nil, erro
+		}
+
+		content, erro := fs.ReadFile(files, driver+"/"+entry.Name())
+		if erro != nil {
+			return nil, erro
+		}
+
+		migration, exists := byVersion[version]
+		if !exists {
+			migration = &Migration{Version: version, Name: parts[2]}
+			byVersion[version] = migration
+		}
+
+		if migration.Name != parts[2] {
+			return nil, fmt.Errorf("migration %d has two different names: %q and %q", version, migration.Name, parts[2])
+		}
+
+		if parts[3] == "up" {
+			migration.Up = string(content)
+		} else {
+			migration.Down = string(content)
+		}
+	}
+
+	var migrations []Migration
+	for _, migration := range byVersion {
+		if migration.Up == "" || migration.Down == "" {
+			return nil, fmt.Errorf("migration %d_%s needs both an up and a down file", migration.Version, migration.Name)
+		}
+
+		migrations = append(migrations, *migration)
+	}
+
+	sort.Slice(migrations, func(i, j int) bool { return migrations[i].Version < migrations[j].Version })
+
+	return migrations, nil
+}
+
+// statements split a migration script into the statements it holds,
+// since the drivers run a single statement per Exec call
+func statements(script string) []string {
+	var result []string
+
+	for _, statement := range strings.Split(script, ";\n") {
+		statement = strings.TrimSpace(strings.TrimSuffix(strings.TrimSpace(statement), ";"))
+		if statement != "" {
+			result = append(result, statement)
+		}
+	}
+
+	return result
+}
@@ -0,0 +1,195 @@
+/*
+Copyright 2022 Danilo S. Lopes.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at:
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package migrations
+
+import (
+	"api/src/config"
+	"api/src/database"
+	"context"
+	"database/sql"
+	"io"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"reflect"
+	"regexp"
+	"sort"
+	"strings"
+	"testing"
+
+	"go.opentelemetry.io/otel/trace/noop"
+)
+
+var drivers = []string{"mysql", "postgres", "sqlite"}
+
+// engineDSNs names the variables holding the data source names of the test databases of MySQL and PostgreSQL
+var engineDSNs = map[string]string{
+	"mysql":    "TEST_MYSQL_DSN",
+	"postgres": "TEST_POSTGRES_DSN",
+}
+
+var (
+	createdTable = regexp.MustCompile(`(?i)CREATE TABLE (?:IF NOT EXISTS )?(\w+)`)
+	createdIndex = regexp.MustCompile(`(?i)CREATE (?:UNIQUE )?INDEX (?:IF NOT EXISTS )?(\w+) ON (\w+) \(([^)]*)\)`)
+	droppedTable = regexp.MustCompile(`(?i)DROP TABLE (?:IF EXISTS )?(\w+)`)
+	droppedIndex = regexp.MustCompile(`(?i)DROP INDEX (?:IF EXISTS )?(\w+)`)
+)
+
+// schema lists what a migration script creates, e.g. "table users" or "index users_createdat_id ON users (createdat, id)"
+func schema(script string) []string {
+	var objects []string
+	for _, match := range createdTable.FindAllStringSubmatch(script, -1) {
+		objects = append(objects, "table "+match[1])
+	}
+	for _, match := range createdIndex.FindAllStringSubmatch(script, -1) {
+		objects = append(objects, "index "+match[1]+" ON "+match[2]+" ("+strings.Join(strings.Fields(match[3]), " ")+")")
+	}
+	sort.Strings(objects)
+
+	return objects
+}
+
+// dropped lists the tables and indexes a migration script drops
+func dropped(script string) map[string]bool {
+	objects := make(map[string]bool)
+	for _, match := range droppedTable.FindAllStringSubmatch(script, -1) {
+		objects["table "+match[1]] = true
+	}
+	for _, match := range droppedIndex.FindAllStringSubmatch(script, -1) {
+		objects["index "+match[1]] = true
+	}
+
+	return objects
+}
+
+// The drivers get the same migrations, creating the same tables and indexes
+func TestDriversShareMigrations(t *testing.T) {
+	reference, erro := Load(drivers[0])
+	if erro != nil {
+		t.Fatal(erro)
+	}
+
+	for _, driver := range drivers[1:] {
+		migrations, erro := Load(driver)
+		if erro != nil {
+			t.Fatal(erro)
+		}
+
+		if len(migrations) != len(reference) {
+			t.Fatalf("%s has %d migrations, %s has %d", driver, len(migrations), drivers[0], len(reference))
+		}
+
+		for index, migration := range migrations {
+			expected := reference[index]
+			if migration.Version != expected.Version || migration.Name != expected.Name {
+				t.Fatalf("%s migration %d_%s, %s has %d_%s", driver, migration.Version, migration.Name, drivers[0], expected.Version, expected.Name)
+			}
+
+			if got, want := schema(migration.Up), schema(expected.Up); !reflect.DeepEqual(got, want) {
+				t.Errorf("%s migration %d_%s creates %v, %s creates %v", driver, migration.Version, migration.Name, got, drivers[0], want)
+			}
+		}
+	}
+}
+
+// The down scripts drop every table and index their up script creates, the indexes along with their table
+func TestMigrationsRevertible(t *testing.T) {
+	for _, driver := range drivers {
+		migrations, erro := Load(driver)
+		if erro != nil {
+			t.Fatal(erro)
+		}
+
+		for _, migration := range migrations {
+			if strings.TrimSpace(migration.Down) == "" {
+				t.Errorf("%s migration %d_%s has no down script", driver, migration.Version, migration.Name)
+				continue
+			}
+
+			removed := dropped(migration.Down)
+			for _, object := range schema(migration.Up) {
+				kind, rest, _ := strings.Cut(object, " ")
+				name, on, _ := strings.Cut(rest, " ON ")
+				table, _, _ := strings.Cut(on, " ")
+				if !removed[kind+" "+name] && !(kind == "index" && removed["table "+table]) {
+					t.Errorf("%s migration %d_%s does not drop the %s %s it creates", driver, migration.Version, migration.Name, kind, name)
+				}
+			}
+		}
+	}
+}
+
+// The migrations go up and down twice in a row, each down undoing exactly its up
+func TestMigrationsRoundTrip(t *testing.T) {
+	for _, driver := range drivers {
+		t.Run(driver, func(t *testing.T) {
+			migrator, erro := New(openDatabase(t, driver))
+			if erro != nil {
+				t.Fatal(erro)
+			}
+
+			ctx := context.Background()
+			for round := 1; round <= 2; round++ {
+				if erro := migrator.Up(ctx); erro != nil {
+					t.Fatalf("round %d up: %v", round, erro)
+				}
+				for version := migrator.Latest(); version > 0; version-- {
+					if erro := migrator.Down(ctx); erro != nil {
+						t.Fatalf("round %d down from version %d: %v", round, version, erro)
+					}
+				}
+			}
+
+			// leave the shared test databases migrated for the other tests
+			if erro := migrator.Up(ctx); erro != nil {
+				t.Fatal(erro)
+			}
+		})
+	}
+}
+
+// openDatabase returns a database of driver: a new SQLite file, or the test database of MySQL and
+// PostgreSQL when its data source name is set
+func openDatabase(t *testing.T, driver string) *database.DB {
+	t.Helper()
+
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	tracer := noop.NewTracerProvider().Tracer("")
+
+	var db *database.DB
+	if driver == "sqlite" {
+		var erro error
+		db, erro = database.Connect(config.Database{Driver: "sqlite", Path: filepath.Join(t.TempDir(), "sm.db")}, logger, tracer)
+		if erro != nil {
+			t.Fatal(erro)
+		}
+	} else {
+		dsn := os.Getenv(engineDSNs[driver])
+		if dsn == "" {
+			t.Skipf("%s is not set", engineDSNs[driver])
+		}
+
+		pool, erro := sql.Open(driver, dsn)
+		if erro != nil {
+			t.Fatal(erro)
+		}
+		db = &database.DB{DB: pool, Dialect: database.Dialect(driver), Logger: logger, Tracer: tracer}
+	}
+	t.Cleanup(func() { db.Close() })
+
+	return db
+}
@@ -0,0 +1,363 @@
+/*
+Copyright 2022 Danilo S. Lopes.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at:
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package migrations
+
+import (
+	"api/src/database"
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+)
+
+const (
+	trackingTable = "schema_migrations"
+	lockName      = "sm_schema_migrations"
+	lockTimeout   = 30 // seconds
+
+	// advisory lock key used on PostgreSQL, which only takes numeric keys
+	lockKey = 7428031
+)
+
+// ErrOutdated is returned by Check when the database misses migrations known by the binary
+var ErrOutdated = errors.New("the database schema is out of date")
+
+// Status represents a migration and whether it is applied in the database
+type Status struct {
+	Version   int
+	Name      string
+	Applied   bool
+	AppliedAt time.Time
+}
+
+// Migrator applies the embedded migrations into a database
+type Migrator struct {
+	db         *database.DB
+	migrations []Migration
+}
+
+// New creates a Migrator loading the migrations of the database dialect
+func New(db *database.DB) (*Migrator, error) {
+	migrations, erro := Load(string(db.Dialect))
+	if erro != nil {
+		return nil, erro
+	}
+
+	return &Migrator{db, migrations}, nil
+}
+
+// Latest return the highest version known by the binary
+func (migrator *Migrator) Latest() int {
+	if len(migrator.migrations) == 0 {
+		return 0
+	}
+
+	return migrator.migrations[len(migrator.migrations)-1].Version
+}
+
+// Up applies every pending migration
+func (migrator *Migrator) Up(ctx context.Context) error {
+	return migrator.To(ctx, migrator.Latest())
+}
+
+// Down reverts the last applied migration
+func (migrator *Migrator) Down(ctx context.Context) error {
+	return migrator.withLock(ctx, func(conn *sql.Conn, applied map[int]time.Time) error {
+		for index := len(migrator.migrations) - 1; index >= 0; index-- {
+			migration := migrator.migrations[index]
+			if _, isApplied := applied[migration.Version]; isApplied {
+				return migrator.revert(ctx, conn, migration)
+			}
+		}
+
+		return nil
+	})
+}
+
+// To applies or reverts migrations until the database is exactly at version
+func (migrator *Migrator) To(ctx context.Context, version int) error {
+	if version != 0 && !migrator.known(version) {
+		return fmt.Errorf("unknown migration version %d", version)
+	}
+
+	return migrator.withLock(ctx, func(conn *sql.Conn, applied map[int]time.Time) error {
+		for index := len(migrator.migrations) - 1; index >= 0; index-- {
+			migration := migrator.migrations[index]
+			if _, isApplied := applied[migration.Version]; isApplied && migration.Version > version {
+				if erro := migrator.revert(ctx, conn, migration); erro != nil {
+					return erro
+				}
+			}
+		}
+
+		for _, migration := range migrator.migrations {
+			if _, isApplied := applied[migration.Version]; !isApplied && migration.Version <= version {
+				if erro := migrator.apply(ctx, conn, migration); erro != nil {
+					return erro
+				}
+			}
+		}
+
+		return nil
+	})
+}
+
+// Status return every known migration and whether it is applied. It only reads the tracking table, taking
+// no lock and changing no schema, so it runs at every startup with the rights of the API.
+func (migrator *Migrator) Status(ctx context.Context) ([]Status, error) {
+	applied, erro := migrator.readApplied(ctx)
+	if erro != nil {
+		return nil, erro
+	}
+
+	var status []Status
+	for _, migration := range migrator.migrations {
+		appliedAt, isApplied := applied[migration.Version]
+		status = append(status, Status{
+			Version:   migration.Version,
+			Name:      migration.Name,
+			Applied:   isApplied,
+			AppliedAt: appliedAt,
+		})
+	}
+
+	return status, nil
+}
+
+// Check returns ErrOutdated when any known migration is not applied
+func (migrator *Migrator) Check(ctx context.Context) error {
+	status, erro := migrator.Status(ctx)
+	if erro != nil {
+		return erro
+	}
+
+	for _, migration := range status {
+		if !migration.Applied {
+			return fmt.Errorf("%w: migration %d_%s is not applied, run `sm migrate up`", ErrOutdated, migration.Version, migration.Name)
+		}
+	}
+
+	return nil
+}
+
+func (migrator *Migrator) known(version int) bool {
+	for _, migration := range migrator.migrations {
+		if migration.Version == version {
+			return true
+		}
+	}
+
+	return false
+}
+
+// readApplied returns the applied versions without the lock, none when the tracking table does not exist yet
+func (migrator *Migrator) readApplied(ctx context.Context) (map[int]time.Time, error) {
+	conn, erro := migrator.db.Conn(ctx)
+	if erro != nil {
+		return nil, erro
+	}
+	defer conn.Close()
+
+	var query string
+	switch migrator.db.Dialect {
+	case database.SQLite:
+		query = "SELECT COUNT(*) FROM sqlite_master WHERE type = 'table' AND name = ?"
+	case database.Postgres:
+		query = "SELECT COUNT(*) FROM information_schema.tables WHERE table_schema = current_schema() AND table_name = $1"
+	default:
+		query = "SELECT COUNT(*) FROM information_schema.tables WHERE table_schema = DATABASE() AND table_name = ?"
+	}
+
+	var tables int
+	if erro := conn.QueryRowContext(ctx, query, trackingTable).Scan(&tables); erro != nil {
+		return nil, erro
+	}
+	if tables == 0 {
+		return map[int]time.Time{}, nil
+	}
+
+	return appliedVersions(ctx, conn)
+}
+
+// withLock runs fn with the applied versions, holding a database wide lock so two processes never migrate
+// at the same time. The lock is taken before creating the tracking table and reading the applied versions.
+// SQLite has no lock but its write transactions, so fn runs in a single BEGIN IMMEDIATE transaction there.
+func (migrator *Migrator) withLock(ctx context.Context, fn func(conn *sql.Conn, applied map[int]time.Time) error) (erro error) {
+	conn, erro := migrator.db.Conn(ctx)
+	if erro != nil {
+		return erro
+	}
+	defer conn.Close()
+
+	release, erro := migrator.lock(ctx, conn)
+	if erro != nil {
+		return erro
+	}
+	defer func() {
+		erro = release(erro)
+	}()
+
+	if _, erro := conn.ExecContext(ctx, `
+		CREATE TABLE IF NOT EXISTS `+trackingTable+`(
+			version INT PRIMARY KEY,
+			name VARCHAR(255) NOT NULL,
+			appliedat TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+		)
+	`); erro != nil {
+		return erro
+	}
+
+	applied, erro := appliedVersions(ctx, conn)
+	if erro != nil {
+		return erro
+	}
+
+	return fn(conn, applied)
+}
+
+// lock takes the migration lock in conn and returns the function releasing it, given the result of the
+// work done under the lock
+func (migrator *Migrator) lock(ctx context.Context, conn *sql.Conn) (func(erro error) error, error) {
+	switch migrator.db.Dialect {
+	case database.SQLite:
+		if _, erro := conn.ExecContext(ctx, "BEGIN IMMEDIATE"); erro != nil {
+			return nil, fmt.Errorf("another process is migrating the database: %w", erro)
+		}
+
+		return func(erro error) error {
+			if erro != nil {
+				conn.ExecContext(context.Background(), "ROLLBACK")
+				return erro
+			}
+
+			_, erro = conn.ExecContext(ctx, "COMMIT")
+			return erro
+		}, nil
+	case database.Postgres:
+		lockCtx, cancel := context.WithTimeout(ctx, lockTimeout*time.Second)
+		defer cancel()
+
+		if _, erro := conn.ExecContext(lockCtx, "SELECT pg_advisory_lock($1)", lockKey); erro != nil {
+			return nil, fmt.Errorf("another process is migrating the database: %w", erro)
+		}
+
+		return func(erro error) error {
+			conn.ExecContext(context.Background(), "SELECT pg_advisory_unlock($1)", lockKey)
+			return erro
+		}, nil
+	default:
+		var acquired sql.NullInt64
+		if erro := conn.QueryRowContext(ctx, "SELECT GET_LOCK(?, ?)", lockName, lockTimeout).Scan(&acquired); erro != nil {
+			return nil, erro
+		}
+
+		if !acquired.Valid || acquired.Int64 != 1 {
+			return nil, errors.New("another process is migrating the database")
+		}
+
+		return func(erro error) error {
+			conn.ExecContext(context.Background(), "SELECT RELEASE_LOCK(?)", lockName)
+			return erro
+		}, nil
+	}
+}
+
+func appliedVersions(ctx context.Context, conn *sql.Conn) (map[int]time.Time, error) {
+	lines, erro := conn.QueryContext(ctx, "SELECT version, appliedat FROM "+trackingTable)
+	if erro != nil {
+		return nil, erro
+	}
+	defer lines.Close()
+
+	applied := make(map[int]time.Time)
+
+	for lines.Next() {
+		var (
+			version   int
+			appliedAt time.Time
+		)
+
+		if erro := lines.Scan(&version, &appliedAt); erro != nil {
+			return nil, erro
+		}
+
+		applied[version] = appliedAt
+	}
+
+	return applied, lines.Err()
+}
+
+// execer is satisfied by both *sql.Conn and *sql.Tx
+type execer interface {
+	ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error)
+}
+
+// inTransaction runs fn in a transaction when the dialect supports transactional DDL, otherwise straight
+// in the connection since MySQL commits every DDL statement implicitly. On SQLite the connection already
+// is in the transaction of the lock.
+func (migrator *Migrator) inTransaction(ctx context.Context, conn *sql.Conn, fn func(exec execer) error) error {
+	if !migrator.db.Dialect.TransactionalDDL() || migrator.db.Dialect == database.SQLite {
+		return fn(conn)
+	}
+
+	tx, erro := conn.BeginTx(ctx, nil)
+	if erro != nil {
+		return erro
+	}
+
+	if erro := fn(tx); erro != nil {
+		tx.Rollback()
+		return erro
+	}
+
+	return tx.Commit()
+}
+
+func (migrator *Migrator) apply(ctx context.Context, conn *sql.Conn, migration Migration) error {
+	return migrator.inTransaction(ctx, conn, func(exec execer) error {
+		for _, statement := range statements(migration.Up) {
+			if _, erro := exec.ExecContext(ctx, statement); erro != nil {
+				return fmt.Errorf("applying migration %d_%s: %w", migration.Version, migration.Name, erro)
+			}
+		}
+
+		_, erro := exec.ExecContext(ctx,
+			migrator.db.Dialect.Rebind("INSERT INTO "+trackingTable+" (version, name) VALUES (?, ?)"),
+			migration.Version, migration.Name,
+		)
+
+		return erro
+	})
+}
+
+func (migrator *Migrator) revert(ctx context.Context, conn *sql.Conn, migration Migration) error {
+	return migrator.inTransaction(ctx, conn, func(exec execer) error {
+		for _, statement := range statements(migration.Down) {
+			if _, erro := exec.ExecContext(ctx, statement); erro != nil {
+				return fmt.Errorf("reverting migration %d_%s: %w", migration.Version, migration.Name, erro)
+			}
+		}
+
+		_, erro := exec.ExecContext(ctx,
+			migrator.db.Dialect.Rebind("DELETE FROM "+trackingTable+" WHERE version = ?"),
+			migration.Version,
+		)
+
+		return erro
+	})
+}
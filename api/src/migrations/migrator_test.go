@@ -0,0 +1,120 @@
+/*
+Copyright 2022 Danilo S. Lopes.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at:
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package migrations
+
+import (
+	"api/src/config"
+	"api/src/database"
+	"context"
+	"errors"
+	"io"
+	"log/slog"
+	"path/filepath"
+	"sync"
+	"testing"
+
+	"go.opentelemetry.io/otel/trace/noop"
+)
+
+// The migrators of several processes sharing a SQLite database apply each migration once
+func TestMigratorsRunOneAtATime(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "sm.db")
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+
+	var migrators []*Migrator
+	for range [4]struct{}{} {
+		db, erro := database.Connect(config.Database{Driver: "sqlite", Path: path}, logger, noop.NewTracerProvider().Tracer(""))
+		if erro != nil {
+			t.Fatal(erro)
+		}
+		t.Cleanup(func() { db.Close() })
+
+		migrator, erro := New(db)
+		if erro != nil {
+			t.Fatal(erro)
+		}
+		migrators = append(migrators, migrator)
+	}
+
+	var wait sync.WaitGroup
+	errs := make(chan error, len(migrators))
+	for _, migrator := range migrators {
+		wait.Add(1)
+		go func(migrator *Migrator) {
+			defer wait.Done()
+			errs <- migrator.Up(context.Background())
+		}(migrator)
+	}
+	wait.Wait()
+	close(errs)
+
+	for erro := range errs {
+		if erro != nil {
+			t.Fatalf("migrating concurrently: %v", erro)
+		}
+	}
+
+	if erro := migrators[0].Check(context.Background()); erro != nil {
+		t.Fatal(erro)
+	}
+	if erro := migrators[1].To(context.Background(), 0); erro != nil {
+		t.Fatalf("reverting every migration: %v", erro)
+	}
+	if erro := migrators[2].Up(context.Background()); erro != nil {
+		t.Fatalf("migrating again: %v", erro)
+	}
+}
+
+// Status reads a database never migrated without creating the tracking table, even while it is being migrated
+func TestStatusChangesNothing(t *testing.T) {
+	db := openDatabase(t, "sqlite")
+	migrator, erro := New(db)
+	if erro != nil {
+		t.Fatal(erro)
+	}
+
+	if erro := migrator.Check(context.Background()); !errors.Is(erro, ErrOutdated) {
+		t.Fatalf("checking a database never migrated got %v, want ErrOutdated", erro)
+	}
+
+	var tables int
+	if erro := db.QueryRowContext(context.Background(), "SELECT COUNT(*) FROM sqlite_master WHERE name = ?", trackingTable).Scan(&tables); erro != nil {
+		t.Fatal(erro)
+	}
+	if tables != 0 {
+		t.Fatal("checking the schema created the tracking table")
+	}
+
+	if erro := migrator.Up(context.Background()); erro != nil {
+		t.Fatal(erro)
+	}
+
+	// a migration holds the lock meanwhile
+	conn, erro := db.Conn(context.Background())
+	if erro != nil {
+		t.Fatal(erro)
+	}
+	defer conn.Close()
+	if _, erro := conn.ExecContext(context.Background(), "BEGIN IMMEDIATE"); erro != nil {
+		t.Fatal(erro)
+	}
+	defer conn.ExecContext(context.Background(), "ROLLBACK")
+
+	if erro := migrator.Check(context.Background()); erro != nil {
+		t.Fatalf("checking a migrated database while it is locked: %v", erro)
+	}
+}
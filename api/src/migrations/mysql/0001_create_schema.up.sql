@@ -1,7 +1,4 @@
-CREATE DATABASE IF NOT EXISTS sm;
-USE sm;
-
-CREATE TABLE users(
+CREATE TABLE IF NOT EXISTS users(
     id INT AUTO_INCREMENT PRIMARY KEY,
     name VARCHAR(50) NOT NULL,
     nick VARCHAR(50) NOT NULL UNIQUE,
@@ -10,7 +7,7 @@ CREATE TABLE users(
     createdat TIMESTAMP DEFAULT current_timestamp()
 ) ENGINE=INNODB;
 
-CREATE TABLE followers(
+CREATE TABLE IF NOT EXISTS followers(
     user_id INT NOT NULL,
         FOREIGN KEY(user_id) REFERENCES users(id) ON DELETE CASCADE,
     follower_id INT NOT NULL,
@@ -18,7 +15,7 @@ CREATE TABLE followers(
     PRIMARY KEY(user_id, follower_id)
 ) ENGINE=INNODB;
 
-CREATE TABLE publications(
+CREATE TABLE IF NOT EXISTS publications(
     id INT AUTO_INCREMENT PRIMARY KEY,
     title VARCHAR(50) NOT NULL,
     content VARCHAR(300) NOT NULL,
@@ -28,7 +25,7 @@ CREATE TABLE publications(
     createdat TIMESTAMP DEFAULT current_timestamp()
 ) ENGINE=INNODB;
 
-CREATE TABLE likes_of_publications(
+CREATE TABLE IF NOT EXISTS likes_of_publications(
     publication_id INT NOT NULL,
         FOREIGN KEY(publication_id) REFERENCES publications(id) ON DELETE CASCADE,
     liker_id INT NOT NULL,
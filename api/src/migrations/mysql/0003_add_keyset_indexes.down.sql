@@ -0,0 +1,4 @@
+CREATE INDEX author_id ON publications (author_id);
+DROP INDEX publications_author_createdat_id ON publications;
+DROP INDEX publications_createdat_id ON publications;
+DROP INDEX users_createdat_id ON users;
@@ -0,0 +1,11 @@
+CREATE INDEX users_createdat_id ON users (createdat, id);
+CREATE INDEX publications_createdat_id ON publications (createdat, id);
+CREATE INDEX publications_author_createdat_id ON publications (author_id, createdat, id);
+SET @dropAuthorIndex = (
+    SELECT IF(COUNT(*) = 0, 'DO 0', 'DROP INDEX author_id ON publications')
+    FROM information_schema.statistics
+    WHERE table_schema = DATABASE() AND table_name = 'publications' AND index_name = 'author_id'
+);
+PREPARE dropAuthorIndex FROM @dropAuthorIndex;
+EXECUTE dropAuthorIndex;
+DEALLOCATE PREPARE dropAuthorIndex;
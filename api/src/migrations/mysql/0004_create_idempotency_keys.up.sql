@@ -0,0 +1,11 @@
+CREATE TABLE IF NOT EXISTS idempotency_keys(
+    user_id INT NOT NULL,
+    idempotency_key VARCHAR(255) NOT NULL,
+    fingerprint CHAR(64) NOT NULL,
+    status INT NOT NULL DEFAULT 0,
+    content_type VARCHAR(100) NOT NULL DEFAULT '',
+    body MEDIUMBLOB,
+    expires_at DATETIME(6) NOT NULL,
+    PRIMARY KEY(user_id, idempotency_key)
+) ENGINE=INNODB;
+CREATE INDEX idempotency_keys_expires_at ON idempotency_keys (expires_at);
@@ -0,0 +1,34 @@
+CREATE TABLE IF NOT EXISTS users(
+    id SERIAL PRIMARY KEY,
+    name VARCHAR(50) NOT NULL,
+    nick VARCHAR(50) NOT NULL UNIQUE,
+    email VARCHAR(50) NOT NULL UNIQUE,
+    pass VARCHAR(100) NOT NULL,
+    createdat TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE TABLE IF NOT EXISTS followers(
+    user_id INT NOT NULL
+        REFERENCES users(id) ON DELETE CASCADE,
+    follower_id INT NOT NULL
+        REFERENCES users(id) ON DELETE CASCADE,
+    PRIMARY KEY(user_id, follower_id)
+);
+
+CREATE TABLE IF NOT EXISTS publications(
+    id SERIAL PRIMARY KEY,
+    title VARCHAR(50) NOT NULL,
+    content VARCHAR(300) NOT NULL,
+    author_id INT NOT NULL
+        REFERENCES users(id) ON DELETE CASCADE,
+    likes INT DEFAULT 0,
+    createdat TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE TABLE IF NOT EXISTS likes_of_publications(
+    publication_id INT NOT NULL
+        REFERENCES publications(id) ON DELETE CASCADE,
+    liker_id INT NOT NULL
+        REFERENCES users(id) ON DELETE CASCADE,
+    PRIMARY KEY(publication_id, liker_id)
+);
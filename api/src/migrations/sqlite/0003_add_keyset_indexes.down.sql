@@ -0,0 +1,3 @@
+DROP INDEX IF EXISTS publications_author_createdat_id;
+DROP INDEX IF EXISTS publications_createdat_id;
+DROP INDEX IF EXISTS users_createdat_id;
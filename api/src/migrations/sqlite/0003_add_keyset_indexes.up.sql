@@ -0,0 +1,3 @@
+CREATE INDEX IF NOT EXISTS users_createdat_id ON users (createdat, id);
+CREATE INDEX IF NOT EXISTS publications_createdat_id ON publications (createdat, id);
+CREATE INDEX IF NOT EXISTS publications_author_createdat_id ON publications (author_id, createdat, id);
@@ -0,0 +1,11 @@
+CREATE TABLE IF NOT EXISTS idempotency_keys(
+    user_id INTEGER NOT NULL,
+    idempotency_key VARCHAR(255) NOT NULL,
+    fingerprint CHAR(64) NOT NULL,
+    status INTEGER NOT NULL DEFAULT 0,
+    content_type VARCHAR(100) NOT NULL DEFAULT '',
+    body BLOB,
+    expires_at TIMESTAMP NOT NULL,
+    PRIMARY KEY(user_id, idempotency_key)
+);
+CREATE INDEX IF NOT EXISTS idempotency_keys_expires_at ON idempotency_keys (expires_at);
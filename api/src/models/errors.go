@@ -0,0 +1,80 @@
+/*
+Copyright 2022 Danilo S. Lopes.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at:
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package models
+
+import "errors"
+
+// Kinds of the domain errors. errors.Is(erro, ErrNotFound) tells the kind of any Error.
+var (
+	ErrNotFound   = errors.New("not found")
+	ErrConflict   = errors.New("conflict")
+	ErrForbidden  = errors.New("forbidden")
+	ErrValidation = errors.New("validation failed")
+)
+
+// Codes of the domain errors, answered to the clients along with the status of their kind
+const (
+	CodeUserNotFound         = "user.not_found"
+	CodeUserInvalid          = "user.invalid"
+	CodeUserNickTaken        = "user.nick_taken"
+	CodeUserEmailTaken       = "user.email_taken"
+	CodeUserForbidden        = "user.forbidden"
+	CodeUserFollowSelf       = "user.follow_self"
+	CodePublicationNotFound  = "publication.not_found"
+	CodePublicationInvalid   = "publication.invalid"
+	CodePublicationForbidden = "publication.forbidden"
+	CodePublicationLiked     = "publication.already_liked"
+)
+
+// Error is an expected failure of a business rule, told apart by its kind and code
+type Error struct {
+	Kind    error
+	Code    string
+	Message string
+
+	// Field names the attribute breaking a validation rule, if any
+	Field string
+}
+
+func (erro *Error) Error() string {
+	return erro.Message
+}
+
+// Is makes errors.Is match the kind of the error
+func (erro *Error) Is(target error) bool {
+	return target == erro.Kind
+}
+
+// NotFound reports a resource that does not exist
+func NotFound(code, message string) error {
+	return &Error{Kind: ErrNotFound, Code: code, Message: message}
+}
+
+// Conflict reports a change clashing with the stored data, e.g. a nick already in use
+func Conflict(code, message string) error {
+	return &Error{Kind: ErrConflict, Code: code, Message: message}
+}
+
+// Forbidden reports an action the user is not allowed to perform
+func Forbidden(code, message string) error {
+	return &Error{Kind: ErrForbidden, Code: code, Message: message}
+}
+
+// Invalid reports the field breaking the validation rules
+func Invalid(code, field, message string) error {
+	return &Error{Kind: ErrValidation, Code: code, Message: message, Field: field}
+}
@@ -0,0 +1,37 @@
+/*
+Copyright 2022 Danilo S. Lopes.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at:
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package models
+
+import "time"
+
+// IdempotencyRecord holds the response of the request a user first sent with an Idempotency-Key,
+// replayed to the retries of the same request until ExpiresAt
+type IdempotencyRecord struct {
+	UserID uint64
+	Key    string
+
+	// Hash of the request, telling its retries apart from another request sent with the same key
+	Fingerprint string
+
+	// Status, Content-Type and body of the response, Status being zero while the request is served
+	Status      int
+	ContentType string
+	Body        []byte
+
+	// End of the lease of the request in progress, then of the stored response
+	ExpiresAt time.Time
+}
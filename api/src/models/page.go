@@ -0,0 +1,25 @@
+/*
+Copyright 2022 Danilo S. Lopes.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at:
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package models
+
+// Page is one page of a list, as answered by the version 2 of the API, along with the URLs of the
+// pages around it, the ones of the Link header
+type Page[T any] struct {
+	Items    []T    `json:"items"`
+	Previous string `json:"prev,omitempty" example:"/v2/publications?cursor=eyJ0IjoxNjY0NTgzMjAwMDAwMDAwMDAwLCJpIjoxMCwiYiI6dHJ1ZX0.T2bLwxO6SgnQdFpXhZo8yA&limit=20"`
+	Next     string `json:"next,omitempty" example:"/v2/publications?cursor=eyJ0IjoxNjY0NTgzMjAwMDAwMDAwMDAwLCJpIjo5fQ.Zr8S4Jx2vn1rsKnKZ0b1Jw&limit=20"`
+}
@@ -18,6 +18,6 @@ package models
 
 // Pass represents the password struc for password change
 type Pass struct {
-	New     string `json:"new"`
-	Current string `json:"current"`
+	New     string `json:"new" example:"n3w!@#$$#@!"`
+	Current string `json:"current" example:"usr!@#$$#@!"`
 }
@@ -17,23 +17,28 @@ limitations under the License.
 package models
 
 import (
-	"errors"
+	"api/src/pagination"
 	"strings"
 	"time"
 )
 
 // Publications represents en publication made by user
 type Publication struct {
-	ID          uint64    `json:"id,omitempty"`
-	Title       string    `json:"title,omitempty"`
-	Content     string    `json:"content,omitempty"`
-	AuthorID    uint64    `json:"authorid,omitempty"`
-	AuthorNick  string    `json:"authornick,omitempty"`
-	AuthorEmail string    `json:"authoremail,omitempty"`
-	Likes       uint64    `json:"likes"`
+	ID          uint64    `json:"id,omitempty" example:"1"`
+	Title       string    `json:"title,omitempty" example:"Publication Foo Bar"`
+	Content     string    `json:"content,omitempty" example:"My publication"`
+	AuthorID    uint64    `json:"authorid,omitempty" example:"1"`
+	AuthorNick  string    `json:"authornick,omitempty" example:"usr1"`
+	AuthorEmail string    `json:"authoremail,omitempty" example:"user1@gmail.com"`
+	Likes       uint64    `json:"likes" example:"5"`
 	CreatedAt   time.Time `json:"createdat,omitempty"`
 }
 
+// Key returns the position of the publication in the lists
+func (publication Publication) Key() pagination.Key {
+	return pagination.Key{CreatedAt: publication.CreatedAt, ID: publication.ID}
+}
+
 // Prepare will prepate the publication
 func (publication *Publication) Prepare() error {
 	if erro := publication.validate(); erro != nil {
@@ -47,11 +52,11 @@ func (publication *Publication) Prepare() error {
 
 func (publication *Publication) validate() error {
 	if publication.Title == "" {
-		return errors.New("the title cant be empty")
+		return Invalid(CodePublicationInvalid, "title", "the title cant be empty")
 	}
 
 	if publication.Content == "" {
-		return errors.New("the content cant be empty")
+		return Invalid(CodePublicationInvalid, "content", "the content cant be empty")
 	}
 
 	return nil
@@ -17,8 +17,8 @@ limitations under the License.
 package models
 
 import (
+	"api/src/pagination"
 	"api/src/security"
-	"errors"
 	"strings"
 	"time"
 
@@ -27,12 +27,18 @@ import (
 
 // User represents an Social media User
 type User struct {
-	ID        uint64    `json:"id,omitempty"`
-	Name      string    `json:"name,omitempty"`
-	Nick      string    `json:"nick,omitempty"`
-	Email     string    `json:"email,omitempty"`
-	Pass      string    `json:"pass,omitempty"`
+	ID        uint64    `json:"id,omitempty" example:"1"`
+	Name      string    `json:"name,omitempty" example:"User Foo Bar"`
+	Nick      string    `json:"nick,omitempty" example:"usr1"`
+	Email     string    `json:"email,omitempty" example:"user1@gmail.com"`
+	Pass      string    `json:"pass,omitempty" example:"usr!@#$$#@!"`
 	CreatedAt time.Time `json:"createdat,omitempty"`
+	Disabled  bool      `json:"-"`
+}
+
+// Key returns the position of the user in the lists
+func (user User) Key() pagination.Key {
+	return pagination.Key{CreatedAt: user.CreatedAt, ID: user.ID}
 }
 
 // Prepare will validate the User Struct.
@@ -50,23 +56,23 @@ func (user *User) Prepare(stage string) error {
 
 func (user *User) test(stage string) error {
 	if user.Name == "" {
-		return errors.New("the identity name cant be empty")
+		return Invalid(CodeUserInvalid, "name", "the identity name cant be empty")
 	}
 
 	if user.Nick == "" {
-		return errors.New("the identity nick cant be empty")
+		return Invalid(CodeUserInvalid, "nick", "the identity nick cant be empty")
 	}
 
 	if user.Email == "" {
-		return errors.New("the identity email cant be empty")
+		return Invalid(CodeUserInvalid, "email", "the identity email cant be empty")
 	}
 
 	if erro := checkmail.ValidateFormat(user.Email); erro != nil {
-		return errors.New("the identity email is invalid")
+		return Invalid(CodeUserInvalid, "email", "the identity email is invalid")
 	}
 
 	if stage == "registration" && user.Pass == "" {
-		return errors.New("the identity pass cant be empty")
+		return Invalid(CodeUserInvalid, "pass", "the identity pass cant be empty")
 	}
 
 	return nil
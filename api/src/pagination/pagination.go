@@ -0,0 +1,211 @@
+/*
+Copyright 2022 Danilo S. Lopes.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at:
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package pagination
+
+import (
+	"api/src/config"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+var (
+	// ErrInvalidLimit is returned for a limit that is not a positive integer
+	ErrInvalidLimit = errors.New("the limit must be a positive integer")
+
+	// ErrInvalidCursor is returned for a cursor that was altered or issued for another list
+	ErrInvalidCursor = errors.New("the cursor is invalid")
+)
+
+// Key is the position of an item in the lists, sorted by creation time then ID, newest first
+type Key struct {
+	CreatedAt time.Time
+	ID        uint64
+}
+
+// Older tells whether key comes after other in the lists
+func (key Key) Older(other Key) bool {
+	if !key.CreatedAt.Equal(other.CreatedAt) {
+		return key.CreatedAt.Before(other.CreatedAt)
+	}
+
+	return key.ID < other.ID
+}
+
+// Item is implemented by the models listed in pages
+type Item interface {
+	Key() Key
+}
+
+// Page selects the items of one page: the Limit items following Key, or preceding it when Backward.
+// The first page has no Key.
+type Page struct {
+	Limit    int
+	Key      *Key
+	Backward bool
+}
+
+// Fetch is the number of items the repositories return for the page, the one past Limit telling another page follows
+func (page Page) Fetch() int {
+	return page.Limit + 1
+}
+
+// Slice turns the items fetched for page, in the order the repositories return them, into the items of the
+// page, newest first, and the pages preceding and following it, nil when there is none
+func Slice[T Item](page Page, items []T) ([]T, *Page, *Page) {
+	more := len(items) > page.Limit
+	if more {
+		items = items[:page.Limit]
+	}
+
+	if page.Backward {
+		for left, right := 0, len(items)-1; left < right; left, right = left+1, right-1 {
+			items[left], items[right] = items[right], items[left]
+		}
+	}
+
+	if len(items) == 0 {
+		return items, nil, nil
+	}
+
+	var previous, next *Page
+	first, last := items[0].Key(), items[len(items)-1].Key()
+	if page.Backward && more || !page.Backward && page.Key != nil {
+		previous = &Page{Limit: page.Limit, Key: &first, Backward: true}
+	}
+	if !page.Backward && more || page.Backward {
+		next = &Page{Limit: page.Limit, Key: &last}
+	}
+
+	return items, previous, next
+}
+
+// Paginator reads the pages the clients ask for and links the pages around the ones they get.
+// The cursors are signed, so the clients can neither forge one nor use it on another list.
+type Paginator struct {
+	secret       []byte
+	defaultLimit int
+	maxLimit     int
+}
+
+// New creates a Paginator signing its cursors with a key derived from secret
+func New(secret []byte, settings config.Pagination) *Paginator {
+	mac := hmac.New(sha256.New, secret)
+	mac.Write([]byte("pagination cursors"))
+
+	return &Paginator{mac.Sum(nil), settings.DefaultLimit, settings.MaxLimit}
+}
+
+// cursor is the content of a signed cursor
+type cursor struct {
+	CreatedAt int64  `json:"t"`
+	ID        uint64 `json:"i"`
+	Backward  bool   `json:"b,omitempty"`
+}
+
+// Parse returns the page asked by the limit and cursor query parameters of r
+func (paginator *Paginator) Parse(r *http.Request) (Page, error) {
+	page := Page{Limit: paginator.defaultLimit}
+
+	query := r.URL.Query()
+	if limit := query.Get("limit"); limit != "" {
+		value, erro := strconv.Atoi(limit)
+		if erro != nil || value <= 0 {
+			return Page{}, ErrInvalidLimit
+		}
+		page.Limit = min(value, paginator.maxLimit)
+	}
+
+	token := query.Get("cursor")
+	if token == "" {
+		return page, nil
+	}
+
+	encoded, signature, found := strings.Cut(token, ".")
+	if !found {
+		return Page{}, ErrInvalidCursor
+	}
+
+	given, erro := base64.RawURLEncoding.DecodeString(signature)
+	if erro != nil || !hmac.Equal(given, paginator.sign(r.URL.Path, encoded)) {
+		return Page{}, ErrInvalidCursor
+	}
+
+	content, erro := base64.RawURLEncoding.DecodeString(encoded)
+	if erro != nil {
+		return Page{}, ErrInvalidCursor
+	}
+
+	var position cursor
+	if erro := json.Unmarshal(content, &position); erro != nil {
+		return Page{}, ErrInvalidCursor
+	}
+
+	page.Key = &Key{CreatedAt: time.Unix(0, position.CreatedAt).UTC(), ID: position.ID}
+	page.Backward = position.Backward
+
+	return page, nil
+}
+
+// Link adds to the Link header of w the URLs of the pages around the one r asked for, if any, and
+// returns them, "" for a missing page
+func (paginator *Paginator) Link(w http.ResponseWriter, r *http.Request, previous, next *Page) (string, string) {
+	var previousURL, nextURL string
+	var links []string
+	if previous != nil {
+		previousURL = paginator.url(r, *previous)
+		links = append(links, fmt.Sprintf(`<%s>; rel="prev"`, previousURL))
+	}
+	if next != nil {
+		nextURL = paginator.url(r, *next)
+		links = append(links, fmt.Sprintf(`<%s>; rel="next"`, nextURL))
+	}
+
+	if len(links) > 0 {
+		w.Header().Add("Link", strings.Join(links, ", "))
+	}
+
+	return previousURL, nextURL
+}
+
+// url returns the path and query of r asking for page
+func (paginator *Paginator) url(r *http.Request, page Page) string {
+	content, _ := json.Marshal(cursor{CreatedAt: page.Key.CreatedAt.UnixNano(), ID: page.Key.ID, Backward: page.Backward})
+	encoded := base64.RawURLEncoding.EncodeToString(content)
+
+	query := r.URL.Query()
+	query.Set("limit", strconv.Itoa(page.Limit))
+	query.Set("cursor", encoded+"."+base64.RawURLEncoding.EncodeToString(paginator.sign(r.URL.Path, encoded)))
+
+	return (&url.URL{Path: r.URL.Path, RawQuery: query.Encode()}).String()
+}
+
+// sign returns the signature of a cursor issued for the list at path
+func (paginator *Paginator) sign(path, encoded string) []byte {
+	mac := hmac.New(sha256.New, paginator.secret)
+	mac.Write([]byte(path + "\n" + encoded))
+
+	return mac.Sum(nil)[:16]
+}
@@ -16,128 +16,232 @@ limitations under the License.
 
 package prommetrics
 
-import "github.com/prometheus/client_golang/prometheus"
-
-// Prometheus Metrics Instantiation
-var (
-	PromOpenedConnections = prometheus.NewGauge(
-		prometheus.GaugeOpts{
-			Name: "sm_open_connections",
-			Help: "The current number of open connections",
-		},
-	)
-
-	PromHandlerDuration = prometheus.NewHistogramVec(
-		prometheus.HistogramOpts{
-			Name: "sm_handlers_duration_seconds",
-			Help: "Handlers request duration in seconds",
-		}, []string{"path"},
-	)
-
-	PromRequestsDuration = prometheus.NewHistogram(
-		prometheus.HistogramOpts{
-			Name: "sm_request_duration_seconds",
-			Help: "The duration of the requests to the sm service",
-		},
-	)
-
-	PromRequestsCurrent = prometheus.NewGauge(
-		prometheus.GaugeOpts{
-			Name: "sm_requests_current",
-			Help: "The current number of requests to the sm service",
-		},
-	)
-
-	PromRequestStatus = prometheus.NewCounterVec(
-		prometheus.CounterOpts{
-			Name: "sm_requests_total",
-			Help: "The total number of requests to the sm service by status",
-		}, []string{"status"},
-	)
-
-	PromClientErrors = prometheus.NewCounter(
-		prometheus.CounterOpts{
-			Name: "sm_errors",
-			Help: "The total number of sm client errors",
-		},
-	)
-
-	PromTimeTookToCreateUser = prometheus.NewHistogramVec(
-		prometheus.HistogramOpts{
-			Name:    "sm_time_to_create_user",
-			Help:    "Time took to create a new user",
-			Buckets: []float64{1, 2, 5, 6, 10},
-		}, []string{"status"},
-	)
-
-	PromTimeTookToDeleteUser = prometheus.NewHistogramVec(
-		prometheus.HistogramOpts{
-			Name:    "sm_time_to_delete_user",
-			Help:    "Time took to delete a new user",
-			Buckets: []float64{1, 2, 5, 6, 10},
-		}, []string{"status"},
-	)
+import (
+	"database/sql"
 
-	PromCountCreatedUsers = prometheus.NewCounter(
-		prometheus.CounterOpts{
-			Name: "sm_created_users_total",
-			Help: "Quantity of users created",
-		},
-	)
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+)
 
-	PromCountDeletedUsers = prometheus.NewCounter(
-		prometheus.CounterOpts{
-			Name: "sm_deleted_users_total",
-			Help: "Quantity of users deleted",
-		},
-	)
+// Metrics holds the Prometheus collectors of one API instance
+type Metrics struct {
+	OpenedConnections           prometheus.Gauge
+	HandlerDuration             *prometheus.HistogramVec
+	RequestsDuration            prometheus.Histogram
+	RequestsCurrent             prometheus.Gauge
+	RequestStatus               *prometheus.CounterVec
+	ClientErrors                prometheus.Counter
+	TimeTookToCreateUser        *prometheus.HistogramVec
+	TimeTookToDeleteUser        *prometheus.HistogramVec
+	CountCreatedUsers           prometheus.Counter
+	CountDeletedUsers           prometheus.Counter
+	TimeTookToCreatePublication *prometheus.HistogramVec
+	TimeTookToDeletePublication *prometheus.HistogramVec
+	CountNewPublication         prometheus.Counter
+	CountDeletePublication      prometheus.Counter
+	CanceledRequests            *prometheus.CounterVec
+	Panics                      *prometheus.CounterVec
+	RateLimited                 *prometheus.CounterVec
+	LegacyRequests              *prometheus.CounterVec
+	IdempotentRequests          *prometheus.CounterVec
+	CertificateExpiry           *prometheus.GaugeVec
+	CertificateReloads          *prometheus.CounterVec
+}
 
-	PromTimeTookToCreatePublication = prometheus.NewHistogramVec(
-		prometheus.HistogramOpts{
-			Name:    "sm_time_to_create_publication",
-			Help:    "Time took to create a new publication",
-			Buckets: []float64{1, 2, 5, 6, 10},
-		}, []string{"status"},
+// New instantiates the API collectors and register them into the given registry
+func New(registry prometheus.Registerer) *Metrics {
+	metrics := &Metrics{
+		OpenedConnections: prometheus.NewGauge(
+			prometheus.GaugeOpts{
+				Name: "sm_open_connections",
+				Help: "The current number of open connections",
+			},
+		),
+
+		HandlerDuration: prometheus.NewHistogramVec(
+			prometheus.HistogramOpts{
+				Name: "sm_handlers_duration_seconds",
+				Help: "Handlers request duration in seconds",
+			}, []string{"path"},
+		),
+
+		RequestsDuration: prometheus.NewHistogram(
+			prometheus.HistogramOpts{
+				Name: "sm_request_duration_seconds",
+				Help: "The duration of the requests to the sm service",
+			},
+		),
+
+		RequestsCurrent: prometheus.NewGauge(
+			prometheus.GaugeOpts{
+				Name: "sm_requests_current",
+				Help: "The current number of requests to the sm service",
+			},
+		),
+
+		RequestStatus: prometheus.NewCounterVec(
+			prometheus.CounterOpts{
+				Name: "sm_requests_total",
+				Help: "The total number of requests to the sm service by status",
+			}, []string{"status"},
+		),
+
+		ClientErrors: prometheus.NewCounter(
+			prometheus.CounterOpts{
+				Name: "sm_errors",
+				Help: "The total number of sm client errors",
+			},
+		),
+
+		TimeTookToCreateUser: prometheus.NewHistogramVec(
+			prometheus.HistogramOpts{
+				Name:    "sm_time_to_create_user",
+				Help:    "Time took to create a new user",
+				Buckets: []float64{1, 2, 5, 6, 10},
+			}, []string{"status"},
+		),
+
+		TimeTookToDeleteUser: prometheus.NewHistogramVec(
+			prometheus.HistogramOpts{
+				Name:    "sm_time_to_delete_user",
+				Help:    "Time took to delete a new user",
+				Buckets: []float64{1, 2, 5, 6, 10},
+			}, []string{"status"},
+		),
+
+		CountCreatedUsers: prometheus.NewCounter(
+			prometheus.CounterOpts{
+				Name: "sm_created_users_total",
+				Help: "Quantity of users created",
+			},
+		),
+
+		CountDeletedUsers: prometheus.NewCounter(
+			prometheus.CounterOpts{
+				Name: "sm_deleted_users_total",
+				Help: "Quantity of users deleted",
+			},
+		),
+
+		TimeTookToCreatePublication: prometheus.NewHistogramVec(
+			prometheus.HistogramOpts{
+				Name:    "sm_time_to_create_publication",
+				Help:    "Time took to create a new publication",
+				Buckets: []float64{1, 2, 5, 6, 10},
+			}, []string{"status"},
+		),
+
+		TimeTookToDeletePublication: prometheus.NewHistogramVec(
+			prometheus.HistogramOpts{
+				Name:    "sm_time_to_delete_publication",
+				Help:    "Time took to delete a publication",
+				Buckets: []float64{1, 2, 5, 6, 10},
+			}, []string{"status"},
+		),
+
+		CountNewPublication: prometheus.NewCounter(
+			prometheus.CounterOpts{
+				Name: "sm_created_publications_total",
+				Help: "Quantity of publications created",
+			},
+		),
+
+		CountDeletePublication: prometheus.NewCounter(
+			prometheus.CounterOpts{
+				Name: "sm_deleted_publications_total",
+				Help: "Quantity of publications deleted",
+			},
+		),
+
+		CanceledRequests: prometheus.NewCounterVec(
+			prometheus.CounterOpts{
+				Name: "sm_canceled_requests_total",
+				Help: "Requests whose queries were aborted, by route and reason (canceled by the client or deadline_exceeded)",
+			}, []string{"path", "reason"},
+		),
+
+		Panics: prometheus.NewCounterVec(
+			prometheus.CounterOpts{
+				Name: "sm_panics_total",
+				Help: "Panics recovered while serving a request, answered with a 500, by route",
+			}, []string{"path"},
+		),
+
+		RateLimited: prometheus.NewCounterVec(
+			prometheus.CounterOpts{
+				Name: "sm_rate_limited_requests_total",
+				Help: "Requests refused with a 429 by the rate limiting, by route and client kind (ip or user)",
+			}, []string{"path", "client"},
+		),
+
+		LegacyRequests: prometheus.NewCounterVec(
+			prometheus.CounterOpts{
+				Name: "sm_legacy_requests_total",
+				Help: "Requests sent to the deprecated unversioned routes, by route",
+			}, []string{"path"},
+		),
+
+		IdempotentRequests: prometheus.NewCounterVec(
+			prometheus.CounterOpts{
+				Name: "sm_idempotent_requests_total",
+				Help: "Requests sent with an Idempotency-Key, by route and outcome: stored, replayed, reused or in_progress",
+			}, []string{"path", "outcome"},
+		),
+
+		CertificateExpiry: prometheus.NewGaugeVec(
+			prometheus.GaugeOpts{
+				Name: "sm_tls_certificate_expiry_timestamp_seconds",
+				Help: "When the certificate served (server) or the first of the client CAs (client_ca) expires, in Unix time",
+			}, []string{"certificate"},
+		),
+
+		CertificateReloads: prometheus.NewCounterVec(
+			prometheus.CounterOpts{
+				Name: "sm_tls_certificate_reloads_total",
+				Help: "Reloads of the TLS certificates, by result (success or failure)",
+			}, []string{"result"},
+		),
+	}
+
+	registry.MustRegister(
+		metrics.OpenedConnections,
+		metrics.HandlerDuration,
+		metrics.RequestsDuration,
+		metrics.RequestsCurrent,
+		metrics.RequestStatus,
+		metrics.ClientErrors,
+		metrics.TimeTookToCreateUser,
+		metrics.TimeTookToDeleteUser,
+		metrics.CountCreatedUsers,
+		metrics.CountDeletedUsers,
+		metrics.TimeTookToCreatePublication,
+		metrics.TimeTookToDeletePublication,
+		metrics.CountNewPublication,
+		metrics.CountDeletePublication,
+		metrics.CanceledRequests,
+		metrics.Panics,
+		metrics.RateLimited,
+		metrics.LegacyRequests,
+		metrics.IdempotentRequests,
+		metrics.CertificateExpiry,
+		metrics.CertificateReloads,
 	)
 
-	PromTimeTookToDeletePublication = prometheus.NewHistogramVec(
-		prometheus.HistogramOpts{
-			Name:    "sm_time_to_delete_publication",
-			Help:    "Time took to delete a publication",
-			Buckets: []float64{1, 2, 5, 6, 10},
-		}, []string{"status"},
-	)
+	return metrics
+}
 
-	PromCountNewPublication = prometheus.NewCounter(
-		prometheus.CounterOpts{
-			Name: "sm_created_publications_total",
-			Help: "Quantity of publications created",
-		},
+// NewRegistry creates a registry with the default Go runtime and process collectors
+func NewRegistry() *prometheus.Registry {
+	registry := prometheus.NewRegistry()
+	registry.MustRegister(
+		collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
 	)
 
-	PromCountDeletePublication = prometheus.NewCounter(
-		prometheus.CounterOpts{
-			Name: "sm_deleted_publications_total",
-			Help: "Quantity of publications deleted",
-		},
-	)
-)
+	return registry
+}
 
-var Metrics []prometheus.Collector
-
-func Load() {
-	Metrics = append(Metrics, PromOpenedConnections)
-	Metrics = append(Metrics, PromHandlerDuration)
-	Metrics = append(Metrics, PromRequestsDuration)
-	Metrics = append(Metrics, PromRequestsCurrent)
-	Metrics = append(Metrics, PromRequestStatus)
-	Metrics = append(Metrics, PromClientErrors)
-	Metrics = append(Metrics, PromTimeTookToCreateUser)
-	Metrics = append(Metrics, PromTimeTookToDeleteUser)
-	Metrics = append(Metrics, PromCountCreatedUsers)
-	Metrics = append(Metrics, PromCountDeletedUsers)
-	Metrics = append(Metrics, PromTimeTookToCreatePublication)
-	Metrics = append(Metrics, PromTimeTookToDeletePublication)
-	Metrics = append(Metrics, PromCountNewPublication)
-	Metrics = append(Metrics, PromCountDeletePublication)
+// RegisterDatabase exports the connection pool statistics (sql.DBStats) of the given database
+func RegisterDatabase(registry prometheus.Registerer, db *sql.DB) {
+	registry.MustRegister(collectors.NewDBStatsCollector(db, "sm"))
 }
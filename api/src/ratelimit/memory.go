@@ -0,0 +1,104 @@
+/*
+Copyright 2022 Danilo S. Lopes.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at:
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package ratelimit
+
+import (
+	"api/src/config"
+	"context"
+	"math"
+	"sync"
+	"time"
+)
+
+// How often the buckets that refilled completely are dropped
+const sweepInterval = time.Minute
+
+// MemoryStore keeps the buckets in process memory, so each API instance limits the clients on its own
+type MemoryStore struct {
+	mu      sync.Mutex
+	buckets map[string]*bucket
+	swept   time.Time
+	now     func() time.Time
+}
+
+type bucket struct {
+	tokens  float64
+	updated time.Time
+
+	// when the bucket is full again, after which it is the same as a missing one
+	full time.Time
+}
+
+// NewMemoryStore creates an empty in-memory store
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{
+		buckets: make(map[string]*bucket),
+		swept:   time.Now(),
+		now:     time.Now,
+	}
+}
+
+// Take counts one request of the client key against its bucket
+func (store *MemoryStore) Take(ctx context.Context, key string, policy config.RatePolicy) (Decision, error) {
+	store.mu.Lock()
+	defer store.mu.Unlock()
+
+	now := store.now()
+	if now.Sub(store.swept) >= sweepInterval {
+		store.sweep(now)
+	}
+
+	perSecond := float64(policy.Limit) / policy.Window.Seconds()
+
+	current, exists := store.buckets[key]
+	if !exists {
+		current = &bucket{tokens: float64(policy.Limit), updated: now}
+		store.buckets[key] = current
+	}
+
+	current.tokens = math.Min(float64(policy.Limit), current.tokens+now.Sub(current.updated).Seconds()*perSecond)
+	current.updated = now
+
+	var decision Decision
+	if current.tokens >= 1 {
+		current.tokens--
+		decision.Allowed = true
+	} else {
+		decision.RetryAfter = seconds((1 - current.tokens) / perSecond)
+	}
+
+	decision.Remaining = int(current.tokens)
+	decision.Reset = seconds((float64(policy.Limit) - current.tokens) / perSecond)
+	current.full = now.Add(decision.Reset)
+
+	return decision, nil
+}
+
+// sweep drops the buckets that refilled completely
+func (store *MemoryStore) sweep(now time.Time) {
+	for key, current := range store.buckets {
+		if !current.full.After(now) {
+			delete(store.buckets, key)
+		}
+	}
+
+	store.swept = now
+}
+
+func seconds(value float64) time.Duration {
+	return time.Duration(value * float64(time.Second))
+}
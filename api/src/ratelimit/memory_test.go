@@ -0,0 +1,100 @@
+/*
+Copyright 2022 Danilo S. Lopes.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at:
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package ratelimit
+
+import (
+	"api/src/config"
+	"context"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+// The bucket lets a client burst up to the limit, then refills one token every window/limit
+func TestMemoryStoreRefill(t *testing.T) {
+	now := time.Unix(1700000000, 0)
+	store := NewMemoryStore()
+	store.now = func() time.Time { return now }
+	policy := config.RatePolicy{Limit: 2, Window: 10 * time.Second}
+
+	take := func() Decision {
+		t.Helper()
+
+		decision, erro := store.Take(context.Background(), "client", policy)
+		if erro != nil {
+			t.Fatal(erro)
+		}
+
+		return decision
+	}
+
+	for remaining := 1; remaining >= 0; remaining-- {
+		if decision := take(); !decision.Allowed || decision.Remaining != remaining {
+			t.Fatalf("got %+v, want allowed with %d tokens left", decision, remaining)
+		}
+	}
+
+	refused := take()
+	if refused.Allowed || refused.RetryAfter != 5*time.Second || refused.Reset != 10*time.Second {
+		t.Fatalf("the request over the limit got %+v, want refused, retrying after 5s and full after 10s", refused)
+	}
+
+	now = now.Add(5 * time.Second)
+	if decision := take(); !decision.Allowed || decision.Remaining != 0 {
+		t.Fatalf("once a token refilled got %+v, want allowed with no token left", decision)
+	}
+
+	now = now.Add(time.Hour)
+	if decision := take(); !decision.Allowed || decision.Remaining != 1 {
+		t.Fatalf("once the bucket refilled got %+v, want allowed with 1 token left, never over the limit", decision)
+	}
+
+	if decision, _ := store.Take(context.Background(), "other", policy); !decision.Allowed || decision.Remaining != 1 {
+		t.Fatalf("another client got %+v, want a bucket of its own", decision)
+	}
+}
+
+func TestClientIP(t *testing.T) {
+	tests := []struct {
+		name              string
+		remoteAddr        string
+		forwardedFor      string
+		trustForwardedFor bool
+		want              string
+	}{
+		{"connection address", "192.0.2.1:1234", "", false, "192.0.2.1"},
+		{"forwarded for ignored", "192.0.2.1:1234", "198.51.100.7", false, "192.0.2.1"},
+		{"forwarded for trusted", "192.0.2.1:1234", "198.51.100.7, 192.0.2.1", true, "198.51.100.7"},
+		{"invalid forwarded for", "192.0.2.1:1234", "unknown", true, "192.0.2.1"},
+		{"missing forwarded for", "[2001:db8::1]:1234", "", true, "2001:db8::1"},
+		{"address without port", "192.0.2.1", "", false, "192.0.2.1"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			request := httptest.NewRequest("GET", "/", nil)
+			request.RemoteAddr = test.remoteAddr
+			if test.forwardedFor != "" {
+				request.Header.Set("X-Forwarded-For", test.forwardedFor)
+			}
+
+			if got := ClientIP(request, test.trustForwardedFor); got != test.want {
+				t.Fatalf("got %q, want %q", got, test.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,166 @@
+/*
+Copyright 2022 Danilo S. Lopes.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at:
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package ratelimit_test
+
+import (
+	"api/src/authentication"
+	"api/src/config"
+	"api/src/middlewares"
+	"api/src/models"
+	"api/src/prommetrics"
+	"api/src/ratelimit"
+	"api/src/repositories"
+	"context"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+)
+
+var secretKey = []byte("secret")
+
+// limited wraps a handler answering 204 in the RateLimit middleware
+func limited(store ratelimit.Store, metrics *prommetrics.Metrics, settings config.RateLimit, method, uri string, authenticated bool) http.HandlerFunc {
+	scope, policy := settings.PolicyFor(method, uri, authenticated)
+
+	return middlewares.RateLimit(store, settings, nil, slog.New(slog.NewTextHandler(io.Discard, nil)), metrics, uri, scope, policy,
+		func(w http.ResponseWriter, r *http.Request) { w.WriteHeader(http.StatusNoContent) },
+	)
+}
+
+// call sends a request from remoteAddr, along token when not empty
+func call(handler http.Handler, method, remoteAddr, token string) *httptest.ResponseRecorder {
+	request := httptest.NewRequest(method, "/", nil)
+	request.RemoteAddr = remoteAddr
+	if token != "" {
+		request.Header.Set("Authorization", "Bearer "+token)
+	}
+
+	response := httptest.NewRecorder()
+	handler.ServeHTTP(response, request)
+
+	return response
+}
+
+// The client over its quota gets a 429 with Retry-After, every response telling the quota
+func TestRateLimit(t *testing.T) {
+	metrics := prommetrics.New(prommetrics.NewRegistry())
+	settings := config.RateLimit{Anonymous: config.RatePolicy{Limit: 2, Window: time.Minute}}
+	handler := limited(ratelimit.NewMemoryStore(), metrics, settings, http.MethodGet, "/users", false)
+
+	for _, remaining := range []string{"1", "0"} {
+		response := call(handler, http.MethodGet, "192.0.2.1:1234", "")
+		if response.Code != http.StatusNoContent {
+			t.Fatalf("got %d, want 204", response.Code)
+		}
+
+		header := response.Header()
+		if header.Get("RateLimit-Limit") != "2" || header.Get("RateLimit-Remaining") != remaining || header.Get("RateLimit-Policy") != "2;w=60" {
+			t.Fatalf("got the headers %v, want a limit of 2 per 60s with %s left", header, remaining)
+		}
+	}
+
+	refused := call(handler, http.MethodGet, "192.0.2.1:1234", "")
+	if refused.Code != http.StatusTooManyRequests || refused.Header().Get("Content-Type") != "application/problem+json" {
+		t.Fatalf("the request over the limit got %d %q, want a 429 problem", refused.Code, refused.Header().Get("Content-Type"))
+	}
+	if refused.Header().Get("Retry-After") != "30" || refused.Header().Get("RateLimit-Reset") != "60" {
+		t.Fatalf("got Retry-After %q and RateLimit-Reset %q, want 30 and 60",
+			refused.Header().Get("Retry-After"), refused.Header().Get("RateLimit-Reset"))
+	}
+	if got := testutil.ToFloat64(metrics.RateLimited.WithLabelValues("/users", "ip")); got != 1 {
+		t.Fatalf("counted %v refused requests, want 1", got)
+	}
+
+	if other := call(handler, http.MethodGet, "192.0.2.2:1234", ""); other.Code != http.StatusNoContent {
+		t.Fatalf("another IP got %d, want 204", other.Code)
+	}
+}
+
+// A route with a policy of its own has its own buckets, the others sharing those of their scope
+func TestRateLimitRoutePolicies(t *testing.T) {
+	metrics := prommetrics.New(prommetrics.NewRegistry())
+	store := ratelimit.NewMemoryStore()
+	settings := config.RateLimit{
+		Anonymous: config.RatePolicy{Limit: 1, Window: time.Minute},
+		Routes:    map[string]config.RatePolicy{"POST /login": {Limit: 3, Window: time.Minute}},
+	}
+
+	users := limited(store, metrics, settings, http.MethodGet, "/users", false)
+	publications := limited(store, metrics, settings, http.MethodGet, "/publications", false)
+	login := limited(store, metrics, settings, http.MethodPost, "/login", false)
+
+	if response := call(users, http.MethodGet, "192.0.2.1:1234", ""); response.Code != http.StatusNoContent {
+		t.Fatalf("got %d, want 204", response.Code)
+	}
+	if response := call(publications, http.MethodGet, "192.0.2.1:1234", ""); response.Code != http.StatusTooManyRequests {
+		t.Fatalf("a route of the same scope got %d, want 429 as the bucket is shared", response.Code)
+	}
+
+	response := call(login, http.MethodPost, "192.0.2.1:1234", "")
+	if response.Code != http.StatusNoContent || response.Header().Get("RateLimit-Limit") != "3" {
+		t.Fatalf("the route with its own policy got %d limited to %q, want 204 limited to 3",
+			response.Code, response.Header().Get("RateLimit-Limit"))
+	}
+}
+
+// Running before Authenticate, the limit throttles the requests with a bad token by IP and tells the users
+// apart by the user ID of their token
+func TestRateLimitBeforeAuthentication(t *testing.T) {
+	metrics := prommetrics.New(prommetrics.NewRegistry())
+	users := repositories.NewMemoryStore().Users
+	for _, nick := range []string{"first", "second"} {
+		if _, erro := users.Create(context.Background(), models.User{Name: nick, Nick: nick, Email: nick + "@example.com", Pass: "secret"}); erro != nil {
+			t.Fatal(erro)
+		}
+	}
+
+	settings := config.RateLimit{Authenticated: config.RatePolicy{Limit: 1, Window: time.Minute}}
+	scope, policy := settings.PolicyFor(http.MethodGet, "/users", true)
+	handler := middlewares.RateLimit(ratelimit.NewMemoryStore(), settings, secretKey, slog.New(slog.NewTextHandler(io.Discard, nil)),
+		metrics, "/users", scope, policy,
+		middlewares.Authenticate(secretKey, users, func(w http.ResponseWriter, r *http.Request) { w.WriteHeader(http.StatusNoContent) }),
+	)
+
+	if response := call(handler, http.MethodGet, "192.0.2.1:1234", "bad"); response.Code != http.StatusUnauthorized {
+		t.Fatalf("a bad token got %d, want 401", response.Code)
+	}
+	if response := call(handler, http.MethodGet, "192.0.2.1:1234", "bad"); response.Code != http.StatusTooManyRequests {
+		t.Fatalf("a bad token over the limit got %d, want 429", response.Code)
+	}
+	if got := testutil.ToFloat64(metrics.RateLimited.WithLabelValues("/users", "ip")); got != 1 {
+		t.Fatalf("counted %v requests refused by IP, want 1", got)
+	}
+
+	for _, userID := range []uint64{1, 2} {
+		token, erro := authentication.GenerateToken(secretKey, userID)
+		if erro != nil {
+			t.Fatal(erro)
+		}
+
+		if response := call(handler, http.MethodGet, "192.0.2.1:1234", token); response.Code != http.StatusNoContent {
+			t.Fatalf("the user %d got %d from the throttled IP, want 204 as the users have their own buckets", userID, response.Code)
+		}
+		if response := call(handler, http.MethodGet, "192.0.2.1:1234", token); response.Code != http.StatusTooManyRequests {
+			t.Fatalf("the user %d over the limit got %d, want 429", userID, response.Code)
+		}
+	}
+}
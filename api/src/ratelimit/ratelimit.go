@@ -0,0 +1,115 @@
+/*
+Copyright 2022 Danilo S. Lopes.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at:
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package ratelimit
+
+import (
+	"api/src/config"
+	"context"
+	"fmt"
+	"net"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Decision is the outcome of taking a token from the bucket of a client
+type Decision struct {
+	Allowed bool
+
+	// Tokens left in the bucket once the request was counted
+	Remaining int
+
+	// Time until the bucket is full again
+	Reset time.Duration
+
+	// Time until the next token when the request was refused
+	RetryAfter time.Duration
+}
+
+// Store keeps the token buckets of the clients. A store shared by several API instances (e.g. Redis)
+// only has to take the tokens atomically.
+type Store interface {
+	// Take counts one request of the client key against its bucket, refilled as policy says
+	Take(ctx context.Context, key string, policy config.RatePolicy) (Decision, error)
+}
+
+// StoreFactory creates the store selected by RATE_LIMIT_STORE
+type StoreFactory func(settings config.RateLimit) (Store, error)
+
+var (
+	storesMutex sync.RWMutex
+	stores      = map[string]StoreFactory{
+		"memory": func(settings config.RateLimit) (Store, error) {
+			return NewMemoryStore(), nil
+		},
+	}
+)
+
+// RegisterStore makes a store selectable by RATE_LIMIT_STORE, e.g. one shared by the API instances
+func RegisterStore(name string, factory StoreFactory) {
+	storesMutex.Lock()
+	defer storesMutex.Unlock()
+
+	stores[name] = factory
+}
+
+// Stores returns the names accepted by RATE_LIMIT_STORE
+func Stores() []string {
+	storesMutex.RLock()
+	defer storesMutex.RUnlock()
+
+	var names []string
+	for name := range stores {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	return names
+}
+
+// New creates the store configured by settings
+func New(settings config.RateLimit) (Store, error) {
+	storesMutex.RLock()
+	factory, exists := stores[settings.Store]
+	storesMutex.RUnlock()
+
+	if !exists {
+		return nil, fmt.Errorf("unknown rate limit store %q", settings.Store)
+	}
+
+	return factory(settings)
+}
+
+// ClientIP returns the address of the client of r. With trustForwardedFor the first address of the
+// X-Forwarded-For header is preferred, which only the proxy in front of the API should set.
+func ClientIP(r *http.Request, trustForwardedFor bool) string {
+	if trustForwardedFor {
+		first, _, _ := strings.Cut(r.Header.Get("X-Forwarded-For"), ",")
+		if ip := net.ParseIP(strings.TrimSpace(first)); ip != nil {
+			return ip.String()
+		}
+	}
+
+	host, _, erro := net.SplitHostPort(r.RemoteAddr)
+	if erro != nil {
+		return r.RemoteAddr
+	}
+
+	return host
+}
@@ -0,0 +1,69 @@
+/*
+Copyright 2022 Danilo S. Lopes.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at:
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package repositories
+
+import (
+	"api/src/database"
+	"api/src/models"
+	"database/sql"
+	"strings"
+)
+
+var (
+	errUserNotFound        = models.NotFound(models.CodeUserNotFound, "the user does not exist")
+	errPublicationNotFound = models.NotFound(models.CodePublicationNotFound, "the publication does not exist")
+	errNickTaken           = models.Conflict(models.CodeUserNickTaken, "the nick is already in use")
+	errEmailTaken          = models.Conflict(models.CodeUserEmailTaken, "the email is already in use")
+	errPublicationLiked    = models.Conflict(models.CodePublicationLiked, "the publication is already liked")
+)
+
+// userConflict translates the violation of the nick or email unique index into its domain error
+func userConflict(erro error) error {
+	index, duplicated := database.UniqueViolation(erro)
+	if !duplicated {
+		return erro
+	}
+
+	if strings.Contains(index, "email") {
+		return errEmailTaken
+	}
+
+	return errNickTaken
+}
+
+// notFound translates sql.ErrNoRows into notFoundErro
+func notFound(erro, notFoundErro error) error {
+	if erro == sql.ErrNoRows {
+		return notFoundErro
+	}
+
+	return erro
+}
+
+// affected returns notFoundErro when the statement changed no row
+func affected(result sql.Result, notFoundErro error) error {
+	rows, erro := result.RowsAffected()
+	if erro != nil {
+		return erro
+	}
+
+	if rows == 0 {
+		return notFoundErro
+	}
+
+	return nil
+}
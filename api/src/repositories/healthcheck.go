@@ -17,22 +17,30 @@ limitations under the License.
 package repositories
 
 import (
+	"api/src/database"
 	"context"
-	"database/sql"
 	"net"
 )
 
-type HealthcheckRepository struct {
-	db *sql.DB
+// HealthcheckRepository represents the checks performed against the storage
+type HealthcheckRepository interface {
+	PingDatabase(ctx context.Context) error
+	DNSResolver(ctx context.Context, address string) error
+	SimulateDatabaseInsert(ctx context.Context) error
 }
 
-func NewHealthcheckRepository(db *sql.DB) *HealthcheckRepository {
-	return &HealthcheckRepository{db}
+type healthcheckRepository struct {
+	db *database.DB
+}
+
+// NewHealthcheckRepository creates a Healthcheck repository backed by a SQL database
+func NewHealthcheckRepository(db *database.DB) HealthcheckRepository {
+	return &healthcheckRepository{db}
 }
 
 // PingDatabase check connectivity to database
-func (repository HealthcheckRepository) PingDatabase() error {
-	if erro := repository.db.Ping(); erro != nil {
+func (repository healthcheckRepository) PingDatabase(ctx context.Context) error {
+	if erro := repository.db.PingContext(ctx); erro != nil {
 		return erro
 	}
 
@@ -40,8 +48,8 @@ func (repository HealthcheckRepository) PingDatabase() error {
 }
 
 // DNSResolver check name resolution
-func (repository HealthcheckRepository) DNSResolver(address string) error {
-	if _, erro := net.LookupHost(address); erro != nil {
+func (repository healthcheckRepository) DNSResolver(ctx context.Context, address string) error {
+	if _, erro := net.DefaultResolver.LookupHost(ctx, address); erro != nil {
 		return erro
 	}
 
@@ -49,8 +57,7 @@ func (repository HealthcheckRepository) DNSResolver(address string) error {
 }
 
 // SimulateDatabaseInsert check if our API can insert data in database
-func (repository HealthcheckRepository) SimulateDatabaseInsert() error {
-	ctx := context.Background()
+func (repository healthcheckRepository) SimulateDatabaseInsert(ctx context.Context) error {
 	tx, erro := repository.db.BeginTx(ctx, nil)
 	if erro != nil {
 		return erro
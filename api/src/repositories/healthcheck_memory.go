@@ -0,0 +1,41 @@
+/*
+Copyright 2022 Danilo S. Lopes.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at:
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package repositories
+
+import "context"
+
+type memoryHealthcheckRepository struct{}
+
+// NewMemoryHealthcheckRepository creates an in-memory Healthcheck repository
+func NewMemoryHealthcheckRepository() HealthcheckRepository {
+	return &memoryHealthcheckRepository{}
+}
+
+// PingDatabase always succeed since there is no database to reach
+func (repository memoryHealthcheckRepository) PingDatabase(ctx context.Context) error {
+	return nil
+}
+
+// DNSResolver always succeed since there is no database host to resolve
+func (repository memoryHealthcheckRepository) DNSResolver(ctx context.Context, address string) error {
+	return nil
+}
+
+// SimulateDatabaseInsert always succeed since memory writes can not fail
+func (repository memoryHealthcheckRepository) SimulateDatabaseInsert(ctx context.Context) error {
+	return nil
+}
@@ -0,0 +1,124 @@
+/*
+Copyright 2022 Danilo S. Lopes.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at:
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package repositories
+
+import (
+	"api/src/database"
+	"api/src/models"
+	"context"
+	"database/sql"
+	"time"
+)
+
+// IdempotencyRepository keeps the responses of the requests sent with an Idempotency-Key
+type IdempotencyRepository interface {
+	// Reserve stores record, with no response yet, unless its user holds the key already: then it
+	// returns the stored record and false. A key whose record expired, in progress or not, is free again.
+	Reserve(ctx context.Context, record models.IdempotencyRecord) (models.IdempotencyRecord, bool, error)
+
+	// Complete stores the response and the expiry of the record, while its request still holds the key
+	Complete(ctx context.Context, record models.IdempotencyRecord) error
+
+	// Release drops the key, while the request of record still holds it
+	Release(ctx context.Context, record models.IdempotencyRecord) error
+	Purge(ctx context.Context) error
+}
+
+type idempotencyRepository struct {
+	db *database.DB
+}
+
+// NewIdempotencyRepository creates an Idempotency repository backed by a SQL database
+func NewIdempotencyRepository(db *database.DB) IdempotencyRepository {
+	return &idempotencyRepository{db}
+}
+
+// Reserve stores the record of a request sent with a key its user does not hold yet, the expired keys being free again
+func (repository idempotencyRepository) Reserve(ctx context.Context, record models.IdempotencyRecord) (models.IdempotencyRecord, bool, error) {
+	if _, erro := repository.db.ExecContext(ctx,
+		"DELETE FROM idempotency_keys WHERE user_id = ? AND idempotency_key = ? AND expires_at <= ?",
+		record.UserID, record.Key, repository.db.Dialect.Timestamp(time.Now()),
+	); erro != nil {
+		return models.IdempotencyRecord{}, false, erro
+	}
+
+	result, erro := repository.db.ExecContext(ctx,
+		repository.db.Dialect.InsertIgnore("idempotency_keys", "user_id, idempotency_key, fingerprint, expires_at", "?, ?, ?, ?"),
+		record.UserID, record.Key, record.Fingerprint, repository.db.Dialect.Timestamp(record.ExpiresAt),
+	)
+	if erro != nil {
+		return models.IdempotencyRecord{}, false, erro
+	}
+
+	inserted, erro := result.RowsAffected()
+	if erro != nil {
+		return models.IdempotencyRecord{}, false, erro
+	}
+	if inserted == 1 {
+		return record, true, nil
+	}
+
+	stored := models.IdempotencyRecord{UserID: record.UserID, Key: record.Key}
+	var body []byte
+	if erro := repository.db.QueryRowContext(ctx, `
+		SELECT fingerprint, status, content_type, body FROM idempotency_keys
+		WHERE user_id = ? AND idempotency_key = ?`,
+		record.UserID, record.Key,
+	).Scan(&stored.Fingerprint, &stored.Status, &stored.ContentType, &body); erro != nil {
+		if erro != sql.ErrNoRows {
+			return models.IdempotencyRecord{}, false, erro
+		}
+
+		// released by the request holding it meanwhile, still in progress for this one
+		stored.Fingerprint = record.Fingerprint
+	}
+	stored.Body = body
+
+	return stored, false, nil
+}
+
+// Complete stores the response of the request holding the key
+func (repository idempotencyRepository) Complete(ctx context.Context, record models.IdempotencyRecord) error {
+	_, erro := repository.db.ExecContext(ctx, `
+		UPDATE idempotency_keys SET status = ?, content_type = ?, body = ?, expires_at = ?
+		WHERE user_id = ? AND idempotency_key = ? AND fingerprint = ? AND status = 0`,
+		record.Status, record.ContentType, record.Body, repository.db.Dialect.Timestamp(record.ExpiresAt),
+		record.UserID, record.Key, record.Fingerprint,
+	)
+
+	return erro
+}
+
+// Release drops the key, so the request can be sent again with it
+func (repository idempotencyRepository) Release(ctx context.Context, record models.IdempotencyRecord) error {
+	_, erro := repository.db.ExecContext(ctx,
+		"DELETE FROM idempotency_keys WHERE user_id = ? AND idempotency_key = ? AND fingerprint = ?",
+		record.UserID, record.Key, record.Fingerprint,
+	)
+
+	return erro
+}
+
+// Purge drops the expired keys
+func (repository idempotencyRepository) Purge(ctx context.Context) error {
+	_, erro := repository.db.ExecContext(ctx,
+		"DELETE FROM idempotency_keys WHERE expires_at <= ?",
+		repository.db.Dialect.Timestamp(time.Now()),
+	)
+
+	return erro
+}
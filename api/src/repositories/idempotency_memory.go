@@ -0,0 +1,95 @@
+/*
+Copyright 2022 Danilo S. Lopes.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at:
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package repositories
+
+import (
+	"api/src/models"
+	"context"
+	"sync"
+	"time"
+)
+
+type idempotencyKey struct {
+	userID uint64
+	key    string
+}
+
+type memoryIdempotencyRepository struct {
+	mu      sync.Mutex
+	records map[idempotencyKey]models.IdempotencyRecord
+}
+
+// NewMemoryIdempotencyRepository creates an in-memory Idempotency repository
+func NewMemoryIdempotencyRepository() IdempotencyRepository {
+	return &memoryIdempotencyRepository{records: make(map[idempotencyKey]models.IdempotencyRecord)}
+}
+
+// Reserve stores the record of a request sent with a key its user does not hold yet, the expired keys being free again
+func (repository *memoryIdempotencyRepository) Reserve(ctx context.Context, record models.IdempotencyRecord) (models.IdempotencyRecord, bool, error) {
+	repository.mu.Lock()
+	defer repository.mu.Unlock()
+
+	if stored, exists := repository.records[idempotencyKey{record.UserID, record.Key}]; exists && stored.ExpiresAt.After(time.Now()) {
+		return stored, false, nil
+	}
+
+	repository.records[idempotencyKey{record.UserID, record.Key}] = record
+
+	return record, true, nil
+}
+
+// Complete stores the response of the request holding the key
+func (repository *memoryIdempotencyRepository) Complete(ctx context.Context, record models.IdempotencyRecord) error {
+	repository.mu.Lock()
+	defer repository.mu.Unlock()
+
+	stored, exists := repository.records[idempotencyKey{record.UserID, record.Key}]
+	if !exists || stored.Fingerprint != record.Fingerprint || stored.Status != 0 {
+		return nil
+	}
+
+	repository.records[idempotencyKey{record.UserID, record.Key}] = record
+
+	return nil
+}
+
+// Release drops the key, so the request can be sent again with it
+func (repository *memoryIdempotencyRepository) Release(ctx context.Context, record models.IdempotencyRecord) error {
+	repository.mu.Lock()
+	defer repository.mu.Unlock()
+
+	if stored, exists := repository.records[idempotencyKey{record.UserID, record.Key}]; exists && stored.Fingerprint == record.Fingerprint {
+		delete(repository.records, idempotencyKey{record.UserID, record.Key})
+	}
+
+	return nil
+}
+
+// Purge drops the expired keys
+func (repository *memoryIdempotencyRepository) Purge(ctx context.Context) error {
+	repository.mu.Lock()
+	defer repository.mu.Unlock()
+
+	now := time.Now()
+	for key, record := range repository.records {
+		if !record.ExpiresAt.After(now) {
+			delete(repository.records, key)
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,83 @@
+/*
+Copyright 2022 Danilo S. Lopes.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at:
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package repositories
+
+import (
+	"api/src/models"
+	"context"
+	"testing"
+	"time"
+)
+
+func TestIdempotency(t *testing.T) {
+	forEachEngine(t, func(t *testing.T, store Store) {
+		ctx := context.Background()
+		record := models.IdempotencyRecord{UserID: 1, Key: "key", Fingerprint: "first", ExpiresAt: time.Now().Add(time.Hour)}
+
+		if _, reserved, erro := store.Idempotency.Reserve(ctx, record); erro != nil || !reserved {
+			t.Fatalf("reserving a new key got %v, %v", reserved, erro)
+		}
+
+		retry := record
+		retry.Fingerprint = "second"
+		stored, reserved, erro := store.Idempotency.Reserve(ctx, retry)
+		if erro != nil || reserved || stored.Fingerprint != "first" || stored.Status != 0 {
+			t.Fatalf("reserving a held key got %+v, %v, %v, want the first request in progress", stored, reserved, erro)
+		}
+
+		if erro := store.Idempotency.Complete(ctx, retry); erro != nil {
+			t.Fatal(erro)
+		}
+		completed := record
+		completed.Status, completed.ContentType, completed.Body = 201, "application/json", []byte(`{"id":1}`)
+		if erro := store.Idempotency.Complete(ctx, completed); erro != nil {
+			t.Fatal(erro)
+		}
+		stored, reserved, erro = store.Idempotency.Reserve(ctx, record)
+		if erro != nil || reserved || stored.Status != 201 || stored.ContentType != "application/json" || string(stored.Body) != `{"id":1}` {
+			t.Fatalf("reserving a completed key got %+v, %v, %v, want its response", stored, reserved, erro)
+		}
+
+		other := record
+		other.UserID = 2
+		if _, reserved, erro := store.Idempotency.Reserve(ctx, other); erro != nil || !reserved {
+			t.Fatalf("reserving the key of another user got %v, %v", reserved, erro)
+		}
+
+		if erro := store.Idempotency.Release(ctx, record); erro != nil {
+			t.Fatal(erro)
+		}
+		if _, reserved, erro := store.Idempotency.Reserve(ctx, record); erro != nil || !reserved {
+			t.Fatalf("reserving a released key got %v, %v", reserved, erro)
+		}
+
+		// the request of a dead process holds the key until its lease expires
+		expired := models.IdempotencyRecord{UserID: 1, Key: "expired", Fingerprint: "first", ExpiresAt: time.Now().Add(-time.Minute)}
+		if _, reserved, erro := store.Idempotency.Reserve(ctx, expired); erro != nil || !reserved {
+			t.Fatalf("reserving a new key got %v, %v", reserved, erro)
+		}
+		takeover := expired
+		takeover.ExpiresAt = time.Now().Add(time.Hour)
+		if _, reserved, erro := store.Idempotency.Reserve(ctx, takeover); erro != nil || !reserved {
+			t.Fatalf("reserving a key whose lease expired got %v, %v", reserved, erro)
+		}
+
+		if erro := store.Idempotency.Purge(ctx); erro != nil {
+			t.Fatal(erro)
+		}
+	})
+}
@@ -0,0 +1,100 @@
+/*
+Copyright 2022 Danilo S. Lopes.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at:
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package repositories
+
+import (
+	"api/src/models"
+	"sort"
+	"sync"
+)
+
+type follow struct {
+	userID     uint64
+	followerID uint64
+}
+
+type like struct {
+	publicationID uint64
+	likerID       uint64
+}
+
+// memoryDB holds the tables shared by the in-memory repositories
+type memoryDB struct {
+	mu sync.RWMutex
+
+	lastUserID        uint64
+	lastPublicationID uint64
+
+	users        map[uint64]models.User
+	publications map[uint64]models.Publication
+	followers    map[follow]struct{}
+	likes        map[like]struct{}
+}
+
+func newMemoryDB() *memoryDB {
+	return &memoryDB{
+		users:        make(map[uint64]models.User),
+		publications: make(map[uint64]models.Publication),
+		followers:    make(map[follow]struct{}),
+		likes:        make(map[like]struct{}),
+	}
+}
+
+// publicUser strips the fields the SQL queries never return
+func publicUser(user models.User) models.User {
+	user.Pass = ""
+	return user
+}
+
+// sortedUserIDs returns the users ids in insertion order
+func (data *memoryDB) sortedUserIDs() []uint64 {
+	IDs := make([]uint64, 0, len(data.users))
+	for ID := range data.users {
+		IDs = append(IDs, ID)
+	}
+	sort.Slice(IDs, func(i, j int) bool { return IDs[i] < IDs[j] })
+
+	return IDs
+}
+
+// sortedPublicationIDs returns the publications ids in insertion order
+func (data *memoryDB) sortedPublicationIDs() []uint64 {
+	IDs := make([]uint64, 0, len(data.publications))
+	for ID := range data.publications {
+		IDs = append(IDs, ID)
+	}
+	sort.Slice(IDs, func(i, j int) bool { return IDs[i] < IDs[j] })
+
+	return IDs
+}
+
+// withAuthorNick fills the publication author nick like the SQL join does
+func (data *memoryDB) withAuthorNick(publication models.Publication) models.Publication {
+	publication.AuthorNick = data.users[publication.AuthorID].Nick
+	return publication
+}
+
+// deletePublication removes a publication and the likes it received
+func (data *memoryDB) deletePublication(publicationID uint64) {
+	delete(data.publications, publicationID)
+
+	for relation := range data.likes {
+		if relation.publicationID == publicationID {
+			delete(data.likes, relation)
+		}
+	}
+}
@@ -0,0 +1,70 @@
+/*
+Copyright 2022 Danilo S. Lopes.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at:
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package repositories
+
+import (
+	"api/src/database"
+	"api/src/pagination"
+	"fmt"
+	"sort"
+)
+
+// keyset returns the condition selecting the rows of page by the createdat and id columns of the
+// table aliased alias, to be the last one of the WHERE clause, and the ORDER BY and LIMIT clauses
+// returning them in the order pagination.Slice expects, followed by the arguments of both
+func keyset(dialect database.Dialect, page pagination.Page, alias string) (string, string, []interface{}) {
+	comparison, order := "<", "DESC"
+	if page.Backward {
+		comparison, order = ">", "ASC"
+	}
+
+	orderBy := fmt.Sprintf("ORDER BY %[1]s.createdat %[2]s, %[1]s.id %[2]s LIMIT ?", alias, order)
+
+	if page.Key == nil {
+		return "1 = 1", orderBy, []interface{}{page.Fetch()}
+	}
+
+	createdAt := dialect.Timestamp(page.Key.CreatedAt)
+	condition := fmt.Sprintf("(%[1]s.createdat %[2]s ? OR (%[1]s.createdat = ? AND %[1]s.id %[2]s ?))", alias, comparison)
+
+	return condition, orderBy, []interface{}{createdAt, createdAt, page.Key.ID, page.Fetch()}
+}
+
+// memoryPage returns the items of page out of items, in the order of the keyset queries
+func memoryPage[T pagination.Item](page pagination.Page, items []T) []T {
+	selected := []T{}
+	for _, item := range items {
+		if page.Key == nil ||
+			!page.Backward && item.Key().Older(*page.Key) ||
+			page.Backward && page.Key.Older(item.Key()) {
+			selected = append(selected, item)
+		}
+	}
+
+	sort.Slice(selected, func(i, j int) bool {
+		if page.Backward {
+			return selected[i].Key().Older(selected[j].Key())
+		}
+		return selected[j].Key().Older(selected[i].Key())
+	})
+
+	if len(selected) > page.Fetch() {
+		selected = selected[:page.Fetch()]
+	}
+
+	return selected
+}
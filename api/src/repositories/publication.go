@@ -17,88 +17,92 @@ limitations under the License.
 package repositories
 
 import (
+	"api/src/database"
 	"api/src/models"
+	"api/src/pagination"
 	"context"
-	"database/sql"
 )
 
-type PublicationsRepository struct {
-	db *sql.DB
+// PublicationsRepository represents the publications storage operations
+type PublicationsRepository interface {
+	Create(ctx context.Context, publication models.Publication) (uint64, error)
+	SearchByID(ctx context.Context, publicationID uint64) (models.Publication, error)
+	Get(ctx context.Context, userID uint64, page pagination.Page) ([]models.Publication, error)
+	Update(ctx context.Context, publicationID uint64, publication models.Publication) error
+	Delete(ctx context.Context, publicationID uint64) error
+	GetByUser(ctx context.Context, userID uint64, page pagination.Page) ([]models.Publication, error)
+	LikePublication(ctx context.Context, publicationID, likerID uint64) error
+	UnLikePublication(ctx context.Context, publicationID, unLikerID uint64) error
+	GetLikers(ctx context.Context, publicationID uint64, page pagination.Page) ([]models.User, error)
 }
 
-// NewPublicationRepository creates a Publication repository
-func NewPublicationRepository(db *sql.DB) *PublicationsRepository {
-	return &PublicationsRepository{db}
+type publicationsRepository struct {
+	db *database.DB
+}
+
+// NewPublicationRepository creates a Publication repository backed by a SQL database
+func NewPublicationRepository(db *database.DB) PublicationsRepository {
+	return &publicationsRepository{db}
 }
 
 // Create create post in database
-func (repository PublicationsRepository) Create(post models.Publication) (uint64, error) {
-	statement, erro := repository.db.Prepare(
+func (repository publicationsRepository) Create(ctx context.Context, post models.Publication) (uint64, error) {
+	ID, erro := repository.db.InsertReturningID(ctx,
 		"INSERT INTO publications (title, content, author_id) VALUES (?, ?, ?)",
+		post.Title, post.Content, post.AuthorID,
 	)
 	if erro != nil {
-		return 0, erro
-	}
-	defer statement.Close()
-
-	result, erro := statement.Exec(post.Title, post.Content, post.AuthorID)
-	if erro != nil {
-		return 0, erro
-	}
-
-	lastInsertedID, erro := result.LastInsertId()
-	if erro != nil {
+		if database.ForeignKeyViolation(erro) {
+			return 0, errUserNotFound
+		}
 		return 0, erro
 	}
 
-	return uint64(lastInsertedID), nil
+	return ID, nil
 }
 
 // SearchByID return one publication in database
-func (repository PublicationsRepository) SearchByID(publicationID uint64) (models.Publication, error) {
-	line, erro := repository.db.Query(`
+func (repository publicationsRepository) SearchByID(ctx context.Context, publicationID uint64) (models.Publication, error) {
+	var publication models.Publication
+
+	if erro := repository.db.QueryRowContext(ctx, `
 		SELECT p.*, u.nick FROM publications p JOIN users u
 		ON u.id = p.author_id WHERE p.id = ?`,
 		publicationID,
-	)
-	if erro != nil {
-		return models.Publication{}, erro
-	}
-	defer line.Close()
-
-	var publication models.Publication
-
-	if line.Next() {
-		if erro := line.Scan(
-			&publication.ID,
-			&publication.Title,
-			&publication.Content,
-			&publication.AuthorID,
-			&publication.Likes,
-			&publication.CreatedAt,
-			&publication.AuthorNick,
-		); erro != nil {
-			return models.Publication{}, erro
-		}
+	).Scan(
+		&publication.ID,
+		&publication.Title,
+		&publication.Content,
+		&publication.AuthorID,
+		&publication.Likes,
+		&publication.CreatedAt,
+		&publication.AuthorNick,
+	); erro != nil {
+		return models.Publication{}, notFound(erro, errPublicationNotFound)
 	}
 
 	return publication, nil
 }
 
-// Get return all publications related by an user(self publications and his friends publications)
-func (repository PublicationsRepository) Get(userID uint64) ([]models.Publication, error) {
-	lines, erro := repository.db.Query(`
-		SELECT DISTINCT p.*, u.nick from publications p
+// Get return one page of the publications related by an user(self publications and his friends publications)
+func (repository publicationsRepository) Get(ctx context.Context, userID uint64, page pagination.Page) ([]models.Publication, error) {
+	condition, orderBy, args := keyset(repository.db.Dialect, page, "p")
+
+	lines, erro := repository.db.QueryContext(ctx, `
+		SELECT p.*, u.nick from publications p
 		JOIN users u on u.id = p.author_id
-		JOIN followers f on p.author_id = f.user_id
-		WHERE u.id = ? OR f.follower_id = ?
-	`, userID, userID)
+		WHERE (p.author_id = ? OR EXISTS (
+			SELECT 1 FROM followers f WHERE f.user_id = p.author_id AND f.follower_id = ?
+		)) AND `+condition+`
+		`+orderBy,
+		append([]interface{}{userID, userID}, args...)...,
+	)
 	if erro != nil {
 		return nil, erro
 	}
 	defer lines.Close()
 
-	var publications []models.Publication
+	publications := []models.Publication{}
 
 	for lines.Next() {
 		var publication models.Publication
@@ -118,12 +122,16 @@ func (repository PublicationsRepository) Get(userID uint64) ([]models.Publicatio
 		publications = append(publications, publication)
 	}
 
+	if erro := lines.Err(); erro != nil {
+		return nil, erro
+	}
+
 	return publications, nil
 }
 
 // Update updates an publication in database
-func (repository PublicationsRepository) Update(publicationID uint64, publication models.Publication) error {
-	statement, erro := repository.db.Prepare(
+func (repository publicationsRepository) Update(ctx context.Context, publicationID uint64, publication models.Publication) error {
+	statement, erro := repository.db.PrepareContext(ctx,
 		"UPDATE publications SET title = ?, content = ? WHERE id = ?",
 	)
 	if erro != nil {
@@ -131,16 +139,17 @@ func (repository PublicationsRepository) Update(publicationID uint64, publicatio
 	}
 	defer statement.Close()
 
-	if _, erro := statement.Exec(publication.Title, publication.Content, publicationID); erro != nil {
+	result, erro := statement.ExecContext(ctx, publication.Title, publication.Content, publicationID)
+	if erro != nil {
 		return erro
 	}
 
-	return nil
+	return affected(result, errPublicationNotFound)
 }
 
 // Delete deletes an publication in database
-func (repository PublicationsRepository) Delete(publicationID uint64) error {
-	statement, erro := repository.db.Prepare(
+func (repository publicationsRepository) Delete(ctx context.Context, publicationID uint64) error {
+	statement, erro := repository.db.PrepareContext(ctx,
 		"DELETE FROM publications WHERE id = ?",
 	)
 	if erro != nil {
@@ -148,26 +157,31 @@ func (repository PublicationsRepository) Delete(publicationID uint64) error {
 	}
 	defer statement.Close()
 
-	if _, erro := statement.Exec(publicationID); erro != nil {
+	result, erro := statement.ExecContext(ctx, publicationID)
+	if erro != nil {
 		return erro
 	}
 
-	return nil
+	return affected(result, errPublicationNotFound)
 }
 
-// GetByUser return all publications from an user
-func (repository PublicationsRepository) GetByUser(userID uint64) ([]models.Publication, error) {
-	lines, erro := repository.db.Query(`
-		SELECT p.*, u.nick from publications
+// GetByUser return one page of the publications from an user
+func (repository publicationsRepository) GetByUser(ctx context.Context, userID uint64, page pagination.Page) ([]models.Publication, error) {
+	condition, orderBy, args := keyset(repository.db.Dialect, page, "p")
+
+	lines, erro := repository.db.QueryContext(ctx, `
+		SELECT p.*, u.nick from publications p
 		JOIN users u on u.id = p.author_id
-		WHERE p.author_id = ?
-	`, userID)
+		WHERE p.author_id = ? AND `+condition+`
+		`+orderBy,
+		append([]interface{}{userID}, args...)...,
+	)
 	if erro != nil {
 		return nil, erro
 	}
 	defer lines.Close()
 
-	var publications []models.Publication
+	publications := []models.Publication{}
 
 	for lines.Next() {
 		var publication models.Publication
@@ -187,12 +201,15 @@ func (repository PublicationsRepository) GetByUser(userID uint64) ([]models.Publ
 		publications = append(publications, publication)
 	}
 
+	if erro := lines.Err(); erro != nil {
+		return nil, erro
+	}
+
 	return publications, nil
 }
 
 // LikePublication likes an publication in database
-func (repository PublicationsRepository) LikePublication(publicationID, likerID uint64) error {
-	ctx := context.Background()
+func (repository publicationsRepository) LikePublication(ctx context.Context, publicationID, likerID uint64) error {
 	tx, erro := repository.db.BeginTx(ctx, nil)
 	if erro != nil {
 		return erro
@@ -211,14 +228,24 @@ func (repository PublicationsRepository) LikePublication(publicationID, likerID
 				publicationID, likerID,
 			); erro != nil {
 				tx.Rollback()
+				if _, duplicated := database.UniqueViolation(erro); duplicated {
+					return errPublicationLiked
+				}
+				if database.ForeignKeyViolation(erro) {
+					return errPublicationNotFound
+				}
 				return erro
 			}
 		} else if sqlQueryIndex == 1 {
-			if _, erro = tx.ExecContext(
+			result, erro := tx.ExecContext(
 				ctx,
 				query,
 				publicationID,
-			); erro != nil {
+			)
+			if erro == nil {
+				erro = affected(result, errPublicationNotFound)
+			}
+			if erro != nil {
 				tx.Rollback()
 				return erro
 			}
@@ -232,68 +259,68 @@ func (repository PublicationsRepository) LikePublication(publicationID, likerID
 	return nil
 }
 
-// UnLikePublication unlikes an publication in database
-func (repository PublicationsRepository) UnLikePublication(publicationID, unLikerID uint64) error {
-	ctx := context.Background()
+// UnLikePublication unlikes an publication in database, its likes decremented only when the user liked it
+func (repository publicationsRepository) UnLikePublication(ctx context.Context, publicationID, unLikerID uint64) error {
+	var publications int
+	if erro := repository.db.QueryRowContext(ctx,
+		"SELECT COUNT(*) FROM publications WHERE id = ?",
+		publicationID,
+	).Scan(&publications); erro != nil {
+		return erro
+	}
+	if publications == 0 {
+		return errPublicationNotFound
+	}
+
 	tx, erro := repository.db.BeginTx(ctx, nil)
 	if erro != nil {
 		return erro
 	}
 
-	sqlQueries := []string{
-		`
-		UPDATE publications SET likes =
-		CASE
-			WHEN likes > 0 THEN likes - 1
-		ELSE 0
-		END
-		WHERE id = ?
-		`,
-		`
-		DELETE FROM likes_of_publications WHERE publication_id = ? AND liker_id = ?
-		`,
+	result, erro := tx.ExecContext(ctx,
+		"DELETE FROM likes_of_publications WHERE publication_id = ? AND liker_id = ?",
+		publicationID, unLikerID,
+	)
+	if erro != nil {
+		tx.Rollback()
+		return erro
 	}
 
-	for sqlQueryIndex, query := range sqlQueries {
-		if sqlQueryIndex == 0 {
-			if _, erro = tx.ExecContext(
-				ctx,
-				query,
-				publicationID,
-			); erro != nil {
-				tx.Rollback()
-				return erro
-			}
-		} else if sqlQueryIndex == 1 {
-			if _, erro = tx.ExecContext(
-				ctx,
-				query,
-				publicationID, unLikerID,
-			); erro != nil {
-				tx.Rollback()
-				return erro
-			}
-		}
+	unliked, erro := result.RowsAffected()
+	if erro != nil {
+		tx.Rollback()
+		return erro
 	}
 
-	if erro := tx.Commit(); erro != nil {
-		return erro
+	if unliked == 1 {
+		if _, erro := tx.ExecContext(ctx,
+			"UPDATE publications SET likes = likes - 1 WHERE id = ? AND likes > 0",
+			publicationID,
+		); erro != nil {
+			tx.Rollback()
+			return erro
+		}
 	}
 
-	return nil
+	return tx.Commit()
 }
 
-// GetLikers return all users who like an publication
-func (repository PublicationsRepository) GetLikers(publicationID uint64) ([]models.User, error) {
-	lines, erro := repository.db.Query(`
+// GetLikers return one page of the users who like an publication
+func (repository publicationsRepository) GetLikers(ctx context.Context, publicationID uint64, page pagination.Page) ([]models.User, error) {
+	condition, orderBy, args := keyset(repository.db.Dialect, page, "u")
+
+	lines, erro := repository.db.QueryContext(ctx, `
 		SELECT u.id, u.name, u.nick, u.createdat FROM
-		users u JOIN likes_of_publications p on u.id = p.liker_id WHERE p.publication_id = ?;
-	`, publicationID)
+		users u JOIN likes_of_publications p on u.id = p.liker_id WHERE p.publication_id = ? AND `+condition+`
+		`+orderBy,
+		append([]interface{}{publicationID}, args...)...,
+	)
 	if erro != nil {
 		return nil, erro
 	}
+	defer lines.Close()
 
-	var users []models.User
+	users := []models.User{}
 
 	for lines.Next() {
 		var user models.User
@@ -310,5 +337,9 @@ func (repository PublicationsRepository) GetLikers(publicationID uint64) ([]mode
 		users = append(users, user)
 	}
 
+	if erro := lines.Err(); erro != nil {
+		return nil, erro
+	}
+
 	return users, nil
 }
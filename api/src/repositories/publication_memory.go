@@ -0,0 +1,201 @@
+/*
+Copyright 2022 Danilo S. Lopes.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at:
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package repositories
+
+import (
+	"api/src/models"
+	"api/src/pagination"
+	"context"
+	"time"
+)
+
+type memoryPublicationsRepository struct {
+	data *memoryDB
+}
+
+// newMemoryPublicationRepository creates an in-memory Publication repository
+func newMemoryPublicationRepository(data *memoryDB) PublicationsRepository {
+	return &memoryPublicationsRepository{data}
+}
+
+// Create create post in memory
+func (repository memoryPublicationsRepository) Create(ctx context.Context, post models.Publication) (uint64, error) {
+	repository.data.mu.Lock()
+	defer repository.data.mu.Unlock()
+
+	if _, exists := repository.data.users[post.AuthorID]; !exists {
+		return 0, errUserNotFound
+	}
+
+	repository.data.lastPublicationID++
+	publication := models.Publication{
+		ID:        repository.data.lastPublicationID,
+		Title:     post.Title,
+		Content:   post.Content,
+		AuthorID:  post.AuthorID,
+		CreatedAt: time.Now(),
+	}
+	repository.data.publications[publication.ID] = publication
+
+	return publication.ID, nil
+}
+
+// SearchByID return one publication
+func (repository memoryPublicationsRepository) SearchByID(ctx context.Context, publicationID uint64) (models.Publication, error) {
+	repository.data.mu.RLock()
+	defer repository.data.mu.RUnlock()
+
+	publication, exists := repository.data.publications[publicationID]
+	if !exists {
+		return models.Publication{}, errPublicationNotFound
+	}
+
+	return repository.data.withAuthorNick(publication), nil
+}
+
+// Get return one page of the publications related by an user(self publications and his friends publications)
+func (repository memoryPublicationsRepository) Get(ctx context.Context, userID uint64, page pagination.Page) ([]models.Publication, error) {
+	repository.data.mu.RLock()
+	defer repository.data.mu.RUnlock()
+
+	publications := []models.Publication{}
+	for _, ID := range repository.data.sortedPublicationIDs() {
+		publication := repository.data.publications[ID]
+		_, following := repository.data.followers[follow{publication.AuthorID, userID}]
+
+		if publication.AuthorID == userID || following {
+			publications = append(publications, repository.data.withAuthorNick(publication))
+		}
+	}
+
+	return memoryPage(page, publications), nil
+}
+
+// Update updates an publication
+func (repository memoryPublicationsRepository) Update(ctx context.Context, publicationID uint64, publication models.Publication) error {
+	repository.data.mu.Lock()
+	defer repository.data.mu.Unlock()
+
+	stored, exists := repository.data.publications[publicationID]
+	if !exists {
+		return errPublicationNotFound
+	}
+
+	stored.Title = publication.Title
+	stored.Content = publication.Content
+	repository.data.publications[publicationID] = stored
+
+	return nil
+}
+
+// Delete deletes an publication
+func (repository memoryPublicationsRepository) Delete(ctx context.Context, publicationID uint64) error {
+	repository.data.mu.Lock()
+	defer repository.data.mu.Unlock()
+
+	if _, exists := repository.data.publications[publicationID]; !exists {
+		return errPublicationNotFound
+	}
+
+	repository.data.deletePublication(publicationID)
+
+	return nil
+}
+
+// GetByUser return one page of the publications from an user
+func (repository memoryPublicationsRepository) GetByUser(ctx context.Context, userID uint64, page pagination.Page) ([]models.Publication, error) {
+	repository.data.mu.RLock()
+	defer repository.data.mu.RUnlock()
+
+	publications := []models.Publication{}
+	for _, ID := range repository.data.sortedPublicationIDs() {
+		publication := repository.data.publications[ID]
+		if publication.AuthorID == userID {
+			publications = append(publications, repository.data.withAuthorNick(publication))
+		}
+	}
+
+	return memoryPage(page, publications), nil
+}
+
+// LikePublication likes an publication
+func (repository memoryPublicationsRepository) LikePublication(ctx context.Context, publicationID, likerID uint64) error {
+	repository.data.mu.Lock()
+	defer repository.data.mu.Unlock()
+
+	publication, exists := repository.data.publications[publicationID]
+	if !exists {
+		return errPublicationNotFound
+	}
+
+	if _, exists := repository.data.users[likerID]; !exists {
+		return errUserNotFound
+	}
+
+	relation := like{publicationID, likerID}
+	if _, liked := repository.data.likes[relation]; liked {
+		return errPublicationLiked
+	}
+
+	repository.data.likes[relation] = struct{}{}
+	publication.Likes++
+	repository.data.publications[publicationID] = publication
+
+	return nil
+}
+
+// UnLikePublication unlikes an publication
+func (repository memoryPublicationsRepository) UnLikePublication(ctx context.Context, publicationID, unLikerID uint64) error {
+	repository.data.mu.Lock()
+	defer repository.data.mu.Unlock()
+
+	if _, exists := repository.data.publications[publicationID]; !exists {
+		return errPublicationNotFound
+	}
+
+	relation := like{publicationID, unLikerID}
+	if _, liked := repository.data.likes[relation]; !liked {
+		return nil
+	}
+
+	delete(repository.data.likes, relation)
+
+	publication := repository.data.publications[publicationID]
+	if publication.Likes > 0 {
+		publication.Likes--
+	}
+	repository.data.publications[publicationID] = publication
+
+	return nil
+}
+
+// GetLikers return one page of the users who like an publication
+func (repository memoryPublicationsRepository) GetLikers(ctx context.Context, publicationID uint64, page pagination.Page) ([]models.User, error) {
+	repository.data.mu.RLock()
+	defer repository.data.mu.RUnlock()
+
+	users := []models.User{}
+	for _, ID := range repository.data.sortedUserIDs() {
+		if _, liked := repository.data.likes[like{publicationID, ID}]; liked {
+			user := publicUser(repository.data.users[ID])
+			user.Email = ""
+			users = append(users, user)
+		}
+	}
+
+	return memoryPage(page, users), nil
+}
@@ -0,0 +1,184 @@
+/*
+Copyright 2022 Danilo S. Lopes.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at:
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package repositories
+
+import (
+	"api/src/models"
+	"api/src/pagination"
+	"context"
+	"testing"
+)
+
+func TestPublications(t *testing.T) {
+	forEachEngine(t, func(t *testing.T, store Store) {
+		ctx := context.Background()
+		author := createUser(t, store, "author")
+		reader := createUser(t, store, "reader")
+		publication := createPublication(t, store, author, "first")
+
+		stored, erro := store.Publications.SearchByID(ctx, publication)
+		if erro != nil {
+			t.Fatal(erro)
+		}
+		if stored.AuthorNick != "author" || stored.Likes != 0 {
+			t.Fatalf("got publication %+v, want the author nick and no like", stored)
+		}
+
+		// nobody follows the author yet
+		own, erro := store.Publications.Get(ctx, author, pagination.Page{Limit: 10})
+		if erro != nil {
+			t.Fatal(erro)
+		}
+		if len(own) != 1 || own[0].ID != publication {
+			t.Fatalf("got feed %+v for an author without followers, want their publication %d", own, publication)
+		}
+
+		_, erro = store.Publications.SearchByID(ctx, publication+100)
+		wantKind(t, erro, models.ErrNotFound)
+
+		_, erro = store.Publications.Create(ctx, models.Publication{Title: "t", Content: "c", AuthorID: reader + 100})
+		wantKind(t, erro, models.ErrNotFound)
+
+		if erro := store.Publications.LikePublication(ctx, publication, reader); erro != nil {
+			t.Fatal(erro)
+		}
+		wantKind(t, store.Publications.LikePublication(ctx, publication, reader), models.ErrConflict)
+		wantKind(t, store.Publications.LikePublication(ctx, publication+100, reader), models.ErrNotFound)
+
+		likers, erro := store.Publications.GetLikers(ctx, publication, pagination.Page{Limit: 10})
+		if erro != nil {
+			t.Fatal(erro)
+		}
+		if len(likers) != 1 || likers[0].ID != reader {
+			t.Fatalf("got likers %+v, want the user %d", likers, reader)
+		}
+
+		liked, erro := store.Users.LikedPublications(ctx, reader, pagination.Page{Limit: 10})
+		if erro != nil {
+			t.Fatal(erro)
+		}
+		if len(liked) != 1 || liked[0].ID != publication || liked[0].Likes != 1 {
+			t.Fatalf("got liked publications %+v, want the publication %d liked once", liked, publication)
+		}
+
+		if erro := store.Publications.LikePublication(ctx, publication, author); erro != nil {
+			t.Fatal(erro)
+		}
+		if erro := store.Publications.UnLikePublication(ctx, publication, reader); erro != nil {
+			t.Fatal(erro)
+		}
+		if erro := store.Publications.UnLikePublication(ctx, publication, reader); erro != nil {
+			t.Fatalf("unliking a publication not liked: %v", erro)
+		}
+		unliked, erro := store.Publications.SearchByID(ctx, publication)
+		if erro != nil {
+			t.Fatal(erro)
+		}
+		if unliked.Likes != 1 {
+			t.Fatalf("got %d likes after unliking twice one of the two likes, want 1", unliked.Likes)
+		}
+		if erro := store.Publications.UnLikePublication(ctx, publication, author); erro != nil {
+			t.Fatal(erro)
+		}
+		wantKind(t, store.Publications.UnLikePublication(ctx, publication+100, reader), models.ErrNotFound)
+
+		feed, erro := store.Publications.Get(ctx, reader, pagination.Page{Limit: 10})
+		if erro != nil {
+			t.Fatal(erro)
+		}
+		if len(feed) != 0 {
+			t.Fatalf("got feed %+v before following the author, want none", feed)
+		}
+
+		if erro := store.Users.Follow(ctx, author, reader); erro != nil {
+			t.Fatal(erro)
+		}
+		feed, erro = store.Publications.Get(ctx, reader, pagination.Page{Limit: 10})
+		if erro != nil {
+			t.Fatal(erro)
+		}
+		if len(feed) != 1 || feed[0].ID != publication {
+			t.Fatalf("got feed %+v, want the publication %d", feed, publication)
+		}
+
+		if erro := store.Publications.Update(ctx, publication, models.Publication{Title: "edited", Content: "edited"}); erro != nil {
+			t.Fatal(erro)
+		}
+		wantKind(t, store.Publications.Update(ctx, publication+100, models.Publication{Title: "t", Content: "c"}), models.ErrNotFound)
+
+		if erro := store.Publications.Delete(ctx, publication); erro != nil {
+			t.Fatal(erro)
+		}
+		wantKind(t, store.Publications.Delete(ctx, publication), models.ErrNotFound)
+	})
+}
+
+// The keyset pages cover every publication once, newest first, both ways
+func TestPublicationsPages(t *testing.T) {
+	forEachEngine(t, func(t *testing.T, store Store) {
+		ctx := context.Background()
+		author := createUser(t, store, "author")
+
+		var created []uint64
+		for _, title := range []string{"1", "2", "3", "4", "5"} {
+			created = append([]uint64{createPublication(t, store, author, title)}, created...)
+		}
+
+		var (
+			seen  []uint64
+			pages []pagination.Page
+			last  *pagination.Page
+		)
+		page := pagination.Page{Limit: 2}
+		for {
+			fetched, erro := store.Publications.GetByUser(ctx, author, page)
+			if erro != nil {
+				t.Fatal(erro)
+			}
+
+			items, previous, next := pagination.Slice(page, fetched)
+			for _, item := range items {
+				seen = append(seen, item.ID)
+			}
+			pages = append(pages, page)
+
+			if next == nil {
+				last = previous
+				break
+			}
+			page = *next
+		}
+
+		if len(seen) != len(created) || len(pages) != 3 {
+			t.Fatalf("got publications %v in %d pages, want %v in 3", seen, len(pages), created)
+		}
+		for index := range created {
+			if seen[index] != created[index] {
+				t.Fatalf("got publications %v, want %v", seen, created)
+			}
+		}
+
+		fetched, erro := store.Publications.GetByUser(ctx, author, *last)
+		if erro != nil {
+			t.Fatal(erro)
+		}
+		items, _, _ := pagination.Slice(*last, fetched)
+		if len(items) != 2 || items[0].ID != created[2] || items[1].ID != created[3] {
+			t.Fatalf("got the previous page %+v, want the publications %v", items, created[2:4])
+		}
+	})
+}
@@ -0,0 +1,49 @@
+/*
+Copyright 2022 Danilo S. Lopes.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at:
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package repositories
+
+import "api/src/database"
+
+// Store groups the repositories used by the API controllers
+type Store struct {
+	Users        UsersRepository
+	Publications PublicationsRepository
+	Healthcheck  HealthcheckRepository
+	Idempotency  IdempotencyRepository
+}
+
+// NewSQLStore creates a Store backed by a SQL database (mysql, sqlite or postgres)
+func NewSQLStore(db *database.DB) Store {
+	return Store{
+		Users:        NewUsersRepository(db),
+		Publications: NewPublicationRepository(db),
+		Healthcheck:  NewHealthcheckRepository(db),
+		Idempotency:  NewIdempotencyRepository(db),
+	}
+}
+
+// NewMemoryStore creates a Store that keeps all data in process memory
+func NewMemoryStore() Store {
+	data := newMemoryDB()
+
+	return Store{
+		Users:        newMemoryUsersRepository(data),
+		Publications: newMemoryPublicationRepository(data),
+		Healthcheck:  NewMemoryHealthcheckRepository(),
+		Idempotency:  NewMemoryIdempotencyRepository(),
+	}
+}
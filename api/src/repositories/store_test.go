@@ -0,0 +1,138 @@
+/*
+Copyright 2022 Danilo S. Lopes.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at:
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package repositories
+
+import (
+	"api/src/config"
+	"api/src/database"
+	"api/src/migrations"
+	"api/src/models"
+	"context"
+	"database/sql"
+	"errors"
+	"io"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"go.opentelemetry.io/otel/trace/noop"
+)
+
+// Environment variables holding the data source names of the MySQL and PostgreSQL databases the
+// tests run against, skipped when unset. Every table of the databases is emptied.
+// e.g. TEST_MYSQL_DSN="sm:sm@tcp(localhost:3306)/sm_test?parseTime=true&clientFoundRows=true"
+// and TEST_POSTGRES_DSN="postgres://sm:sm@localhost:5432/sm_test?sslmode=disable"
+var engineDSNs = map[string]string{
+	"mysql":    "TEST_MYSQL_DSN",
+	"postgres": "TEST_POSTGRES_DSN",
+}
+
+// Tables emptied before each test, the referencing ones first
+var tables = []string{"likes_of_publications", "followers", "publications", "users", "idempotency_keys"}
+
+// forEachEngine runs test against an empty store of every engine: memory and SQLite, plus MySQL and
+// PostgreSQL when their data source name is set
+func forEachEngine(t *testing.T, test func(t *testing.T, store Store)) {
+	for _, engine := range []string{"memory", "sqlite", "mysql", "postgres"} {
+		t.Run(engine, func(t *testing.T) {
+			test(t, openStore(t, engine))
+		})
+	}
+}
+
+// openStore returns an empty store of engine, its schema migrated
+func openStore(t *testing.T, engine string) Store {
+	t.Helper()
+
+	if engine == "memory" {
+		return NewMemoryStore()
+	}
+
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	tracer := noop.NewTracerProvider().Tracer("")
+
+	var db *database.DB
+	if engine == "sqlite" {
+		var erro error
+		db, erro = database.Connect(config.Database{Driver: "sqlite", Path: filepath.Join(t.TempDir(), "sm.db")}, logger, tracer)
+		if erro != nil {
+			t.Fatal(erro)
+		}
+	} else {
+		dsn := os.Getenv(engineDSNs[engine])
+		if dsn == "" {
+			t.Skipf("%s is not set", engineDSNs[engine])
+		}
+
+		pool, erro := sql.Open(engine, dsn)
+		if erro != nil {
+			t.Fatal(erro)
+		}
+		db = &database.DB{DB: pool, Dialect: database.Dialect(engine), Logger: logger, Tracer: tracer}
+	}
+	t.Cleanup(func() { db.Close() })
+
+	migrator, erro := migrations.New(db)
+	if erro != nil {
+		t.Fatal(erro)
+	}
+	if erro := migrator.Up(context.Background()); erro != nil {
+		t.Fatalf("migrating the database: %v", erro)
+	}
+
+	for _, table := range tables {
+		if _, erro := db.ExecContext(context.Background(), "DELETE FROM "+table); erro != nil {
+			t.Fatalf("emptying %s: %v", table, erro)
+		}
+	}
+
+	return NewSQLStore(db)
+}
+
+// createUser stores a user named after nick and returns its ID
+func createUser(t *testing.T, store Store, nick string) uint64 {
+	t.Helper()
+
+	ID, erro := store.Users.Create(context.Background(), models.User{Name: nick, Nick: nick, Email: nick + "@example.com", Pass: "hash"})
+	if erro != nil {
+		t.Fatalf("creating the user %s: %v", nick, erro)
+	}
+
+	return ID
+}
+
+// createPublication stores a publication of authorID and returns its ID
+func createPublication(t *testing.T, store Store, authorID uint64, title string) uint64 {
+	t.Helper()
+
+	ID, erro := store.Publications.Create(context.Background(), models.Publication{Title: title, Content: title, AuthorID: authorID})
+	if erro != nil {
+		t.Fatalf("creating the publication %s: %v", title, erro)
+	}
+
+	return ID
+}
+
+// wantKind fails the test when erro is not a domain error of kind
+func wantKind(t *testing.T, erro, kind error) {
+	t.Helper()
+
+	if !errors.Is(erro, kind) {
+		t.Fatalf("got error %v, want %v", erro, kind)
+	}
+}
@@ -17,50 +17,61 @@ limitations under the License.
 package repositories
 
 import (
+	"api/src/database"
 	"api/src/models"
-	"database/sql"
+	"api/src/pagination"
+	"context"
 	"fmt"
 )
 
+// UsersRepository represents the users storage operations
+type UsersRepository interface {
+	Create(ctx context.Context, user models.User) (uint64, error)
+	Search(ctx context.Context, nameOrNick string, page pagination.Page) ([]models.User, error)
+	SearchByID(ctx context.Context, ID uint64) (models.User, error)
+	SearchByEmail(ctx context.Context, email string) (models.User, error)
+	Update(ctx context.Context, ID uint64, user models.User) error
+	Delete(ctx context.Context, ID uint64) error
+	Disable(ctx context.Context, ID uint64) error
+	Follow(ctx context.Context, userID, followerID uint64) error
+	UnFollow(ctx context.Context, userID, followerID uint64) error
+	GetFollowers(ctx context.Context, userID uint64, page pagination.Page) ([]models.User, error)
+	GetFollowing(ctx context.Context, userID uint64, page pagination.Page) ([]models.User, error)
+	GetUserPass(ctx context.Context, userID uint64) (string, error)
+	UpadateUserPass(ctx context.Context, userID uint64, pass string) error
+	LikedPublications(ctx context.Context, userID uint64, page pagination.Page) ([]models.Publication, error)
+}
+
 type usersRepository struct {
-	db *sql.DB
+	db *database.DB
 }
 
-// NewUsersRepository creates a Users repository
-func NewUsersRepository(db *sql.DB) *usersRepository {
+// NewUsersRepository creates a Users repository backed by a SQL database
+func NewUsersRepository(db *database.DB) UsersRepository {
 	return &usersRepository{db}
 }
 
 // Create creates a User in database
-func (repository usersRepository) Create(user models.User) (uint64, error) {
-	statement, erro := repository.db.Prepare(
+func (repository usersRepository) Create(ctx context.Context, user models.User) (uint64, error) {
+	ID, erro := repository.db.InsertReturningID(ctx,
 		"INSERT INTO users (name, nick, email, pass) VALUES (?, ?, ?, ?)",
+		user.Name, user.Nick, user.Email, user.Pass,
 	)
 	if erro != nil {
-		return 0, erro
-	}
-	defer statement.Close()
-
-	result, erro := statement.Exec(user.Name, user.Nick, user.Email, user.Pass)
-	if erro != nil {
-		return 0, erro
+		return 0, userConflict(erro)
 	}
 
-	lastID, erro := result.LastInsertId()
-	if erro != nil {
-		return 0, erro
-	}
-
-	return uint64(lastID), nil
+	return ID, nil
 }
 
-// Search return all Users or Nicknames matching with the filter(nameOrNick)
-func (repository usersRepository) Search(nameOrNick string) ([]models.User, error) {
+// Search return one page of the Users or Nicknames matching with the filter(nameOrNick)
+func (repository usersRepository) Search(ctx context.Context, nameOrNick string, page pagination.Page) ([]models.User, error) {
 	nameOrNick = fmt.Sprintf("%%%s%%", nameOrNick) // %nameOrNick%
+	condition, orderBy, args := keyset(repository.db.Dialect, page, "users")
 
-	lines, erro := repository.db.Query(
-		"SELECT id, name, nick, email, createdat FROM users WHERE name LIKE ? OR nick LIKE ?",
-		nameOrNick, nameOrNick,
+	lines, erro := repository.db.QueryContext(ctx,
+		"SELECT id, name, nick, email, createdat FROM users WHERE (LOWER(name) LIKE LOWER(?) OR LOWER(nick) LIKE LOWER(?)) AND "+condition+" "+orderBy,
+		append([]interface{}{nameOrNick, nameOrNick}, args...)...,
 	)
 	if erro != nil {
 		return nil, erro
@@ -68,7 +79,7 @@ func (repository usersRepository) Search(nameOrNick string) ([]models.User, erro
 
 	defer lines.Close()
 
-	var users []models.User
+	users := []models.User{}
 
 	for lines.Next() {
 		var user models.User
@@ -86,107 +97,132 @@ func (repository usersRepository) Search(nameOrNick string) ([]models.User, erro
 		users = append(users, user)
 	}
 
+	if erro := lines.Err(); erro != nil {
+		return nil, erro
+	}
+
 	return users, nil
 }
 
 // SearchByID return the User matching with the ID
-func (repository usersRepository) SearchByID(ID uint64) (models.User, error) {
-	lines, erro := repository.db.Query(
-		"SELECT id, name, nick, email, createdat FROM users WHERE id = ?",
+func (repository usersRepository) SearchByID(ctx context.Context, ID uint64) (models.User, error) {
+	var user models.User
+
+	if erro := repository.db.QueryRowContext(ctx,
+		"SELECT id, name, nick, email, createdat, disabled FROM users WHERE id = ?",
 		ID,
-	)
-	if erro != nil {
-		return models.User{}, erro
+	).Scan(
+		&user.ID,
+		&user.Name,
+		&user.Nick,
+		&user.Email,
+		&user.CreatedAt,
+		&user.Disabled,
+	); erro != nil {
+		return models.User{}, notFound(erro, errUserNotFound)
 	}
-	defer lines.Close()
 
+	return user, nil
+}
+
+// SearchByEmail search an user by email and returns the id, the password hash and if he is disabled
+func (repository usersRepository) SearchByEmail(ctx context.Context, email string) (models.User, error) {
 	var user models.User
 
-	if lines.Next() {
-		if erro := lines.Scan(
-			&user.ID,
-			&user.Name,
-			&user.Nick,
-			&user.Email,
-			&user.CreatedAt,
-		); erro != nil {
-			return models.User{}, erro
-		}
+	if erro := repository.db.QueryRowContext(ctx,
+		"SELECT id, pass, disabled FROM users WHERE email = ?",
+		email,
+	).Scan(
+		&user.ID,
+		&user.Pass,
+		&user.Disabled,
+	); erro != nil {
+		return models.User{}, notFound(erro, errUserNotFound)
 	}
 
 	return user, nil
 }
 
-// SearchByEmail search an user by email and returns the id and the password hash
-func (repository usersRepository) SearchByEmail(email string) (models.User, error) {
-	line, erro := repository.db.Query(
-		"SELECT id, pass FROM users WHERE email = ?",
-		email,
+// Update updates an Users attributes into database
+func (repository usersRepository) Update(ctx context.Context, ID uint64, user models.User) error {
+	statement, erro := repository.db.PrepareContext(ctx,
+		"UPDATE users SET name = ?, nick = ?, email = ? WHERE id = ?",
 	)
 	if erro != nil {
-		return models.User{}, erro
+		return erro
 	}
-	defer line.Close()
-
-	var user models.User
+	defer statement.Close()
 
-	if line.Next() {
-		if erro := line.Scan(
-			&user.ID,
-			&user.Pass,
-		); erro != nil {
-			return models.User{}, erro
-		}
+	result, erro := statement.ExecContext(ctx, user.Name, user.Nick, user.Email, ID)
+	if erro != nil {
+		return userConflict(erro)
 	}
 
-	return user, nil
+	return affected(result, errUserNotFound)
 }
 
-// Update updates an Users attributes into database
-func (repository usersRepository) Update(ID uint64, user models.User) error {
-	statement, erro := repository.db.Prepare(
-		"UPDATE users SET name = ?, nick = ?, email = ? WHERE id = ?",
+// Delete delete an User into database
+func (repository usersRepository) Delete(ctx context.Context, ID uint64) error {
+	statement, erro := repository.db.PrepareContext(ctx,
+		"DELETE FROM users WHERE id = ?",
 	)
 	if erro != nil {
 		return erro
 	}
 	defer statement.Close()
 
-	if _, erro := statement.Exec(user.Name, user.Nick, user.Email, ID); erro != nil {
+	result, erro := statement.ExecContext(ctx, ID)
+	if erro != nil {
 		return erro
 	}
 
-	return nil
+	return affected(result, errUserNotFound)
 }
 
-// Delete delete an User into database
-func (repository usersRepository) Delete(ID uint64) error {
-	statement, erro := repository.db.Prepare(
-		"DELETE FROM users WHERE id = ?",
+// Disable prevents an User from logging in
+func (repository usersRepository) Disable(ctx context.Context, ID uint64) error {
+	statement, erro := repository.db.PrepareContext(ctx,
+		"UPDATE users SET disabled = TRUE WHERE id = ?",
 	)
 	if erro != nil {
 		return erro
 	}
 	defer statement.Close()
 
-	if _, erro := statement.Exec(ID); erro != nil {
+	result, erro := statement.ExecContext(ctx, ID)
+	if erro != nil {
 		return erro
 	}
 
-	return nil
+	return affected(result, errUserNotFound)
 }
 
 //Follow permits an User to follow another User
-func (repository usersRepository) Follow(userID, followerID uint64) error {
-	statement, erro := repository.db.Prepare(
-		"INSERT IGNORE INTO followers (user_id, follower_id) VALUES (?, ?)",
+func (repository usersRepository) Follow(ctx context.Context, userID, followerID uint64) error {
+	// INSERT IGNORE of MySQL skips the foreign key violations too, so both users are looked up first
+	var users int
+	if erro := repository.db.QueryRowContext(ctx,
+		"SELECT COUNT(*) FROM users WHERE id IN (?, ?)",
+		userID, followerID,
+	).Scan(&users); erro != nil {
+		return erro
+	}
+	if users != 2 {
+		return errUserNotFound
+	}
+
+	statement, erro := repository.db.PrepareContext(ctx,
+		repository.db.Dialect.InsertIgnore("followers", "user_id, follower_id", "?, ?"),
 	)
 	if erro != nil {
 		return erro
 	}
 	defer statement.Close()
 
-	if _, erro := statement.Exec(userID, followerID); erro != nil {
+	if _, erro := statement.ExecContext(ctx, userID, followerID); erro != nil {
+		if database.ForeignKeyViolation(erro) {
+			return errUserNotFound
+		}
 		return erro
 	}
 
@@ -194,8 +230,8 @@ func (repository usersRepository) Follow(userID, followerID uint64) error {
 }
 
 //Follow permits an User to unfollow another User
-func (repository usersRepository) UnFollow(userID, followerID uint64) error {
-	statement, erro := repository.db.Prepare(
+func (repository usersRepository) UnFollow(ctx context.Context, userID, followerID uint64) error {
+	statement, erro := repository.db.PrepareContext(ctx,
 		"DELETE FROM followers WHERE user_id = ? and follower_id = ? ",
 	)
 	if erro != nil {
@@ -203,26 +239,29 @@ func (repository usersRepository) UnFollow(userID, followerID uint64) error {
 	}
 	defer statement.Close()
 
-	if _, erro := statement.Exec(userID, followerID); erro != nil {
+	if _, erro := statement.ExecContext(ctx, userID, followerID); erro != nil {
 		return erro
 	}
 
 	return nil
 }
 
-//GetFollowers return all followers from User
-func (repository usersRepository) GetFollowers(userID uint64) ([]models.User, error) {
-	lines, erro := repository.db.Query(`
+//GetFollowers return one page of the followers from User
+func (repository usersRepository) GetFollowers(ctx context.Context, userID uint64, page pagination.Page) ([]models.User, error) {
+	condition, orderBy, args := keyset(repository.db.Dialect, page, "u")
+
+	lines, erro := repository.db.QueryContext(ctx, `
 		SELECT u.id, u.name, u.nick, u.email, u.createdat
-		FROM users u INNER JOIN followers s on u.id = s.follower_id WHERE s.user_id = ?
-	`, userID,
+		FROM users u INNER JOIN followers s on u.id = s.follower_id WHERE s.user_id = ? AND `+condition+`
+		`+orderBy,
+		append([]interface{}{userID}, args...)...,
 	)
 	if erro != nil {
 		return nil, erro
 	}
 	defer lines.Close()
 
-	var followers []models.User
+	followers := []models.User{}
 
 	for lines.Next() {
 		var follower models.User
@@ -240,22 +279,29 @@ func (repository usersRepository) GetFollowers(userID uint64) ([]models.User, er
 		followers = append(followers, follower)
 	}
 
+	if erro := lines.Err(); erro != nil {
+		return nil, erro
+	}
+
 	return followers, nil
 }
 
-//GetFollowing return all users one user is following
-func (repository usersRepository) GetFollowing(userID uint64) ([]models.User, error) {
-	lines, erro := repository.db.Query(`
+//GetFollowing return one page of the users one user is following
+func (repository usersRepository) GetFollowing(ctx context.Context, userID uint64, page pagination.Page) ([]models.User, error) {
+	condition, orderBy, args := keyset(repository.db.Dialect, page, "u")
+
+	lines, erro := repository.db.QueryContext(ctx, `
 		SELECT u.id, u.name, u.nick, u.email, u.createdat
-		FROM users u INNER JOIN followers s on u.id = s.user_id WHERE s.follower_id = ?
-	`, userID,
+		FROM users u INNER JOIN followers s on u.id = s.user_id WHERE s.follower_id = ? AND `+condition+`
+		`+orderBy,
+		append([]interface{}{userID}, args...)...,
 	)
 	if erro != nil {
 		return nil, erro
 	}
 	defer lines.Close()
 
-	var users []models.User
+	users := []models.User{}
 
 	for lines.Next() {
 		var user models.User
@@ -273,36 +319,30 @@ func (repository usersRepository) GetFollowing(userID uint64) ([]models.User, er
 		users = append(users, user)
 	}
 
+	if erro := lines.Err(); erro != nil {
+		return nil, erro
+	}
+
 	return users, nil
 }
 
 // GetUserPass return the user password thought ID
-func (repository usersRepository) GetUserPass(userID uint64) (string, error) {
-	line, erro := repository.db.Query(
+func (repository usersRepository) GetUserPass(ctx context.Context, userID uint64) (string, error) {
+	var pass string
+
+	if erro := repository.db.QueryRowContext(ctx,
 		"SELECT pass FROM users WHERE id = ?",
 		userID,
-	)
-	if erro != nil {
-		return "", erro
+	).Scan(&pass); erro != nil {
+		return "", notFound(erro, errUserNotFound)
 	}
-	defer line.Close()
 
-	var user models.User
-
-	if line.Next() {
-		if erro := line.Scan(
-			&user.Pass,
-		); erro != nil {
-			return "", erro
-		}
-	}
-
-	return user.Pass, nil
+	return pass, nil
 }
 
 // UpadateUserPass update the user pass
-func (repository usersRepository) UpadateUserPass(userID uint64, pass string) error {
-	statement, erro := repository.db.Prepare(
+func (repository usersRepository) UpadateUserPass(ctx context.Context, userID uint64, pass string) error {
+	statement, erro := repository.db.PrepareContext(ctx,
 		"UPDATE users SET pass = ? WHERE id = ?",
 	)
 	if erro != nil {
@@ -310,28 +350,32 @@ func (repository usersRepository) UpadateUserPass(userID uint64, pass string) er
 	}
 	defer statement.Close()
 
-	if _, erro = statement.Exec(pass, userID); erro != nil {
+	result, erro := statement.ExecContext(ctx, pass, userID)
+	if erro != nil {
 		return erro
 	}
 
-	return nil
+	return affected(result, errUserNotFound)
 }
 
-// LikedPublication return all publications and user liked
-func (repository usersRepository) LikedPublications(userID uint64) ([]models.Publication, error) {
-	lines, erro := repository.db.Query(`
+// LikedPublication return one page of the publications and user liked
+func (repository usersRepository) LikedPublications(ctx context.Context, userID uint64, page pagination.Page) ([]models.Publication, error) {
+	condition, orderBy, args := keyset(repository.db.Dialect, page, "p")
+
+	lines, erro := repository.db.QueryContext(ctx, `
 		SELECT DISTINCT p.* FROM publications p
 		JOIN likes_of_publications l on p.id = l.publication_id
 		JOIN users u on u.id = p.author_id
-		WHERE u.id = ? OR l.liker_id = ?;
-	`, userID, userID,
+		WHERE (u.id = ? OR l.liker_id = ?) AND `+condition+`
+		`+orderBy,
+		append([]interface{}{userID, userID}, args...)...,
 	)
 	if erro != nil {
 		return nil, erro
 	}
 	defer lines.Close()
 
-	var publications []models.Publication
+	publications := []models.Publication{}
 
 	for lines.Next() {
 		var publication models.Publication
@@ -350,5 +394,9 @@ func (repository usersRepository) LikedPublications(userID uint64) ([]models.Pub
 		publications = append(publications, publication)
 	}
 
+	if erro := lines.Err(); erro != nil {
+		return nil, erro
+	}
+
 	return publications, nil
 }
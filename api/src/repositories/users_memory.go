@@ -0,0 +1,287 @@
+/*
+Copyright 2022 Danilo S. Lopes.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at:
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package repositories
+
+import (
+	"api/src/models"
+	"api/src/pagination"
+	"context"
+	"strings"
+	"time"
+)
+
+type memoryUsersRepository struct {
+	data *memoryDB
+}
+
+// newMemoryUsersRepository creates an in-memory Users repository
+func newMemoryUsersRepository(data *memoryDB) UsersRepository {
+	return &memoryUsersRepository{data}
+}
+
+// Create creates a User in memory
+func (repository memoryUsersRepository) Create(ctx context.Context, user models.User) (uint64, error) {
+	repository.data.mu.Lock()
+	defer repository.data.mu.Unlock()
+
+	if erro := repository.checkUnique(0, user); erro != nil {
+		return 0, erro
+	}
+
+	repository.data.lastUserID++
+	user.ID = repository.data.lastUserID
+	user.CreatedAt = time.Now()
+	repository.data.users[user.ID] = user
+
+	return user.ID, nil
+}
+
+// Search return one page of the Users or Nicknames matching with the filter(nameOrNick)
+func (repository memoryUsersRepository) Search(ctx context.Context, nameOrNick string, page pagination.Page) ([]models.User, error) {
+	repository.data.mu.RLock()
+	defer repository.data.mu.RUnlock()
+
+	nameOrNick = strings.ToLower(nameOrNick)
+
+	users := []models.User{}
+	for _, ID := range repository.data.sortedUserIDs() {
+		user := repository.data.users[ID]
+		if strings.Contains(strings.ToLower(user.Name), nameOrNick) ||
+			strings.Contains(strings.ToLower(user.Nick), nameOrNick) {
+			users = append(users, publicUser(user))
+		}
+	}
+
+	return memoryPage(page, users), nil
+}
+
+// SearchByID return the User matching with the ID
+func (repository memoryUsersRepository) SearchByID(ctx context.Context, ID uint64) (models.User, error) {
+	repository.data.mu.RLock()
+	defer repository.data.mu.RUnlock()
+
+	user, exists := repository.data.users[ID]
+	if !exists {
+		return models.User{}, errUserNotFound
+	}
+
+	return publicUser(user), nil
+}
+
+// SearchByEmail search an user by email and returns the id, the password hash and if he is disabled
+func (repository memoryUsersRepository) SearchByEmail(ctx context.Context, email string) (models.User, error) {
+	repository.data.mu.RLock()
+	defer repository.data.mu.RUnlock()
+
+	for _, user := range repository.data.users {
+		if user.Email == email {
+			return models.User{ID: user.ID, Pass: user.Pass, Disabled: user.Disabled}, nil
+		}
+	}
+
+	return models.User{}, errUserNotFound
+}
+
+// Update updates an Users attributes in memory
+func (repository memoryUsersRepository) Update(ctx context.Context, ID uint64, user models.User) error {
+	repository.data.mu.Lock()
+	defer repository.data.mu.Unlock()
+
+	stored, exists := repository.data.users[ID]
+	if !exists {
+		return errUserNotFound
+	}
+
+	if erro := repository.checkUnique(ID, user); erro != nil {
+		return erro
+	}
+
+	stored.Name = user.Name
+	stored.Nick = user.Nick
+	stored.Email = user.Email
+	repository.data.users[ID] = stored
+
+	return nil
+}
+
+// Delete delete an User and everything related to him
+func (repository memoryUsersRepository) Delete(ctx context.Context, ID uint64) error {
+	repository.data.mu.Lock()
+	defer repository.data.mu.Unlock()
+
+	if _, exists := repository.data.users[ID]; !exists {
+		return errUserNotFound
+	}
+
+	delete(repository.data.users, ID)
+
+	for relation := range repository.data.followers {
+		if relation.userID == ID || relation.followerID == ID {
+			delete(repository.data.followers, relation)
+		}
+	}
+
+	for publicationID, publication := range repository.data.publications {
+		if publication.AuthorID == ID {
+			repository.data.deletePublication(publicationID)
+		}
+	}
+
+	for relation := range repository.data.likes {
+		if relation.likerID == ID {
+			delete(repository.data.likes, relation)
+		}
+	}
+
+	return nil
+}
+
+// Disable prevents an User from logging in
+func (repository memoryUsersRepository) Disable(ctx context.Context, ID uint64) error {
+	repository.data.mu.Lock()
+	defer repository.data.mu.Unlock()
+
+	user, exists := repository.data.users[ID]
+	if !exists {
+		return errUserNotFound
+	}
+
+	user.Disabled = true
+	repository.data.users[ID] = user
+
+	return nil
+}
+
+// Follow permits an User to follow another User
+func (repository memoryUsersRepository) Follow(ctx context.Context, userID, followerID uint64) error {
+	repository.data.mu.Lock()
+	defer repository.data.mu.Unlock()
+
+	_, userExists := repository.data.users[userID]
+	_, followerExists := repository.data.users[followerID]
+	if !userExists || !followerExists {
+		return errUserNotFound
+	}
+
+	repository.data.followers[follow{userID, followerID}] = struct{}{}
+
+	return nil
+}
+
+// UnFollow permits an User to unfollow another User
+func (repository memoryUsersRepository) UnFollow(ctx context.Context, userID, followerID uint64) error {
+	repository.data.mu.Lock()
+	defer repository.data.mu.Unlock()
+
+	delete(repository.data.followers, follow{userID, followerID})
+
+	return nil
+}
+
+// GetFollowers return one page of the followers from User
+func (repository memoryUsersRepository) GetFollowers(ctx context.Context, userID uint64, page pagination.Page) ([]models.User, error) {
+	repository.data.mu.RLock()
+	defer repository.data.mu.RUnlock()
+
+	followers := []models.User{}
+	for _, ID := range repository.data.sortedUserIDs() {
+		if _, follows := repository.data.followers[follow{userID, ID}]; follows {
+			followers = append(followers, publicUser(repository.data.users[ID]))
+		}
+	}
+
+	return memoryPage(page, followers), nil
+}
+
+// GetFollowing return one page of the users one user is following
+func (repository memoryUsersRepository) GetFollowing(ctx context.Context, userID uint64, page pagination.Page) ([]models.User, error) {
+	repository.data.mu.RLock()
+	defer repository.data.mu.RUnlock()
+
+	users := []models.User{}
+	for _, ID := range repository.data.sortedUserIDs() {
+		if _, follows := repository.data.followers[follow{ID, userID}]; follows {
+			users = append(users, publicUser(repository.data.users[ID]))
+		}
+	}
+
+	return memoryPage(page, users), nil
+}
+
+// GetUserPass return the user password thought ID
+func (repository memoryUsersRepository) GetUserPass(ctx context.Context, userID uint64) (string, error) {
+	repository.data.mu.RLock()
+	defer repository.data.mu.RUnlock()
+
+	user, exists := repository.data.users[userID]
+	if !exists {
+		return "", errUserNotFound
+	}
+
+	return user.Pass, nil
+}
+
+// UpadateUserPass update the user pass
+func (repository memoryUsersRepository) UpadateUserPass(ctx context.Context, userID uint64, pass string) error {
+	repository.data.mu.Lock()
+	defer repository.data.mu.Unlock()
+
+	user, exists := repository.data.users[userID]
+	if !exists {
+		return errUserNotFound
+	}
+
+	user.Pass = pass
+	repository.data.users[userID] = user
+
+	return nil
+}
+
+// LikedPublications return one page of the publications and user liked
+func (repository memoryUsersRepository) LikedPublications(ctx context.Context, userID uint64, page pagination.Page) ([]models.Publication, error) {
+	repository.data.mu.RLock()
+	defer repository.data.mu.RUnlock()
+
+	publications := []models.Publication{}
+	for _, ID := range repository.data.sortedPublicationIDs() {
+		if _, liked := repository.data.likes[like{ID, userID}]; liked {
+			publication := repository.data.publications[ID]
+			publications = append(publications, publication)
+		}
+	}
+
+	return memoryPage(page, publications), nil
+}
+
+// checkUnique mimics the nick and email UNIQUE constraints of the users table
+func (repository memoryUsersRepository) checkUnique(ID uint64, user models.User) error {
+	for _, stored := range repository.data.users {
+		if stored.ID == ID {
+			continue
+		}
+
+		if stored.Nick == user.Nick {
+			return errNickTaken
+		}
+
+		if stored.Email == user.Email {
+			return errEmailTaken
+		}
+	}
+
+	return nil
+}
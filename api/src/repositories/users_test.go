@@ -0,0 +1,101 @@
+/*
+Copyright 2022 Danilo S. Lopes.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at:
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package repositories
+
+import (
+	"api/src/models"
+	"api/src/pagination"
+	"context"
+	"testing"
+)
+
+func TestUsers(t *testing.T) {
+	forEachEngine(t, func(t *testing.T, store Store) {
+		ctx := context.Background()
+		first := createUser(t, store, "first")
+		second := createUser(t, store, "second")
+
+		_, erro := store.Users.Create(ctx, models.User{Name: "other", Nick: "first", Email: "other@example.com", Pass: "hash"})
+		wantKind(t, erro, models.ErrConflict)
+
+		_, erro = store.Users.Create(ctx, models.User{Name: "other", Nick: "other", Email: "first@example.com", Pass: "hash"})
+		wantKind(t, erro, models.ErrConflict)
+
+		user, erro := store.Users.SearchByID(ctx, first)
+		if erro != nil {
+			t.Fatal(erro)
+		}
+		if user.Nick != "first" || user.Pass != "" {
+			t.Fatalf("got user %+v, want the nick first without the password", user)
+		}
+
+		_, erro = store.Users.SearchByID(ctx, second+100)
+		wantKind(t, erro, models.ErrNotFound)
+
+		if erro := store.Users.Update(ctx, first, models.User{Name: "renamed", Nick: "first", Email: "first@example.com"}); erro != nil {
+			t.Fatal(erro)
+		}
+		wantKind(t, store.Users.Update(ctx, second+100, models.User{Name: "x", Nick: "x", Email: "x@example.com"}), models.ErrNotFound)
+
+		found, erro := store.Users.Search(ctx, "renamed", pagination.Page{Limit: 10})
+		if erro != nil {
+			t.Fatal(erro)
+		}
+		if len(found) != 1 || found[0].ID != first {
+			t.Fatalf("searching renamed got %+v, want the user %d", found, first)
+		}
+
+		if erro := store.Users.Follow(ctx, first, second); erro != nil {
+			t.Fatal(erro)
+		}
+		if erro := store.Users.Follow(ctx, first, second); erro != nil {
+			t.Fatalf("following twice: %v", erro)
+		}
+		wantKind(t, store.Users.Follow(ctx, second+100, second), models.ErrNotFound)
+
+		followers, erro := store.Users.GetFollowers(ctx, first, pagination.Page{Limit: 10})
+		if erro != nil {
+			t.Fatal(erro)
+		}
+		if len(followers) != 1 || followers[0].ID != second {
+			t.Fatalf("got followers %+v, want the user %d", followers, second)
+		}
+
+		following, erro := store.Users.GetFollowing(ctx, second, pagination.Page{Limit: 10})
+		if erro != nil {
+			t.Fatal(erro)
+		}
+		if len(following) != 1 || following[0].ID != first {
+			t.Fatalf("got following %+v, want the user %d", following, first)
+		}
+
+		if erro := store.Users.Delete(ctx, first); erro != nil {
+			t.Fatal(erro)
+		}
+		_, erro = store.Users.SearchByID(ctx, first)
+		wantKind(t, erro, models.ErrNotFound)
+		wantKind(t, store.Users.Delete(ctx, first), models.ErrNotFound)
+
+		following, erro = store.Users.GetFollowing(ctx, second, pagination.Page{Limit: 10})
+		if erro != nil {
+			t.Fatal(erro)
+		}
+		if len(following) != 0 {
+			t.Fatalf("got following %+v once the user deleted, want none", following)
+		}
+	})
+}
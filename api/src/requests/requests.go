@@ -0,0 +1,122 @@
+/*
+Copyright 2022 Danilo S. Lopes.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at:
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package requests
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"slices"
+	"sort"
+	"strings"
+)
+
+var (
+	// ErrEmptyBody is returned for a request with no body
+	ErrEmptyBody = errors.New("the body is empty")
+
+	// ErrNotObject is returned for a body holding another JSON value than an object
+	ErrNotObject = errors.New("the body must be a JSON object")
+
+	// ErrTrailingData is returned for a body holding anything after its JSON value
+	ErrTrailingData = errors.New("the body must hold a single JSON value")
+)
+
+// UnknownFieldError is returned for a body member the route does not accept
+type UnknownFieldError struct {
+	Field string
+}
+
+func (erro *UnknownFieldError) Error() string {
+	return fmt.Sprintf("the field %q is not accepted", erro.Field)
+}
+
+type fieldsKey struct{}
+
+// WithFields returns a copy of ctx telling Decode the only members the body may hold
+func WithFields(ctx context.Context, fields []string) context.Context {
+	return context.WithValue(ctx, fieldsKey{}, fields)
+}
+
+// JSON tells whether header announces a JSON body, encoded in UTF-8 when its charset is given
+func JSON(header http.Header) bool {
+	mediaType, params, erro := mime.ParseMediaType(header.Get("Content-Type"))
+	if erro != nil || mediaType != "application/json" {
+		return false
+	}
+
+	charset, given := params["charset"]
+	return !given || strings.EqualFold(charset, "utf-8")
+}
+
+// Decode reads the JSON object in the body of r into target. The object must be the only value of the body
+// and hold the members accepted by the route, see WithFields, so a client cannot set the fields it is not
+// allowed to write (e.g. the id or the likes of a publication). Without them any field of target is accepted.
+func Decode(r *http.Request, target interface{}) error {
+	decoder := json.NewDecoder(r.Body)
+
+	var members map[string]json.RawMessage
+	if erro := decoder.Decode(&members); erro != nil {
+		var typeErro *json.UnmarshalTypeError
+		switch {
+		case errors.Is(erro, io.EOF):
+			return ErrEmptyBody
+		case errors.As(erro, &typeErro):
+			return ErrNotObject
+		}
+		return erro
+	}
+
+	// null decodes into a nil map without error
+	if members == nil {
+		return ErrNotObject
+	}
+
+	if _, erro := decoder.Token(); erro != io.EOF {
+		return ErrTrailingData
+	}
+
+	if fields, restricted := r.Context().Value(fieldsKey{}).([]string); restricted {
+		names := make([]string, 0, len(members))
+		for name := range members {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+
+		// the names are compared as they are, encoding/json would match "ID" with the id field
+		for _, name := range names {
+			if !slices.Contains(fields, name) {
+				return &UnknownFieldError{name}
+			}
+		}
+	}
+
+	content, erro := json.Marshal(members)
+	if erro != nil {
+		return erro
+	}
+
+	strict := json.NewDecoder(bytes.NewReader(content))
+	strict.DisallowUnknownFields()
+
+	return strict.Decode(target)
+}
@@ -17,36 +17,162 @@ limitations under the License.
 package responses
 
 import (
-	"api/src/prommetrics"
+	"api/src/models"
 	"encoding/json"
-	"log"
+	"errors"
+	"fmt"
 	"net/http"
-	"strconv"
-	"time"
+	"strings"
 )
 
-// JSON returns json response
-func JSON(startedTime time.Time, w http.ResponseWriter, statusCode int, data interface{}) {
-	w.Header().Set("Content-Type", "application/json")
+// JSON returns json response. Encoding and write failures are recorded for the access log, see
+// errorRecorder, and a body that cannot be encoded is replaced by a 500.
+func JSON(w http.ResponseWriter, statusCode int, data interface{}) {
+	write(w, statusCode, "application/json", data)
+}
+
+// write sends data encoded as the body of a contentType response
+func write(w http.ResponseWriter, statusCode int, contentType string, data interface{}) {
+	w.Header().Set("Content-Type", contentType)
+
+	if data == nil {
+		w.WriteHeader(statusCode)
+		return
+	}
+
+	body, erro := json.Marshal(data)
+	if erro != nil {
+		recordError(w, fmt.Errorf("encoding the response: %w", erro))
+		statusCode = http.StatusInternalServerError
+		body = []byte(`{"type":"about:blank","title":"Internal Server Error","status":500,"code":"internal"}`)
+		w.Header().Set("Content-Type", "application/problem+json")
+	}
+
 	w.WriteHeader(statusCode)
+	if _, erro := w.Write(append(body, '\n')); erro != nil {
+		recordError(w, fmt.Errorf("writing the response: %w", erro))
+	}
+}
 
-	if data != nil {
-		prommetrics.PromRequestsDuration.Observe(time.Since(startedTime).Seconds())
-		prommetrics.PromRequestsCurrent.Dec()
-		prommetrics.PromRequestStatus.WithLabelValues(strconv.Itoa(statusCode)).Inc()
-		if erro := json.NewEncoder(w).Encode(data); erro != nil {
-			log.Fatal(erro)
-		}
+// errorRecorder is implemented by the response writers that log the error sent to the client
+type errorRecorder interface {
+	RecordError(erro error)
+}
+
+// recordError hands erro to the access log when w records errors
+func recordError(w http.ResponseWriter, erro error) {
+	if recorder, ok := w.(errorRecorder); ok {
+		recorder.RecordError(erro)
+	}
+}
+
+// RequestIDHeader carries the request ID, accepted from the client and always returned in the response
+const RequestIDHeader = "X-Request-ID"
+
+// Codes of the problems answered by the API, the domain errors carrying their own, see models.Error.
+// The clients can rely on them, unlike on the detail text. The codes of the statuses with no
+// specific one are derived from the status, e.g. "not_found".
+const (
+	CodeInternal           = "internal"
+	CodeShuttingDown       = "api.shutting_down"
+	CodeRateLimited        = "api.rate_limited"
+	CodeInvalidRequest     = "request.invalid"
+	CodeInvalidParameter   = "request.invalid_parameter"
+	CodeInvalidCursor      = "request.invalid_cursor"
+	CodeInvalidBody        = "request.invalid_body"
+	CodeUnreadableBody     = "request.unreadable_body"
+	CodeUnknownField       = "request.unknown_field"
+	CodeBodyTooLarge       = "request.body_too_large"
+	CodeUnsupportedMedia   = "request.unsupported_media_type"
+	CodeInvalidIdempotency = "request.invalid_idempotency_key"
+	CodeIdempotencyReused  = "request.idempotency_key_reused"
+	CodeIdempotencyPending = "request.idempotency_key_in_progress"
+	CodeUndocumented       = "request.undocumented"
+	CodeInvalidResponse    = "response.invalid"
+	CodeInvalidToken       = "auth.invalid_token"
+	CodeInvalidCredentials = "auth.invalid_credentials"
+	CodeUserDisabled       = "auth.user_disabled"
+	CodeUserWrongPassword  = "user.wrong_password"
+)
+
+// Statuses answered for each kind of domain error
+var kindStatuses = map[error]int{
+	models.ErrNotFound:   http.StatusNotFound,
+	models.ErrConflict:   http.StatusConflict,
+	models.ErrForbidden:  http.StatusForbidden,
+	models.ErrValidation: http.StatusUnprocessableEntity,
+}
+
+// ProblemDetails is the RFC 7807 body of the error responses. Its type is always "about:blank",
+// the problem being told apart by the code member.
+type ProblemDetails struct {
+	Type      string       `json:"type" example:"about:blank"`
+	Title     string       `json:"title" example:"Bad Request"`
+	Status    int          `json:"status" example:"400"`
+	Code      string       `json:"code" example:"publication.invalid"`
+	Detail    string       `json:"detail,omitempty" example:"the title cant be empty"`
+	RequestID string       `json:"request_id,omitempty" example:"945ce9a484a2dbcf83a99a1fd8168420"`
+	Errors    []FieldError `json:"errors,omitempty"`
+}
+
+// FieldError describes why one field of a request, or of a response, is invalid
+type FieldError struct {
+	In      string `json:"in" example:"body"`
+	Field   string `json:"field,omitempty" example:"email"`
+	Message string `json:"message" example:"property \"email\" is missing"`
+}
+
+// Erro returns an application/problem+json response with the code of the status
+func Erro(w http.ResponseWriter, statusCode int, erro error) {
+	InvalidFields(w, statusCode, "", erro, nil)
+}
+
+// Fail returns the problem of erro: the status of its kind, its code and its invalid field for the domain errors,
+// a 500 for any other error
+func Fail(w http.ResponseWriter, erro error) {
+	var domainErro *models.Error
+	if !errors.As(erro, &domainErro) {
+		Erro(w, http.StatusInternalServerError, erro)
+		return
 	}
+
+	var fields []FieldError
+	if domainErro.Field != "" {
+		fields = []FieldError{{In: "body", Field: domainErro.Field, Message: domainErro.Message}}
+	}
+
+	InvalidFields(w, kindStatuses[domainErro.Kind], domainErro.Code, erro, fields)
 }
 
-// Erro returns an error json response
-func Erro(startedTime time.Time, w http.ResponseWriter, statusCode int, erro error) {
-	prommetrics.PromClientErrors.Inc()
+// Problem returns an application/problem+json response identified by code
+func Problem(w http.ResponseWriter, statusCode int, code string, erro error) {
+	InvalidFields(w, statusCode, code, erro, nil)
+}
+
+// InvalidFields returns an application/problem+json response listing the invalid fields.
+// erro goes to the access log, the clients only get its text as detail of the 4xx responses.
+func InvalidFields(w http.ResponseWriter, statusCode int, code string, erro error, fields []FieldError) {
+	recordError(w, erro)
+
+	problem := ProblemDetails{
+		Type:      "about:blank",
+		Title:     http.StatusText(statusCode),
+		Status:    statusCode,
+		Code:      code,
+		RequestID: w.Header().Get(RequestIDHeader),
+		Errors:    fields,
+	}
+
+	if problem.Code == "" {
+		problem.Code = strings.ReplaceAll(strings.ToLower(problem.Title), " ", "_")
+		if statusCode == http.StatusInternalServerError {
+			problem.Code = CodeInternal
+		}
+	}
+
+	if statusCode < http.StatusInternalServerError {
+		problem.Detail = erro.Error()
+	}
 
-	JSON(startedTime, w, statusCode, struct {
-		Erro string `json:"erro"`
-	}{
-		Erro: erro.Error(),
-	})
+	write(w, statusCode, "application/problem+json", problem)
 }
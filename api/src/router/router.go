@@ -23,8 +23,8 @@ import (
 )
 
 // Generate generate all API routes configured.
-func Generate() *mux.Router {
+func Generate(options routes.Options) *mux.Router {
 	r := mux.NewRouter()
 
-	return routes.Configure(r)
+	return routes.Configure(r, options)
 }
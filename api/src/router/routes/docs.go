@@ -0,0 +1,39 @@
+/*
+Copyright 2022 Danilo S. Lopes.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at:
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package routes
+
+import (
+	"api/src/swagger"
+	"net/http"
+)
+
+func docsRoutes() []PromRoute {
+	return []PromRoute{
+		{
+			URI:                    "/openapi.json",
+			Method:                 http.MethodGet,
+			Function:               swagger.SpecHandler(),
+			AuthenticationRequired: false,
+		},
+		{
+			URI:                    "/docs",
+			Method:                 http.MethodGet,
+			Function:               swagger.DocsHandler(),
+			AuthenticationRequired: false,
+		},
+	}
+}
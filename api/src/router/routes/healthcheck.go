@@ -21,17 +21,28 @@ import (
 	"net/http"
 )
 
-var healthcheckRoutes = []Route{
-	{
-		URI:                    "/live",
-		Method:                 http.MethodGet,
-		Function:               controllers.Live,
-		AuthenticationRequired: false,
-	},
-	{
-		URI:                    "/ready",
-		Method:                 http.MethodGet,
-		Function:               controllers.Ready,
-		AuthenticationRequired: false,
-	},
+func healthcheckRoutes(controller *controllers.Controller) []Route {
+	return []Route{
+		{
+			URI:                    "/live",
+			Method:                 http.MethodGet,
+			Function:               controller.Live,
+			AuthenticationRequired: false,
+			Unlimited:              true,
+			Summary:                "Application Liveness",
+			Description:            "Endpoint used to check if application can process the requests received",
+			Tags:                   []string{"HealthChecks"},
+		},
+		{
+			URI:                    "/ready",
+			Method:                 http.MethodGet,
+			Function:               controller.Ready,
+			AuthenticationRequired: false,
+			Unlimited:              true,
+			Summary:                "Application Readiness",
+			Description:            "Endpoint used to check if application is ready to receive network connection and provide his functionality",
+			Tags:                   []string{"HealthChecks"},
+			Errors:                 []int{http.StatusServiceUnavailable},
+		},
+	}
 }
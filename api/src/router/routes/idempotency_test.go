@@ -0,0 +1,119 @@
+/*
+Copyright 2022 Danilo S. Lopes.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at:
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package routes
+
+import (
+	"api/src/authentication"
+	"api/src/config"
+	"api/src/middlewares"
+	"api/src/models"
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+// sendWithKey sends a request along an Idempotency-Key, authenticated as userID when not zero
+func sendWithKey(t *testing.T, router http.Handler, method, target, body, key string, userID uint64) *httptest.ResponseRecorder {
+	t.Helper()
+
+	request := httptest.NewRequest(method, target, strings.NewReader(body))
+	request.Header.Set("Content-Type", "application/json")
+	request.Header.Set(middlewares.IdempotencyKeyHeader, key)
+	if userID != 0 {
+		token, erro := authentication.GenerateToken(secretKey, userID)
+		if erro != nil {
+			t.Fatal(erro)
+		}
+		request.Header.Set("Authorization", "Bearer "+token)
+	}
+
+	response := httptest.NewRecorder()
+	router.ServeHTTP(response, request)
+
+	return response
+}
+
+// The retries replay the first response, the anonymous clients sending the same key along another request
+// getting their own response
+func TestIdempotentRoutes(t *testing.T) {
+	router, _ := newValidatedRouter(t)
+
+	user := `{"name":"user","nick":"user","email":"user@example.com","pass":"secret"}`
+	first := sendWithKey(t, router, http.MethodPost, "/v2/users", user, "key", 0)
+	if first.Code != http.StatusCreated {
+		t.Fatalf("creating the user got %d, want 201: %s", first.Code, first.Body)
+	}
+	if strings.Contains(first.Body.String(), `"pass"`) {
+		t.Fatalf("the created user holds its password: %s", first.Body)
+	}
+
+	retried := sendWithKey(t, router, http.MethodPost, "/v2/users", user, "key", 0)
+	if retried.Code != http.StatusCreated || retried.Header().Get(middlewares.IdempotentReplayedHeader) != "true" || retried.Body.String() != first.Body.String() {
+		t.Fatalf("the retried registration got %d replayed %q: %s, want the first response replayed",
+			retried.Code, retried.Header().Get(middlewares.IdempotentReplayedHeader), retried.Body)
+	}
+
+	other := sendWithKey(t, router, http.MethodPost, "/v2/users", strings.Replace(user, "user@", "other@", 1), "key", 0)
+	if other.Code != http.StatusConflict || other.Header().Get(middlewares.IdempotentReplayedHeader) != "" {
+		t.Fatalf("another anonymous client sending the same key got %d replayed %q, want a 409 of its own",
+			other.Code, other.Header().Get(middlewares.IdempotentReplayedHeader))
+	}
+
+	publication := `{"title":"title","content":"content"}`
+	created := sendWithKey(t, router, http.MethodPost, "/v2/publications", publication, "key", 1)
+	if created.Code != http.StatusCreated {
+		t.Fatalf("creating the publication got %d, want 201: %s", created.Code, created.Body)
+	}
+
+	retry := sendWithKey(t, router, http.MethodPost, "/v2/publications", publication, "key", 1)
+	if retry.Code != http.StatusCreated || retry.Header().Get(middlewares.IdempotentReplayedHeader) != "true" || retry.Body.String() != created.Body.String() {
+		t.Fatalf("the retry got %d replayed %q: %s, want the first response replayed",
+			retry.Code, retry.Header().Get(middlewares.IdempotentReplayedHeader), retry.Body)
+	}
+}
+
+// A legacy alias and its versioned route are the same operation, sharing the keys
+func TestIdempotentLegacyAlias(t *testing.T) {
+	router, store := newRouter(t, func(options *Options) {
+		options.LegacyRoutes = config.LegacyRoutes{Enabled: true, Sunset: time.Now().Add(time.Hour)}
+	})
+
+	userID, erro := store.Users.Create(context.Background(), models.User{Name: "user", Nick: "user", Email: "user@example.com", Pass: "hash"})
+	if erro != nil {
+		t.Fatal(erro)
+	}
+
+	publication := `{"title":"title","content":"content"}`
+	created := sendWithKey(t, router, http.MethodPost, "/publications", publication, "key", userID)
+	if created.Code != http.StatusCreated {
+		t.Fatalf("creating the publication got %d, want 201: %s", created.Code, created.Body)
+	}
+
+	retry := sendWithKey(t, router, http.MethodPost, "/v1/publications", publication, "key", userID)
+	if retry.Code != http.StatusCreated || retry.Header().Get(middlewares.IdempotentReplayedHeader) != "true" {
+		t.Fatalf("the retry on the versioned route got %d replayed %q: %s, want the first response replayed",
+			retry.Code, retry.Header().Get(middlewares.IdempotentReplayedHeader), retry.Body)
+	}
+
+	other := sendWithKey(t, router, http.MethodPost, "/v2/publications", publication, "key", userID)
+	if other.Code != http.StatusUnprocessableEntity {
+		t.Fatalf("the key sent to another version got %d, want 422", other.Code)
+	}
+}
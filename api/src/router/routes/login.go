@@ -18,12 +18,22 @@ package routes
 
 import (
 	"api/src/controllers"
+	"api/src/models"
 	"net/http"
 )
 
-var loginRoute = Route{
-	URI:                    "/login",
-	Method:                 http.MethodPost,
-	Function:               controllers.Login,
-	AuthenticationRequired: false,
+func loginRoute(controller *controllers.Controller) Route {
+	return Route{
+		URI:                    "/login",
+		Method:                 http.MethodPost,
+		Function:               controller.Login,
+		AuthenticationRequired: false,
+		Summary:                "Login",
+		Description:            "Endpoint used to login into application, answering the token to send as bearer",
+		Tags:                   []string{"Login"},
+		Request:                models.User{},
+		RequestFields:          []string{"email", "pass"},
+		Response:               "",
+		Errors:                 []int{http.StatusBadRequest, http.StatusUnauthorized, http.StatusForbidden, http.StatusUnprocessableEntity},
+	}
 }
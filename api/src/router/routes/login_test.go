@@ -0,0 +1,90 @@
+/*
+Copyright 2022 Danilo S. Lopes.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at:
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package routes
+
+import (
+	"api/src/models"
+	"api/src/responses"
+	"api/src/security"
+	"context"
+	"net/http"
+	"testing"
+)
+
+// Only the password of a registered user logs in, even the one of the hash compared for the unknown emails
+func TestLogin(t *testing.T) {
+	router, store := newValidatedRouter(t)
+
+	hash, erro := security.Hash("secret")
+	if erro != nil {
+		t.Fatal(erro)
+	}
+	if _, erro := store.Users.Create(context.Background(), models.User{Name: "user", Nick: "user", Email: "user@example.com", Pass: string(hash)}); erro != nil {
+		t.Fatal(erro)
+	}
+
+	tests := []struct {
+		name, body string
+		status     int
+	}{
+		{"registered user", `{"email":"user@example.com","pass":"secret"}`, http.StatusOK},
+		{"wrong password", `{"email":"user@example.com","pass":"wrong"}`, http.StatusUnauthorized},
+		{"unknown email", `{"email":"nobody@example.com","pass":"secret"}`, http.StatusUnauthorized},
+		{"unknown email with the password of the unknown user hash", `{"email":"nobody@example.com","pass":"sm-unknown-user"}`, http.StatusUnauthorized},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if response, _ := serve(t, router, http.MethodPost, "/v2/login", test.body, 0); response.Code != test.status {
+				t.Fatalf("got status %d, want %d: %s", response.Code, test.status, response.Body)
+			}
+		})
+	}
+}
+
+// The tokens issued before a user was disabled or deleted stop working
+func TestTokensOfDisabledUsers(t *testing.T) {
+	router, store := newValidatedRouter(t)
+	ctx := context.Background()
+
+	disabled, erro := store.Users.Create(ctx, models.User{Name: "disabled", Nick: "disabled", Email: "disabled@example.com", Pass: "hash"})
+	if erro != nil {
+		t.Fatal(erro)
+	}
+	deleted, erro := store.Users.Create(ctx, models.User{Name: "deleted", Nick: "deleted", Email: "deleted@example.com", Pass: "hash"})
+	if erro != nil {
+		t.Fatal(erro)
+	}
+
+	if response, _ := serve(t, router, http.MethodGet, "/v2/publications", "", disabled); response.Code != http.StatusOK {
+		t.Fatalf("the token of an enabled user got %d, want 200", response.Code)
+	}
+
+	if erro := store.Users.Disable(ctx, disabled); erro != nil {
+		t.Fatal(erro)
+	}
+	if erro := store.Users.Delete(ctx, deleted); erro != nil {
+		t.Fatal(erro)
+	}
+
+	for _, userID := range []uint64{disabled, deleted} {
+		response, problem := serve(t, router, http.MethodGet, "/v2/publications", "", userID)
+		if response.Code != http.StatusUnauthorized || problem.Code != responses.CodeInvalidToken {
+			t.Fatalf("the token of the user %d got %d %s, want a 401 %s", userID, response.Code, problem.Code, responses.CodeInvalidToken)
+		}
+	}
+}
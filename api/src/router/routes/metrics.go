@@ -19,12 +19,15 @@ package routes
 import (
 	"net/http"
 
+	"github.com/prometheus/client_golang/prometheus"
 	"github.com/prometheus/client_golang/prometheus/promhttp"
 )
 
-var metricsRoute = PromRoute{
-	URI:                    "/metrics",
-	Method:                 http.MethodGet,
-	Function:               promhttp.Handler(),
-	AuthenticationRequired: false,
+func metricsRoute(gatherer prometheus.Gatherer) PromRoute {
+	return PromRoute{
+		URI:                    "/metrics",
+		Method:                 http.MethodGet,
+		Function:               promhttp.HandlerFor(gatherer, promhttp.HandlerOpts{}),
+		AuthenticationRequired: false,
+	}
 }
@@ -0,0 +1,337 @@
+/*
+Copyright 2022 Danilo S. Lopes.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at:
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package routes
+
+import (
+	"api/src/responses"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"reflect"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/getkin/kin-openapi/openapi3"
+)
+
+// Name of the security scheme required by the routes with AuthenticationRequired
+const bearerAuth = "bearerAuth"
+
+// Descriptions of the path parameters, by name
+var pathParameters = map[string]string{
+	"userID":        "The user id",
+	"publicationID": "The publication id",
+}
+
+var pathParameter = regexp.MustCompile(`{(\w+)}`)
+
+// Package paths in the names of the generic types, e.g. "api/src/models." in "Page[api/src/models.User]"
+var packagePath = regexp.MustCompile(`[\w/]+\.`)
+
+// OpenAPI generates the OpenAPI document of the API out of its route table and the models
+func OpenAPI() ([]byte, error) {
+	document := &openapi3.T{
+		OpenAPI: "3.0.1",
+		Info: &openapi3.Info{
+			Title:       "Social Media API Service",
+			Description: "Social Media API Documentation",
+			Contact:     &openapi3.Contact{Email: "lopesd.dan@gmail.com"},
+			License: &openapi3.License{
+				Name: "Apache License 2.0",
+				URL:  "https://github.com/danilo-lopes/socialmedia/blob/main/LICENSE",
+			},
+			Version: "2.0.0",
+		},
+		Servers: openapi3.Servers{{URL: "/"}},
+		Paths:   openapi3.NewPaths(),
+		Components: &openapi3.Components{
+			Schemas: openapi3.Schemas{},
+			SecuritySchemes: openapi3.SecuritySchemes{
+				bearerAuth: &openapi3.SecuritySchemeRef{Value: openapi3.NewJWTSecurityScheme()},
+			},
+		},
+	}
+
+	schemas := schemaGenerator{document.Components.Schemas}
+
+	tags := map[string]bool{}
+	for _, route := range apiRoutes(nil) {
+		for _, tag := range route.Tags {
+			if !tags[tag] {
+				tags[tag] = true
+				document.Tags = append(document.Tags, &openapi3.Tag{Name: tag})
+			}
+		}
+
+		operation, erro := schemas.operation(route)
+		if erro != nil {
+			return nil, fmt.Errorf("%s %s: %w", route.Method, route.URI, erro)
+		}
+
+		document.AddOperation(route.URI, route.Method, operation)
+	}
+
+	if erro := document.Validate(context.Background()); erro != nil {
+		return nil, erro
+	}
+
+	body, erro := json.MarshalIndent(document, "", "  ")
+	if erro != nil {
+		return nil, erro
+	}
+
+	return append(body, '\n'), nil
+}
+
+// schemaGenerator converts the models into schemas, registering the structs as components
+type schemaGenerator struct {
+	components openapi3.Schemas
+}
+
+// operation documents one route
+func (schemas schemaGenerator) operation(route Route) (*openapi3.Operation, error) {
+	name := handlerName(route.Function)
+	name = name[strings.LastIndex(name, ".")+1:]
+	if route.Version > 0 && !route.Deprecated {
+		name += fmt.Sprintf("V%d", route.Version)
+	}
+
+	operation := &openapi3.Operation{
+		Tags:        route.Tags,
+		Summary:     route.Summary,
+		Description: route.Description,
+		OperationID: name,
+		Deprecated:  route.Deprecated,
+		Responses:   openapi3.NewResponsesWithCapacity(len(route.Errors) + 5),
+	}
+
+	if route.Deprecated {
+		operation.Description += fmt.Sprintf(". Deprecated alias of %s%s, answered with the Deprecation and Sunset headers", versions[0].Prefix, route.URI)
+	}
+
+	for _, match := range pathParameter.FindAllStringSubmatch(route.URI, -1) {
+		operation.AddParameter(openapi3.NewPathParameter(match[1]).
+			WithDescription(pathParameters[match[1]]).
+			WithSchema(openapi3.NewIntegerSchema().WithFormat("uint64")))
+	}
+
+	var queries []string
+	for query := range route.Query {
+		queries = append(queries, query)
+	}
+	sort.Strings(queries)
+	for _, query := range queries {
+		operation.AddParameter(openapi3.NewQueryParameter(query).
+			WithDescription(route.Query[query]).
+			WithSchema(openapi3.NewStringSchema()))
+	}
+
+	if route.Paginated {
+		operation.AddParameter(openapi3.NewQueryParameter("limit").
+			WithDescription("Items of the page, up to PAGINATION_MAX_LIMIT").
+			WithSchema(openapi3.NewIntegerSchema().WithMin(1)))
+		operation.AddParameter(openapi3.NewQueryParameter("cursor").
+			WithDescription("Position of the page, taken from the URLs of the Link header").
+			WithSchema(openapi3.NewStringSchema()))
+	}
+
+	if route.Idempotent {
+		operation.AddParameter(openapi3.NewHeaderParameter("Idempotency-Key").
+			WithDescription("Unique key of the request (e.g. a UUID), so its retries get the response of the first one instead of repeating it").
+			WithSchema(openapi3.NewStringSchema().WithMaxLength(255)))
+	}
+
+	if route.Request != nil {
+		body, erro := schemas.requestBody(reflect.TypeOf(route.Request), route.RequestFields)
+		if erro != nil {
+			return nil, erro
+		}
+
+		operation.RequestBody = &openapi3.RequestBodyRef{
+			Value: openapi3.NewRequestBody().WithRequired(true).WithJSONSchema(body),
+		}
+	}
+
+	if route.Revalidable() {
+		operation.AddParameter(openapi3.NewHeaderParameter("If-None-Match").
+			WithDescription("ETag of the response the client holds, answered with a 304 when it is still current").
+			WithSchema(openapi3.NewStringSchema()))
+		operation.AddParameter(openapi3.NewHeaderParameter("If-Modified-Since").
+			WithDescription("Last-Modified of the response the client holds, ignored along with If-None-Match").
+			WithSchema(openapi3.NewStringSchema()))
+	}
+
+	if route.AuthenticationRequired {
+		operation.Security = &openapi3.SecurityRequirements{openapi3.NewSecurityRequirement().Authenticate(bearerAuth)}
+	}
+
+	status := route.Status
+	if status == 0 {
+		status = http.StatusOK
+	}
+
+	success := openapi3.NewResponse().WithDescription(http.StatusText(status))
+	if route.Response != nil {
+		responseType := reflect.TypeOf(route.Response)
+		if responseType.Kind() == reflect.String {
+			success.WithContent(openapi3.NewContentWithSchema(openapi3.NewStringSchema(), []string{"text/plain"}))
+		} else {
+			success.WithJSONSchemaRef(schemas.schema(responseType))
+		}
+	}
+	if route.Paginated {
+		success.Headers = openapi3.Headers{"Link": &openapi3.HeaderRef{Value: &openapi3.Header{Parameter: openapi3.Parameter{
+			Description: `URLs of the previous and next pages, if any, e.g. </publications?cursor=...&limit=20>; rel="next"`,
+			Schema:      openapi3.NewStringSchema().NewRef(),
+		}}}}
+	}
+	if route.Idempotent {
+		success.Headers = openapi3.Headers{"Idempotent-Replayed": &openapi3.HeaderRef{Value: &openapi3.Header{Parameter: openapi3.Parameter{
+			Description: "Present, set to true, on the response replayed to a retry",
+			Schema:      openapi3.NewStringSchema().NewRef(),
+		}}}}
+	}
+	operation.AddResponse(status, success)
+
+	if route.Revalidable() {
+		operation.AddResponse(http.StatusNotModified, openapi3.NewResponse().WithDescription(http.StatusText(http.StatusNotModified)))
+	}
+
+	failures := append([]int{http.StatusInternalServerError}, route.Errors...)
+	if route.AuthenticationRequired {
+		failures = append(failures, http.StatusUnauthorized)
+	}
+	if !route.Unlimited {
+		failures = append(failures, http.StatusTooManyRequests)
+	}
+	if route.Request != nil {
+		failures = append(failures, http.StatusRequestEntityTooLarge, http.StatusUnsupportedMediaType)
+	}
+	if route.Idempotent {
+		failures = append(failures, http.StatusConflict, http.StatusUnprocessableEntity)
+	}
+	problem := openapi3.NewContentWithSchemaRef(schemas.schema(reflect.TypeOf(responses.ProblemDetails{})), []string{"application/problem+json"})
+	for _, status := range failures {
+		operation.AddResponse(status, openapi3.NewResponse().WithDescription(http.StatusText(status)).WithContent(problem))
+	}
+
+	return operation, nil
+}
+
+// requestBody returns an inline schema holding the fields of model read by a route, all of them required
+// and no other allowed
+func (schemas schemaGenerator) requestBody(model reflect.Type, fields []string) (*openapi3.Schema, error) {
+	properties := schemas.object(model).Value.Properties
+
+	body := openapi3.NewObjectSchema()
+	for _, field := range fields {
+		property, exists := properties[field]
+		if !exists {
+			return nil, fmt.Errorf("%s has no field %q", model.Name(), field)
+		}
+
+		body.WithPropertyRef(field, property)
+	}
+	body.Required = append([]string{}, fields...)
+	body.AdditionalProperties = openapi3.AdditionalProperties{Has: openapi3.BoolPtr(false)}
+	sort.Strings(body.Required)
+
+	return body, nil
+}
+
+// schema returns the schema of a Go type, a reference to a component for the structs
+func (schemas schemaGenerator) schema(goType reflect.Type) *openapi3.SchemaRef {
+	switch {
+	case goType == reflect.TypeOf(time.Time{}):
+		return openapi3.NewSchemaRef("", openapi3.NewDateTimeSchema())
+
+	case goType.Kind() == reflect.Struct:
+		name := componentName(goType)
+		if _, exists := schemas.components[name]; !exists {
+			schemas.components[name] = schemas.object(goType)
+		}
+
+		return openapi3.NewSchemaRef("#/components/schemas/"+name, schemas.components[name].Value)
+
+	case goType.Kind() == reflect.Slice:
+		array := openapi3.NewArraySchema()
+		array.Items = schemas.schema(goType.Elem())
+
+		return openapi3.NewSchemaRef("", array)
+
+	case goType.Kind() == reflect.Bool:
+		return openapi3.NewSchemaRef("", openapi3.NewBoolSchema())
+
+	case goType.Kind() == reflect.Int || goType.Kind() == reflect.Uint:
+		return openapi3.NewSchemaRef("", openapi3.NewIntegerSchema())
+
+	case goType.Kind() >= reflect.Int8 && goType.Kind() <= reflect.Uint64:
+		return openapi3.NewSchemaRef("", openapi3.NewIntegerSchema().WithFormat(goType.Kind().String()))
+
+	case goType.Kind() == reflect.Float32 || goType.Kind() == reflect.Float64:
+		return openapi3.NewSchemaRef("", openapi3.NewFloat64Schema())
+
+	default:
+		return openapi3.NewSchemaRef("", openapi3.NewStringSchema())
+	}
+}
+
+// componentName returns the name of the component of a struct, the generic ones named after their
+// type arguments, e.g. "UserPage" for models.Page[models.User]
+func componentName(goType reflect.Type) string {
+	name, arguments, generic := strings.Cut(packagePath.ReplaceAllString(goType.Name(), ""), "[")
+	if !generic {
+		return name
+	}
+
+	return strings.NewReplacer(",", "", "]", "").Replace(arguments) + name
+}
+
+// object returns the schema of a struct out of its json tags, its examples taken from the example tags
+func (schemas schemaGenerator) object(goType reflect.Type) *openapi3.SchemaRef {
+	object := openapi3.NewObjectSchema()
+
+	for index := 0; index < goType.NumField(); index++ {
+		field := goType.Field(index)
+
+		name, _, _ := strings.Cut(field.Tag.Get("json"), ",")
+		if name == "-" || !field.IsExported() {
+			continue
+		}
+		if name == "" {
+			name = field.Name
+		}
+
+		property := schemas.schema(field.Type)
+		if example, exists := field.Tag.Lookup("example"); exists && property.Ref == "" {
+			value := *property.Value
+			value.Example = example
+			if value.Type == openapi3.TypeInteger {
+				value.Example, _ = strconv.ParseInt(example, 10, 64)
+			}
+			property = openapi3.NewSchemaRef("", &value)
+		}
+
+		object.WithPropertyRef(name, property)
+	}
+
+	return openapi3.NewSchemaRef("", object)
+}
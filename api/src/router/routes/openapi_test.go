@@ -0,0 +1,64 @@
+/*
+Copyright 2022 Danilo S. Lopes.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at:
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package routes
+
+import (
+	"api/src/swagger"
+	"bytes"
+	"strings"
+	"testing"
+)
+
+// The document built into sm must be the one generated out of the route table
+func TestOpenAPIUpToDate(t *testing.T) {
+	generated, erro := OpenAPI()
+	if erro != nil {
+		t.Fatalf("generating the document: %v", erro)
+	}
+
+	committed := swagger.Spec()
+	if bytes.Equal(generated, committed) {
+		return
+	}
+
+	t.Fatalf("src/swagger/openapi.json is out of date with the routes, run: sm openapi --output src/swagger/openapi.json\n%s",
+		diff(string(committed), string(generated)))
+}
+
+// diff returns the lines around the first difference between the committed and the generated documents
+func diff(committed, generated string) string {
+	committedLines, generatedLines := strings.Split(committed, "\n"), strings.Split(generated, "\n")
+
+	first := 0
+	for first < len(committedLines) && first < len(generatedLines) && committedLines[first] == generatedLines[first] {
+		first++
+	}
+
+	var report strings.Builder
+	report.WriteString("--- committed\n+++ generated\n")
+	for index := max(first-3, 0); index < first; index++ {
+		report.WriteString("  " + committedLines[index] + "\n")
+	}
+	for index := first; index < min(first+10, len(committedLines)); index++ {
+		report.WriteString("- " + committedLines[index] + "\n")
+	}
+	for index := first; index < min(first+10, len(generatedLines)); index++ {
+		report.WriteString("+ " + generatedLines[index] + "\n")
+	}
+
+	return report.String()
+}
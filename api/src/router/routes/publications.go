@@ -18,62 +18,119 @@ package routes
 
 import (
 	"api/src/controllers"
+	"api/src/models"
 	"net/http"
 )
 
-var publicationsRoutes = []Route{
-	{
-		URI:                    "/publications",
-		Method:                 http.MethodPost,
-		Function:               controllers.CreatePublication,
-		AuthenticationRequired: true,
-	},
-	{
-		URI:                    "/publications",
-		Method:                 http.MethodGet,
-		Function:               controllers.GetPublications,
-		AuthenticationRequired: true,
-	},
-	{
-		URI:                    "/publications/{publicationID}",
-		Method:                 http.MethodGet,
-		Function:               controllers.GetPublication,
-		AuthenticationRequired: true,
-	},
-	{
-		URI:                    "/publications/{publicationID}",
-		Method:                 http.MethodPut,
-		Function:               controllers.UpdatePublication,
-		AuthenticationRequired: true,
-	},
-	{
-		URI:                    "/publications/{publicationID}",
-		Method:                 http.MethodDelete,
-		Function:               controllers.DeletePublication,
-		AuthenticationRequired: true,
-	},
-	{
-		URI:                    "/users/{userID}/publications",
-		Method:                 http.MethodGet,
-		Function:               controllers.GetUserPublications,
-		AuthenticationRequired: true,
-	},
-	{
-		URI:                    "/publications/{publicationID}/like",
-		Method:                 http.MethodPost,
-		Function:               controllers.LikePublication,
-		AuthenticationRequired: true,
-	},
-	{
-		URI:                    "/publications/{publicationID}/unlike",
-		Method:                 http.MethodPost,
-		Function:               controllers.UnLikePublication,
-		AuthenticationRequired: true,
-	},
-	{
-		URI:                    "/publications/{publicationID}/likers",
-		Method:                 http.MethodGet,
-		Function:               controllers.GetLikers,
-		AuthenticationRequired: true,
-	},
+func publicationsRoutes(controller *controllers.Controller) []Route {
+	return []Route{
+		{
+			URI:                    "/publications",
+			Method:                 http.MethodPost,
+			Function:               controller.CreatePublication,
+			AuthenticationRequired: true,
+			Summary:                "Create a Publication",
+			Description:            "Endpoint used to create a publication of the authenticated user",
+			Tags:                   []string{"Publications"},
+			Request:                models.Publication{},
+			RequestFields:          []string{"title", "content"},
+			Idempotent:             true,
+			Status:                 http.StatusCreated,
+			Response:               models.Publication{},
+			Errors:                 []int{http.StatusBadRequest, http.StatusNotFound, http.StatusUnprocessableEntity},
+		},
+		{
+			URI:                    "/publications",
+			Method:                 http.MethodGet,
+			Function:               controller.GetPublications,
+			AuthenticationRequired: true,
+			Summary:                "Return Publications",
+			Description:            "Endpoint used to return the publications of the feed",
+			Tags:                   []string{"Publications"},
+			Paginated:              true,
+			Response:               []models.Publication{},
+			Errors:                 []int{http.StatusBadRequest},
+		},
+		{
+			URI:                    "/publications/{publicationID}",
+			Method:                 http.MethodGet,
+			Function:               controller.GetPublication,
+			AuthenticationRequired: true,
+			Summary:                "Get a Publication",
+			Description:            "Endpoint used to fetch a publication with given publication id",
+			Tags:                   []string{"Publications"},
+			Response:               models.Publication{},
+			Errors:                 []int{http.StatusBadRequest, http.StatusNotFound},
+		},
+		{
+			URI:                    "/publications/{publicationID}",
+			Method:                 http.MethodPut,
+			Function:               controller.UpdatePublication,
+			AuthenticationRequired: true,
+			Summary:                "Update a Publication",
+			Description:            "Endpoint used to update a publication",
+			Tags:                   []string{"Publications"},
+			Request:                models.Publication{},
+			RequestFields:          []string{"title", "content"},
+			Status:                 http.StatusNoContent,
+			Errors:                 []int{http.StatusBadRequest, http.StatusForbidden, http.StatusNotFound, http.StatusUnprocessableEntity},
+		},
+		{
+			URI:                    "/publications/{publicationID}",
+			Method:                 http.MethodDelete,
+			Function:               controller.DeletePublication,
+			AuthenticationRequired: true,
+			Summary:                "Delete a Publication",
+			Description:            "Endpoint used to delete a publication",
+			Tags:                   []string{"Publications"},
+			Status:                 http.StatusNoContent,
+			Errors:                 []int{http.StatusBadRequest, http.StatusForbidden, http.StatusNotFound},
+		},
+		{
+			URI:                    "/users/{userID}/publications",
+			Method:                 http.MethodGet,
+			Function:               controller.GetUserPublications,
+			AuthenticationRequired: true,
+			Summary:                "Get User Publications",
+			Description:            "Endpoint used to return the publications of a user",
+			Tags:                   []string{"Publications"},
+			Paginated:              true,
+			Response:               []models.Publication{},
+			Errors:                 []int{http.StatusBadRequest},
+		},
+		{
+			URI:                    "/publications/{publicationID}/like",
+			Method:                 http.MethodPost,
+			Function:               controller.LikePublication,
+			AuthenticationRequired: true,
+			Summary:                "Like Publication",
+			Description:            "Endpoint used to like a publication",
+			Tags:                   []string{"Publications"},
+			Status:                 http.StatusNoContent,
+			Errors:                 []int{http.StatusBadRequest, http.StatusNotFound, http.StatusConflict},
+		},
+		{
+			URI:                    "/publications/{publicationID}/unlike",
+			Method:                 http.MethodPost,
+			Function:               controller.UnLikePublication,
+			AuthenticationRequired: true,
+			Summary:                "Unlike Publication",
+			Description:            "Endpoint used to unlike a publication",
+			Tags:                   []string{"Publications"},
+			Status:                 http.StatusNoContent,
+			Errors:                 []int{http.StatusBadRequest, http.StatusNotFound},
+		},
+		{
+			URI:                    "/publications/{publicationID}/likers",
+			Method:                 http.MethodGet,
+			Function:               controller.GetLikers,
+			AuthenticationRequired: true,
+			Summary:                "Get Publication Likers",
+			Description:            "Endpoint used to return the users liking a publication",
+			Tags:                   []string{"Publications"},
+			Paginated:              true,
+			Response:               []models.User{},
+			Errors:                 []int{http.StatusBadRequest},
+		},
+	}
 }
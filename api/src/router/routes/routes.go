@@ -17,10 +17,24 @@ limitations under the License.
 package routes
 
 import (
+	"api/src/compression"
+	"api/src/config"
+	"api/src/controllers"
 	"api/src/middlewares"
+	"api/src/prommetrics"
+	"api/src/ratelimit"
+	"api/src/repositories"
+	"api/src/swagger"
+	"log/slog"
 	"net/http"
+	"reflect"
+	"runtime"
+	"strings"
+	"time"
 
 	"github.com/gorilla/mux"
+	"github.com/prometheus/client_golang/prometheus"
+	"go.opentelemetry.io/otel/trace"
 )
 
 // Route represents an API route
@@ -29,6 +43,43 @@ type Route struct {
 	Method                 string
 	Function               func(http.ResponseWriter, *http.Request)
 	AuthenticationRequired bool
+
+	// Unlimited routes are never rate limited, e.g. the probes of the orchestrator
+	Unlimited bool
+
+	// Version of the API the route belongs to, 0 for the unversioned ones (e.g. the probes). The
+	// Deprecated ones are the legacy aliases of the version 1 routes, served without prefix.
+	Version    int
+	Deprecated bool
+
+	// The fields below document the route in openapi.json, see OpenAPI
+	Summary     string
+	Description string
+	Tags        []string
+
+	// Query lists the query parameters with their description
+	Query map[string]string
+
+	// Paginated routes answer one page of their list, read from the limit and cursor query
+	// parameters, and link the pages around it in the Link header
+	Paginated bool
+
+	// Idempotent routes replay the response of a request to its retries sent with the same Idempotency-Key
+	Idempotent bool
+
+	// Request is the model the body is decoded into and RequestFields its
+	// properties the route requires, the only ones a client may send
+	Request       interface{}
+	RequestFields []string
+
+	// Status of the successful response, http.StatusOK when zero, and the model
+	// of its body. A string is sent as text/plain.
+	Status   int
+	Response interface{}
+
+	// Errors lists the error statuses answered besides 401, on the routes
+	// requiring authentication, 429, on the rate limited ones, and 500
+	Errors []int
 }
 
 type PromRoute struct {
@@ -38,30 +89,203 @@ type PromRoute struct {
 	AuthenticationRequired bool
 }
 
+// Options holds the dependencies used to build the API routes
+type Options struct {
+	Controller *controllers.Controller
+	Logger     *slog.Logger
+	Metrics    *prommetrics.Metrics
+	Gatherer   prometheus.Gatherer
+	SecretKey  []byte
+	Tracer     trace.Tracer
+
+	// QueryTimeout returns the deadline of the queries run by the route
+	QueryTimeout func(method, uri string) time.Duration
+
+	// BodyMaxSize returns the greatest body accepted by the route
+	BodyMaxSize func(method, uri string) int64
+
+	// Validator checks the requests against the OpenAPI document when set,
+	// and the responses as well with ValidateResponses
+	Validator         *swagger.Validator
+	ValidateResponses bool
+
+	// RateLimitStore keeps the token buckets of the clients, the rate limiting is off when nil
+	RateLimitStore ratelimit.Store
+	RateLimit      config.RateLimit
+
+	// CORS lets the browsers call the routes from the allowed origins, when any
+	CORS config.CORS
+
+	// Compressor compresses the responses, when it offers any encoding
+	Compressor *compression.Compressor
+
+	// Users tells Authenticate whether the user of a token is still enabled
+	Users repositories.UsersRepository
+
+	// Idempotency stores the responses of the Idempotent routes for IdempotencyTTL
+	Idempotency    repositories.IdempotencyRepository
+	IdempotencyTTL time.Duration
+
+	// LegacyRoutes tells whether the deprecated unversioned routes are served and until when
+	LegacyRoutes config.LegacyRoutes
+}
+
+// Unversioned returns the URI of the route without its version prefix, which keys the settings
+// shared by the versions of a route (rate limits, query timeouts)
+func (route Route) Unversioned() string {
+	if route.Version == 0 || route.Deprecated {
+		return route.URI
+	}
+
+	return route.URI[strings.Index(route.URI[1:], "/")+1:]
+}
+
+// Revalidable tells whether the clients can revalidate the route responses with If-None-Match or If-Modified-Since
+func (route Route) Revalidable() bool {
+	return route.Method == http.MethodGet && route.Response != nil
+}
+
 // Configure instanciate all API routes into mux router
-func Configure(r *mux.Router) *mux.Router {
-	apiRoutes := usersRoutes
-	apiRoutes = append(apiRoutes, loginRoute)
-	apiRoutes = append(apiRoutes, publicationsRoutes...)
-	apiRoutes = append(apiRoutes, healthcheckRoutes...)
+func Configure(r *mux.Router, options Options) *mux.Router {
+	modificationTimes := middlewares.NewModificationTimes()
+
+	for _, apiRoute := range apiRoutes(options.Controller) {
+		if apiRoute.Deprecated && !options.LegacyRoutes.Enabled {
+			continue
+		}
+
+		function := middlewares.Trace(options.Tracer, handlerName(apiRoute.Function),
+			middlewares.Deadline(options.Metrics, apiRoute.URI,
+				options.QueryTimeout(apiRoute.Method, apiRoute.Unversioned()), versioned(apiRoute.Version, apiRoute.Function),
+			),
+		)
+
+		if options.Validator != nil {
+			function = middlewares.Validate(options.Validator, options.ValidateResponses, function)
+		}
+
+		if apiRoute.Idempotent {
+			prefix := ""
+			if apiRoute.Deprecated {
+				prefix = versions[0].Prefix
+			}
+			function = middlewares.Idempotent(options.Idempotency, options.IdempotencyTTL, options.Logger, options.Metrics,
+				apiRoute.URI, prefix, function,
+			)
+		}
+
+		if apiRoute.Request != nil {
+			function = middlewares.Body(options.BodyMaxSize(apiRoute.Method, apiRoute.Unversioned()), apiRoute.RequestFields, function)
+		}
+
+		if apiRoute.Revalidable() {
+			function = middlewares.Conditional(modificationTimes, function)
+		}
 
-	for _, apiRoute := range apiRoutes {
 		if apiRoute.AuthenticationRequired {
-			r.HandleFunc(apiRoute.URI,
-				middlewares.Logger(apiRoute.URI, middlewares.Authenticate(apiRoute.Function)),
-			).Methods(apiRoute.Method)
-		} else {
-			r.HandleFunc(apiRoute.URI,
-				middlewares.Logger(apiRoute.URI, apiRoute.Function),
-			).Methods(apiRoute.Method)
+			function = middlewares.Authenticate(options.SecretKey, options.Users, function)
+		}
+
+		if options.RateLimitStore != nil && !apiRoute.Unlimited {
+			scope, policy := options.RateLimit.PolicyFor(apiRoute.Method, apiRoute.Unversioned(), apiRoute.AuthenticationRequired)
+			if policy.Limit > 0 {
+				var secretKey []byte
+				if apiRoute.AuthenticationRequired {
+					secretKey = options.SecretKey
+				}
+				function = middlewares.RateLimit(options.RateLimitStore, options.RateLimit, secretKey, options.Logger, options.Metrics,
+					apiRoute.URI, scope, policy, function,
+				)
+			}
+		}
+
+		if options.Compressor.Enabled() {
+			function = middlewares.Compress(options.Compressor, function)
+		}
+
+		if apiRoute.Deprecated {
+			function = middlewares.Deprecated(options.LegacyRoutes, options.Metrics, apiRoute.URI, versions[0].Prefix, function)
+		}
+
+		if options.CORS.Enabled() {
+			function = middlewares.CORS(options.CORS, function)
 		}
+
+		r.HandleFunc(apiRoute.URI,
+			middlewares.Logger(options.Logger, options.Metrics, options.Tracer, apiRoute.URI,
+				middlewares.Recover(options.Logger, options.Metrics, apiRoute.URI, function),
+			),
+		).Methods(apiRoute.Method)
 	}
 
-	// Prometheus api route
-	r.Handle(
-		metricsRoute.URI,
-		metricsRoute.Function,
-	).Methods(metricsRoute.Method)
+	// Prometheus api route, compressed by promhttp, OpenAPI document and docs page
+	for _, promRoute := range append([]PromRoute{metricsRoute(options.Gatherer)}, docsRoutes()...) {
+		function := promRoute.Function
+		if options.Compressor.Enabled() && promRoute.URI != "/metrics" {
+			function = middlewares.Compress(options.Compressor, function.ServeHTTP)
+		}
+		if options.CORS.Enabled() {
+			function = middlewares.CORS(options.CORS, function.ServeHTTP)
+		}
+
+		r.Handle(promRoute.URI, function).Methods(promRoute.Method)
+	}
+
+	if options.CORS.Enabled() {
+		configurePreflight(r, options)
+	}
 
 	return r
 }
+
+// configurePreflight answers the CORS preflight requests of every route registered into r
+func configurePreflight(r *mux.Router, options Options) {
+	var uris []string
+	methods := make(map[string][]string)
+
+	r.Walk(func(route *mux.Route, router *mux.Router, ancestors []*mux.Route) error {
+		uri, erro := route.GetPathTemplate()
+		if erro != nil {
+			return nil
+		}
+
+		routeMethods, erro := route.GetMethods()
+		if erro != nil {
+			return nil
+		}
+
+		if _, exists := methods[uri]; !exists {
+			uris = append(uris, uri)
+		}
+		methods[uri] = append(methods[uri], routeMethods...)
+
+		return nil
+	})
+
+	for _, uri := range uris {
+		r.HandleFunc(uri,
+			middlewares.Logger(options.Logger, options.Metrics, options.Tracer, uri,
+				middlewares.Preflight(options.CORS, methods[uri]),
+			),
+		).Methods(http.MethodOptions)
+	}
+}
+
+// versioned tells function the version of the API it serves, when any
+func versioned(version int, function http.HandlerFunc) http.HandlerFunc {
+	if version == 0 {
+		return function
+	}
+
+	return func(w http.ResponseWriter, r *http.Request) {
+		function(w, r.WithContext(controllers.WithVersion(r.Context(), version)))
+	}
+}
+
+// handlerName returns the name of the controller method serving a route, e.g. "controllers.Controller.GetPublications"
+func handlerName(function func(http.ResponseWriter, *http.Request)) string {
+	name := runtime.FuncForPC(reflect.ValueOf(function).Pointer()).Name()
+	name = name[strings.LastIndex(name, "/")+1:]
+
+	return strings.NewReplacer("(*", "", ")", "", "-fm", "").Replace(name)
+}
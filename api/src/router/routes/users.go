@@ -18,74 +18,145 @@ package routes
 
 import (
 	"api/src/controllers"
+	"api/src/models"
 	"net/http"
 )
 
-var usersRoutes = []Route{
-	{
-		URI:                    "/users",
-		Method:                 http.MethodPost,
-		Function:               controllers.CreateUser,
-		AuthenticationRequired: false,
-	},
-	{
-		URI:                    "/users",
-		Method:                 http.MethodGet,
-		Function:               controllers.GetUsers,
-		AuthenticationRequired: true,
-	},
-	{
-		URI:                    "/users/{userID}",
-		Method:                 http.MethodGet,
-		Function:               controllers.GetUser,
-		AuthenticationRequired: true,
-	},
-	{
-		URI:                    "/users/{userID}",
-		Method:                 http.MethodPut,
-		Function:               controllers.UpdateUser,
-		AuthenticationRequired: true,
-	},
-	{
-		URI:                    "/users/{userID}",
-		Method:                 http.MethodDelete,
-		Function:               controllers.DeleteUser,
-		AuthenticationRequired: true,
-	},
-	{
-		URI:                    "/users/{userID}/follow",
-		Method:                 http.MethodPost,
-		Function:               controllers.FollowUser,
-		AuthenticationRequired: true,
-	},
-	{
-		URI:                    "/users/{userID}/unfollow",
-		Method:                 http.MethodPost,
-		Function:               controllers.UnFollowUser,
-		AuthenticationRequired: true,
-	},
-	{
-		URI:                    "/users/{userID}/followers",
-		Method:                 http.MethodGet,
-		Function:               controllers.GetFollowers,
-		AuthenticationRequired: true,
-	},
-	{
-		URI:                    "/users/{userID}/following",
-		Method:                 http.MethodGet,
-		Function:               controllers.GetFollowing,
-		AuthenticationRequired: true,
-	},
-	{
-		URI:                    "/users/{userID}/updatepass",
-		Method:                 http.MethodPost,
-		Function:               controllers.UpdatePass,
-		AuthenticationRequired: true,
-	},
-	{
-		URI:                    "/users/{userID}/likedPublications",
-		Method:                 http.MethodGet,
-		Function:               controllers.LikedPublications,
-		AuthenticationRequired: true,
-	},
+func usersRoutes(controller *controllers.Controller) []Route {
+	return []Route{
+		{
+			URI:                    "/users",
+			Method:                 http.MethodPost,
+			Function:               controller.CreateUser,
+			AuthenticationRequired: false,
+			Summary:                "Create User",
+			Description:            "Endpoint used to create users",
+			Tags:                   []string{"Users"},
+			Request:                models.User{},
+			RequestFields:          []string{"name", "nick", "email", "pass"},
+			Idempotent:             true,
+			Status:                 http.StatusCreated,
+			Response:               models.User{},
+			Errors:                 []int{http.StatusBadRequest, http.StatusConflict, http.StatusUnprocessableEntity},
+		},
+		{
+			URI:                    "/users",
+			Method:                 http.MethodGet,
+			Function:               controller.GetUsers,
+			AuthenticationRequired: true,
+			Summary:                "Return Users",
+			Description:            "Endpoint used to retrieve users with given filter, email or nickname on URL query",
+			Tags:                   []string{"Users"},
+			Query:                  map[string]string{"user": "Nick or email of the users to search"},
+			Paginated:              true,
+			Response:               []models.User{},
+			Errors:                 []int{http.StatusBadRequest},
+		},
+		{
+			URI:                    "/users/{userID}",
+			Method:                 http.MethodGet,
+			Function:               controller.GetUser,
+			AuthenticationRequired: true,
+			Summary:                "Fetch User",
+			Description:            "Endpoint used to fetch a user with given user id",
+			Tags:                   []string{"Users"},
+			Response:               models.User{},
+			Errors:                 []int{http.StatusBadRequest, http.StatusNotFound},
+		},
+		{
+			URI:                    "/users/{userID}",
+			Method:                 http.MethodPut,
+			Function:               controller.UpdateUser,
+			AuthenticationRequired: true,
+			Summary:                "Update User",
+			Description:            "Endpoint used to update a user",
+			Tags:                   []string{"Users"},
+			Request:                models.User{},
+			RequestFields:          []string{"name", "nick", "email"},
+			Status:                 http.StatusNoContent,
+			Errors:                 []int{http.StatusBadRequest, http.StatusForbidden, http.StatusNotFound, http.StatusConflict, http.StatusUnprocessableEntity},
+		},
+		{
+			URI:                    "/users/{userID}",
+			Method:                 http.MethodDelete,
+			Function:               controller.DeleteUser,
+			AuthenticationRequired: true,
+			Summary:                "Delete User",
+			Description:            "Endpoint used to delete a user",
+			Tags:                   []string{"Users"},
+			Status:                 http.StatusNoContent,
+			Errors:                 []int{http.StatusBadRequest, http.StatusForbidden, http.StatusNotFound},
+		},
+		{
+			URI:                    "/users/{userID}/follow",
+			Method:                 http.MethodPost,
+			Function:               controller.FollowUser,
+			AuthenticationRequired: true,
+			Summary:                "Follow User",
+			Description:            "Endpoint used to follow a user",
+			Tags:                   []string{"Users"},
+			Status:                 http.StatusNoContent,
+			Errors:                 []int{http.StatusBadRequest, http.StatusForbidden, http.StatusNotFound},
+		},
+		{
+			URI:                    "/users/{userID}/unfollow",
+			Method:                 http.MethodPost,
+			Function:               controller.UnFollowUser,
+			AuthenticationRequired: true,
+			Summary:                "Unfollow User",
+			Description:            "Endpoint used to unfollow a user",
+			Tags:                   []string{"Users"},
+			Status:                 http.StatusNoContent,
+			Errors:                 []int{http.StatusBadRequest, http.StatusForbidden},
+		},
+		{
+			URI:                    "/users/{userID}/followers",
+			Method:                 http.MethodGet,
+			Function:               controller.GetFollowers,
+			AuthenticationRequired: true,
+			Summary:                "Fetch Followers",
+			Description:            "Endpoint used to return all followers from a user",
+			Tags:                   []string{"Users"},
+			Paginated:              true,
+			Response:               []models.User{},
+			Errors:                 []int{http.StatusBadRequest},
+		},
+		{
+			URI:                    "/users/{userID}/following",
+			Method:                 http.MethodGet,
+			Function:               controller.GetFollowing,
+			AuthenticationRequired: true,
+			Summary:                "Fetch Following",
+			Description:            "Endpoint used to return all users a user follow",
+			Tags:                   []string{"Users"},
+			Paginated:              true,
+			Response:               []models.User{},
+			Errors:                 []int{http.StatusBadRequest},
+		},
+		{
+			URI:                    "/users/{userID}/updatepass",
+			Method:                 http.MethodPost,
+			Function:               controller.UpdatePass,
+			AuthenticationRequired: true,
+			Summary:                "Update Password",
+			Description:            "Endpoint used to update a user password",
+			Tags:                   []string{"Users"},
+			Request:                models.Pass{},
+			RequestFields:          []string{"current", "new"},
+			Status:                 http.StatusNoContent,
+			Errors:                 []int{http.StatusBadRequest, http.StatusForbidden, http.StatusNotFound, http.StatusUnprocessableEntity},
+		},
+		{
+			URI:                    "/users/{userID}/likedPublications",
+			Method:                 http.MethodGet,
+			Function:               controller.LikedPublications,
+			AuthenticationRequired: true,
+			Summary:                "Fetch Liked Publications",
+			Description:            "Endpoint used to return the publications a user have liked",
+			Tags:                   []string{"Users"},
+			Paginated:              true,
+			Response:               []models.Publication{},
+			Errors:                 []int{http.StatusBadRequest},
+		},
+	}
 }
@@ -0,0 +1,198 @@
+/*
+Copyright 2022 Danilo S. Lopes.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at:
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package routes
+
+import (
+	"api/src/authentication"
+	"api/src/config"
+	"api/src/controllers"
+	"api/src/middlewares"
+	"api/src/models"
+	"api/src/prommetrics"
+	"api/src/repositories"
+	"api/src/responses"
+	"api/src/swagger"
+	"context"
+	"encoding/json"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gorilla/mux"
+	"go.opentelemetry.io/otel/trace/noop"
+)
+
+var secretKey = []byte("secret")
+
+// newValidatedRouter returns the API routes over an empty in-memory store, checking the requests and the
+// responses against the OpenAPI document like OPENAPI_VALIDATION=full, and the store
+func newValidatedRouter(t *testing.T) (*mux.Router, repositories.Store) {
+	t.Helper()
+
+	validator, erro := swagger.NewValidator()
+	if erro != nil {
+		t.Fatal(erro)
+	}
+
+	return newRouter(t, func(options *Options) {
+		options.Validator = validator
+		options.ValidateResponses = true
+	})
+}
+
+// newRouter returns the API routes over an empty in-memory store, their options changed by configure, and the store
+func newRouter(t *testing.T, configure func(options *Options)) (*mux.Router, repositories.Store) {
+	t.Helper()
+
+	store := repositories.NewMemoryStore()
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	registry := prommetrics.NewRegistry()
+	metrics := prommetrics.New(registry)
+	cfg := config.Config{SecretKey: secretKey, Pagination: config.Pagination{DefaultLimit: 20, MaxLimit: 100}}
+
+	options := Options{
+		Controller:     controllers.New(cfg, store, metrics, logger),
+		Logger:         logger,
+		Metrics:        metrics,
+		Gatherer:       registry,
+		SecretKey:      secretKey,
+		Tracer:         noop.NewTracerProvider().Tracer(""),
+		QueryTimeout:   func(method, uri string) time.Duration { return 0 },
+		BodyMaxSize:    func(method, uri string) int64 { return 1 << 16 },
+		Users:          store.Users,
+		Idempotency:    store.Idempotency,
+		IdempotencyTTL: time.Hour,
+	}
+	configure(&options)
+
+	return Configure(mux.NewRouter(), options), store
+}
+
+// serve sends a request to router, authenticated as userID when not zero, and returns the response
+func serve(t *testing.T, router http.Handler, method, target, body string, userID uint64) (*httptest.ResponseRecorder, responses.ProblemDetails) {
+	t.Helper()
+
+	request := httptest.NewRequest(method, target, strings.NewReader(body))
+	if body != "" {
+		request.Header.Set("Content-Type", "application/json")
+	}
+	if userID != 0 {
+		token, erro := authentication.GenerateToken(secretKey, userID)
+		if erro != nil {
+			t.Fatal(erro)
+		}
+		request.Header.Set("Authorization", "Bearer "+token)
+	}
+
+	response := httptest.NewRecorder()
+	router.ServeHTTP(response, request)
+
+	var problem responses.ProblemDetails
+	if response.Header().Get("Content-Type") == "application/problem+json" {
+		if erro := json.Unmarshal(response.Body.Bytes(), &problem); erro != nil {
+			t.Fatalf("decoding the problem: %v", erro)
+		}
+	}
+
+	return response, problem
+}
+
+// The requests not matching the document get a 400 listing the invalid fields, the others go through
+// with their responses checked against the document
+func TestValidateRequests(t *testing.T) {
+	router, store := newValidatedRouter(t)
+
+	userID, erro := store.Users.Create(context.Background(), models.User{Name: "user", Nick: "user", Email: "user@example.com", Pass: "hash"})
+	if erro != nil {
+		t.Fatal(erro)
+	}
+
+	tests := []struct {
+		name, method, target, body string
+		status                     int
+		in, field                  string
+	}{
+		{"body missing a field", http.MethodPost, "/v2/users", `{"name":"n","nick":"n","email":"n@example.com"}`, http.StatusBadRequest, "body", "pass"},
+		{"body of the wrong type", http.MethodPost, "/v2/publications", `{"title":1,"content":"c"}`, http.StatusBadRequest, "body", "title"},
+		{"path parameter", http.MethodGet, "/v2/users/abc", "", http.StatusBadRequest, "path", "userID"},
+		{"query parameter", http.MethodGet, "/v2/publications?limit=0", "", http.StatusBadRequest, "query", "limit"},
+		{"valid request", http.MethodPost, "/v2/publications", `{"title":"t","content":"c"}`, http.StatusCreated, "", ""},
+		{"valid list", http.MethodGet, "/v2/publications?limit=5", "", http.StatusOK, "", ""},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			response, problem := serve(t, router, test.method, test.target, test.body, userID)
+			if response.Code != test.status {
+				t.Fatalf("got status %d, want %d: %s", response.Code, test.status, response.Body)
+			}
+			if test.status != http.StatusBadRequest {
+				return
+			}
+
+			if problem.Code != responses.CodeInvalidRequest || len(problem.Errors) == 0 {
+				t.Fatalf("got problem %+v, want %s listing the invalid fields", problem, responses.CodeInvalidRequest)
+			}
+			if problem.Errors[0].In != test.in || problem.Errors[0].Field != test.field {
+				t.Fatalf("got invalid field %+v, want %s in %s", problem.Errors[0], test.field, test.in)
+			}
+		})
+	}
+}
+
+// A response drifting from the document is replaced by a 500 telling why
+func TestValidateResponses(t *testing.T) {
+	validator, erro := swagger.NewValidator()
+	if erro != nil {
+		t.Fatal(erro)
+	}
+
+	router := mux.NewRouter()
+	router.HandleFunc("/v2/users/{userID}", middlewares.Validate(validator, true, func(w http.ResponseWriter, r *http.Request) {
+		responses.JSON(w, http.StatusOK, map[string]interface{}{"id": "not a number", "nick": "user"})
+	}))
+
+	response, problem := serve(t, router, http.MethodGet, "/v2/users/1", "", 0)
+	if response.Code != http.StatusInternalServerError || problem.Code != responses.CodeInvalidResponse {
+		t.Fatalf("got status %d and problem %+v, want a 500 %s", response.Code, problem, responses.CodeInvalidResponse)
+	}
+	if len(problem.Errors) == 0 || problem.Errors[0].In != "response" || problem.Errors[0].Field != "id" {
+		t.Fatalf("got invalid fields %+v, want the response id", problem.Errors)
+	}
+
+	response, problem = serve(t, router, http.MethodGet, "/v2/undocumented", "", 0)
+	if response.Code != http.StatusNotFound {
+		t.Fatalf("got status %d for an unrouted path, want 404", response.Code)
+	}
+}
+
+// A body breaking a validation rule of the models gets a 422 locating the field
+func TestValidationFailures(t *testing.T) {
+	router, _ := newValidatedRouter(t)
+
+	response, problem := serve(t, router, http.MethodPost, "/v2/users", `{"name":"user","nick":"user","email":"not an email","pass":"secret"}`, 0)
+	if response.Code != http.StatusUnprocessableEntity || problem.Code != models.CodeUserInvalid {
+		t.Fatalf("got status %d and problem %+v, want a 422 %s", response.Code, problem, models.CodeUserInvalid)
+	}
+	if len(problem.Errors) != 1 || problem.Errors[0].In != "body" || problem.Errors[0].Field != "email" {
+		t.Fatalf("got invalid fields %+v, want the body email", problem.Errors)
+	}
+}
@@ -0,0 +1,89 @@
+/*
+Copyright 2022 Danilo S. Lopes.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at:
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package routes
+
+import (
+	"api/src/controllers"
+	"api/src/models"
+)
+
+// Version is one version of the API, its routes served under Prefix
+type Version struct {
+	Number int
+	Prefix string
+
+	// Routes returns the route table of the version, their URIs relative to Prefix
+	Routes func(controller *controllers.Controller) []Route
+}
+
+// versions lists the versions of the API, oldest first. The legacy unversioned routes alias the version 1.
+var versions = []Version{
+	{Number: 1, Prefix: "/v1", Routes: v1Routes},
+	{Number: 2, Prefix: "/v2", Routes: v2Routes},
+}
+
+// v1Routes returns the routes of the version 1, the original API
+func v1Routes(controller *controllers.Controller) []Route {
+	routes := usersRoutes(controller)
+	routes = append(routes, loginRoute(controller))
+	routes = append(routes, publicationsRoutes(controller)...)
+
+	return routes
+}
+
+// v2Routes returns the routes of the version 2, answering the lists in a models.Page
+func v2Routes(controller *controllers.Controller) []Route {
+	routes := v1Routes(controller)
+
+	for index, route := range routes {
+		if !route.Paginated {
+			continue
+		}
+
+		switch route.Response.(type) {
+		case []models.User:
+			routes[index].Response = models.Page[models.User]{}
+		case []models.Publication:
+			routes[index].Response = models.Page[models.Publication]{}
+		}
+	}
+
+	return routes
+}
+
+// apiRoutes returns the route table of the API: the routes of every version under its prefix, the
+// legacy aliases of the version 1 ones and the unversioned probes
+func apiRoutes(controller *controllers.Controller) []Route {
+	var table []Route
+
+	for _, version := range versions {
+		for _, route := range version.Routes(controller) {
+			route.Version = version.Number
+
+			if version.Number == 1 {
+				legacy := route
+				legacy.Deprecated = true
+				table = append(table, legacy)
+			}
+
+			route.URI = version.Prefix + route.URI
+			table = append(table, route)
+		}
+	}
+
+	return append(table, healthcheckRoutes(controller)...)
+}
@@ -0,0 +1,118 @@
+/*
+Copyright 2022 Danilo S. Lopes.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at:
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package routes
+
+import (
+	"api/src/config"
+	"api/src/models"
+	"api/src/prommetrics"
+	"context"
+	"encoding/json"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+)
+
+// The legacy aliases answer as the /v1 routes, telling the clients they are deprecated and what replaces them
+func TestLegacyRoutes(t *testing.T) {
+	deprecation := time.Date(2026, 1, 31, 0, 0, 0, 0, time.UTC)
+	sunset := time.Date(2026, 7, 31, 0, 0, 0, 0, time.UTC)
+
+	var metrics *prommetrics.Metrics
+	router, store := newRouter(t, func(options *Options) {
+		options.LegacyRoutes = config.LegacyRoutes{Enabled: true, Deprecation: deprecation, Sunset: sunset}
+		metrics = options.Metrics
+	})
+
+	userID, erro := store.Users.Create(context.Background(), models.User{Name: "user", Nick: "user", Email: "user@example.com", Pass: "hash"})
+	if erro != nil {
+		t.Fatal(erro)
+	}
+	for _, title := range []string{"first", "second"} {
+		if _, erro := store.Publications.Create(context.Background(), models.Publication{Title: title, Content: "content", AuthorID: userID}); erro != nil {
+			t.Fatal(erro)
+		}
+	}
+
+	legacy, _ := serve(t, router, http.MethodGet, "/publications?limit=1", "", userID)
+	if legacy.Code != http.StatusOK {
+		t.Fatalf("got %d, want 200: %s", legacy.Code, legacy.Body)
+	}
+
+	header := legacy.Header()
+	if got := header.Get("Deprecation"); got != "@1769817600" {
+		t.Fatalf("got Deprecation %q, want @1769817600", got)
+	}
+	if got := header.Get("Sunset"); got != "Fri, 31 Jul 2026 00:00:00 GMT" {
+		t.Fatalf("got Sunset %q, want Fri, 31 Jul 2026 00:00:00 GMT", got)
+	}
+	if links := strings.Join(header.Values("Link"), ", "); !strings.Contains(links, `</v1/publications?limit=1>; rel="successor-version"`) {
+		t.Fatalf("got Link %q, want the /v1 route as the successor version", links)
+	}
+	if got := testutil.ToFloat64(metrics.LegacyRequests.WithLabelValues("/publications")); got != 1 {
+		t.Fatalf("counted %v legacy requests, want 1", got)
+	}
+
+	versioned, _ := serve(t, router, http.MethodGet, "/v1/publications?limit=1", "", userID)
+	if versioned.Body.String() != legacy.Body.String() {
+		t.Fatalf("the /v1 route got %s, want the body of its legacy alias %s", versioned.Body, legacy.Body)
+	}
+	if versioned.Header().Get("Deprecation") != "" || versioned.Header().Get("Sunset") != "" {
+		t.Fatalf("the /v1 route got Deprecation %q and Sunset %q, want none",
+			versioned.Header().Get("Deprecation"), versioned.Header().Get("Sunset"))
+	}
+	if got := testutil.ToFloat64(metrics.LegacyRequests.WithLabelValues("/publications")); got != 1 {
+		t.Fatalf("counted %v legacy requests after a versioned one, want 1", got)
+	}
+}
+
+// The /v1 lists are bare arrays, the /v2 ones a page envelope linking the next page
+func TestVersionedLists(t *testing.T) {
+	router, store := newRouter(t, func(options *Options) {})
+
+	userID, erro := store.Users.Create(context.Background(), models.User{Name: "user", Nick: "user", Email: "user@example.com", Pass: "hash"})
+	if erro != nil {
+		t.Fatal(erro)
+	}
+	for _, title := range []string{"first", "second"} {
+		if _, erro := store.Publications.Create(context.Background(), models.Publication{Title: title, Content: "content", AuthorID: userID}); erro != nil {
+			t.Fatal(erro)
+		}
+	}
+
+	v1, _ := serve(t, router, http.MethodGet, "/v1/publications?limit=1", "", userID)
+	var list []models.Publication
+	if erro := json.Unmarshal(v1.Body.Bytes(), &list); erro != nil || len(list) != 1 {
+		t.Fatalf("/v1 got %d %s, want an array of 1 publication", v1.Code, v1.Body)
+	}
+
+	v2, _ := serve(t, router, http.MethodGet, "/v2/publications?limit=1", "", userID)
+	var page models.Page[models.Publication]
+	if erro := json.Unmarshal(v2.Body.Bytes(), &page); erro != nil || !strings.HasPrefix(strings.TrimSpace(v2.Body.String()), "{") {
+		t.Fatalf("/v2 got %d %s, want a page", v2.Code, v2.Body)
+	}
+	if len(page.Items) != 1 || page.Items[0].ID != list[0].ID || !strings.HasPrefix(page.Next, "/v2/publications?") {
+		t.Fatalf("/v2 got the page %+v, want the publication of /v1 and a link to the next page", page)
+	}
+
+	if legacy, _ := serve(t, router, http.MethodGet, "/publications", "", userID); legacy.Code != http.StatusNotFound {
+		t.Fatalf("the legacy alias got %d while LEGACY_ROUTES is off, want 404", legacy.Code)
+	}
+}
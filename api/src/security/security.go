@@ -22,6 +22,10 @@ import (
 	"golang.org/x/crypto/bcrypt"
 )
 
+// UnknownUserHash is compared with the password sent for an unknown user, so the login
+// takes as long as with a registered one
+const UnknownUserHash = "$2a$10$aLVQH3M.uBBqk2SNK9Agp.LH7nzP6rRntUr9SRjEcd7gzm.rjjbay"
+
 // Hash receive password and hash it
 func Hash(pass string) ([]byte, error) {
 	// password lenth cant be higher then 72 bytes
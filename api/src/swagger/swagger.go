@@ -0,0 +1,93 @@
+/*
+Copyright 2022 Danilo S. Lopes.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at:
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package swagger
+
+import (
+	_ "embed"
+	"encoding/json"
+	"net/http"
+	"strings"
+)
+
+// The OpenAPI document of the API, generated from the route table by sm openapi
+//
+//go:generate go run ../.. openapi --output openapi.json
+//go:embed openapi.json
+var spec []byte
+
+// The docs page, a standalone HTML file rendering /openapi.json without any external asset
+//
+//go:embed docs.html
+var docs []byte
+
+// Spec returns the embedded OpenAPI document
+func Spec() []byte {
+	return spec
+}
+
+// SpecHandler serves the OpenAPI document with its servers pointing to the host the request was sent to
+func SpecHandler() http.Handler {
+	var document map[string]json.RawMessage
+	if erro := json.Unmarshal(spec, &document); erro != nil {
+		panic("the embedded openapi.json is invalid: " + erro.Error())
+	}
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		servers, _ := json.Marshal([]map[string]string{{"url": serverURL(r)}})
+
+		withServers := make(map[string]json.RawMessage, len(document))
+		for key, value := range document {
+			withServers[key] = value
+		}
+		withServers["servers"] = servers
+
+		body, erro := json.MarshalIndent(withServers, "", "  ")
+		if erro != nil {
+			http.Error(w, erro.Error(), http.StatusInternalServerError)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		w.Write(body)
+	})
+}
+
+// DocsHandler serves the interactive documentation page
+func DocsHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		w.Write(docs)
+	})
+}
+
+// serverURL returns the base URL the client used to reach the API, honouring the headers set by reverse proxies
+func serverURL(r *http.Request) string {
+	scheme := "http"
+	if r.TLS != nil {
+		scheme = "https"
+	}
+	if proto := r.Header.Get("X-Forwarded-Proto"); proto == "http" || proto == "https" {
+		scheme = proto
+	}
+
+	host := r.Host
+	if forwarded := r.Header.Get("X-Forwarded-Host"); forwarded != "" {
+		host = strings.TrimSpace(strings.Split(forwarded, ",")[0])
+	}
+
+	return scheme + "://" + host + "/"
+}
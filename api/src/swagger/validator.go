@@ -0,0 +1,196 @@
+/*
+Copyright 2022 Danilo S. Lopes.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at:
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package swagger
+
+import (
+	"api/src/responses"
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"strings"
+
+	"github.com/getkin/kin-openapi/openapi3"
+	"github.com/getkin/kin-openapi/openapi3filter"
+	"github.com/getkin/kin-openapi/routers"
+	"github.com/getkin/kin-openapi/routers/gorillamux"
+)
+
+// ErrUndocumented is returned when the request matches no operation of the OpenAPI document
+var ErrUndocumented = errors.New("the route is not documented in openapi.json")
+
+// Validator checks the requests and responses against the embedded OpenAPI document
+type Validator struct {
+	router routers.Router
+}
+
+// NewValidator loads the embedded OpenAPI document, failing when it is not a valid one
+func NewValidator() (*Validator, error) {
+	document, erro := openapi3.NewLoader().LoadFromData(spec)
+	if erro != nil {
+		return nil, erro
+	}
+
+	if erro := document.Validate(context.Background()); erro != nil {
+		return nil, erro
+	}
+
+	// the requests are matched by path only, whatever host they were sent to
+	document.Servers = nil
+
+	router, erro := gorillamux.NewRouter(document)
+	if erro != nil {
+		return nil, erro
+	}
+
+	return &Validator{router}, nil
+}
+
+// Request is a request matched with its operation in the OpenAPI document
+type Request struct {
+	input *openapi3filter.RequestValidationInput
+}
+
+// ValidateRequest checks the parameters and the body of r. The authentication is left to the
+// Authenticate middleware. It returns ErrUndocumented when no operation matches r, otherwise
+// the invalid fields, if any.
+func (validator *Validator) ValidateRequest(r *http.Request) (*Request, []responses.FieldError, error) {
+	route, pathParams, erro := validator.router.FindRoute(r)
+	if erro != nil {
+		return nil, nil, ErrUndocumented
+	}
+
+	input := &openapi3filter.RequestValidationInput{
+		Request:    r,
+		PathParams: pathParams,
+		Route:      route,
+		Options: &openapi3filter.Options{
+			MultiError:         true,
+			AuthenticationFunc: openapi3filter.NoopAuthenticationFunc,
+		},
+	}
+
+	if erro := openapi3filter.ValidateRequest(r.Context(), input); erro != nil {
+		return &Request{input}, fieldErrors(erro), nil
+	}
+
+	return &Request{input}, nil, nil
+}
+
+// ValidateResponse checks the status, the content type and the body the API answered request with
+func (validator *Validator) ValidateResponse(ctx context.Context, request *Request, status int, header http.Header, body []byte) []responses.FieldError {
+	erro := openapi3filter.ValidateResponse(ctx, &openapi3filter.ResponseValidationInput{
+		RequestValidationInput: request.input,
+		Status:                 status,
+		Header:                 header,
+		Body:                   io.NopCloser(bytes.NewReader(body)),
+		Options:                &openapi3filter.Options{MultiError: true},
+	})
+	if erro == nil {
+		return nil
+	}
+
+	return fieldErrors(erro)
+}
+
+// fieldErrors flattens the errors of kin-openapi into one entry per invalid field. The request and
+// response errors wrap the errors of their fields, so only the lists they are part of are flattened.
+func fieldErrors(erro error) []responses.FieldError {
+	if multi, ok := erro.(openapi3.MultiError); ok {
+		var fields []responses.FieldError
+		for _, item := range multi {
+			fields = append(fields, fieldErrors(item)...)
+		}
+		return fields
+	}
+
+	var requestErro *openapi3filter.RequestError
+	if errors.As(erro, &requestErro) {
+		in, name := "body", ""
+		if requestErro.Parameter != nil {
+			in, name = requestErro.Parameter.In, requestErro.Parameter.Name
+		}
+
+		if requestErro.Err != nil {
+			var inner openapi3.MultiError
+			if errors.As(requestErro.Err, &inner) {
+				fields := fieldErrors(inner)
+				for index := range fields {
+					fields[index].In = in
+					if name != "" {
+						fields[index].Field = name
+					}
+				}
+				return fields
+			}
+		}
+
+		field := schemaField(requestErro.Err)
+		if name != "" {
+			field = name
+		}
+
+		message := requestErro.Reason
+		if requestErro.Err != nil {
+			message = schemaReason(requestErro.Err)
+		}
+
+		return []responses.FieldError{{In: in, Field: field, Message: message}}
+	}
+
+	var responseErro *openapi3filter.ResponseError
+	if errors.As(erro, &responseErro) {
+		var inner openapi3.MultiError
+		if errors.As(responseErro.Err, &inner) {
+			fields := fieldErrors(inner)
+			for index := range fields {
+				fields[index].In = "response"
+			}
+			return fields
+		}
+
+		message := responseErro.Reason
+		if responseErro.Err != nil {
+			message = schemaReason(responseErro.Err)
+		}
+
+		return []responses.FieldError{{In: "response", Field: schemaField(responseErro.Err), Message: message}}
+	}
+
+	return []responses.FieldError{{In: "body", Field: schemaField(erro), Message: schemaReason(erro)}}
+}
+
+// schemaField returns the JSON path of the value a schema error is about, e.g. "user.email"
+func schemaField(erro error) string {
+	var schemaErro *openapi3.SchemaError
+	if errors.As(erro, &schemaErro) {
+		return strings.Join(schemaErro.JSONPointer(), ".")
+	}
+
+	return ""
+}
+
+// schemaReason returns the reason of a schema error without the whole schema kin-openapi appends
+func schemaReason(erro error) string {
+	var schemaErro *openapi3.SchemaError
+	if errors.As(erro, &schemaErro) {
+		return schemaErro.Reason
+	}
+
+	return erro.Error()
+}
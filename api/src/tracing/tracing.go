@@ -0,0 +1,153 @@
+/*
+Copyright 2022 Danilo S. Lopes.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at:
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package tracing
+
+import (
+	"api/src/config"
+	"context"
+	"fmt"
+	"os"
+	"sort"
+	"sync"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/exporters/stdout/stdouttrace"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// Name of the tracer used by the API instrumentation
+const instrumentationName = "api"
+
+// Propagator reads and writes the W3C traceparent and tracestate headers
+var Propagator propagation.TextMapPropagator = propagation.TraceContext{}
+
+// ExporterFactory creates the exporter that receives the finished spans
+type ExporterFactory func(settings config.Tracing) (sdktrace.SpanExporter, error)
+
+var (
+	exportersMutex sync.RWMutex
+	exporters      = map[string]ExporterFactory{
+		"stdout": stdoutExporter,
+		"file":   fileExporter,
+	}
+)
+
+// RegisterExporter makes an exporter selectable by TRACING_EXPORTER, e.g. an OTLP or Jaeger one
+func RegisterExporter(name string, factory ExporterFactory) {
+	exportersMutex.Lock()
+	defer exportersMutex.Unlock()
+
+	exporters[name] = factory
+}
+
+// Exporters returns the names accepted by TRACING_EXPORTER besides "none"
+func Exporters() []string {
+	exportersMutex.RLock()
+	defer exportersMutex.RUnlock()
+
+	var names []string
+	for name := range exporters {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	return names
+}
+
+// Tracing owns the tracer provider of one API instance
+type Tracing struct {
+	provider *sdktrace.TracerProvider
+}
+
+// New creates the tracer provider sending the sampled spans to the configured exporter.
+// With the "none" exporter the spans are still created, so the trace IDs reach the logs and the downstream services.
+func New(settings config.Tracing) (*Tracing, error) {
+	options := []sdktrace.TracerProviderOption{
+		sdktrace.WithSampler(sdktrace.ParentBased(sdktrace.TraceIDRatioBased(settings.SampleRatio))),
+		sdktrace.WithResource(resource.NewSchemaless(
+			attribute.String("service.name", settings.ServiceName),
+		)),
+	}
+
+	if settings.Exporter != "none" {
+		exportersMutex.RLock()
+		factory, exists := exporters[settings.Exporter]
+		exportersMutex.RUnlock()
+
+		if !exists {
+			return nil, fmt.Errorf("unknown tracing exporter %q", settings.Exporter)
+		}
+
+		exporter, erro := factory(settings)
+		if erro != nil {
+			return nil, erro
+		}
+
+		options = append(options, sdktrace.WithBatcher(exporter))
+	}
+
+	return &Tracing{sdktrace.NewTracerProvider(options...)}, nil
+}
+
+// Tracer returns the tracer used to instrument the API
+func (tracing *Tracing) Tracer() trace.Tracer {
+	return tracing.provider.Tracer(instrumentationName)
+}
+
+// Shutdown exports the buffered spans and releases the exporter
+func (tracing *Tracing) Shutdown(ctx context.Context) error {
+	return tracing.provider.Shutdown(ctx)
+}
+
+// stdoutExporter writes the spans as JSON into the standard output
+func stdoutExporter(settings config.Tracing) (sdktrace.SpanExporter, error) {
+	return stdouttrace.New(stdouttrace.WithPrettyPrint())
+}
+
+// fileExporter appends the spans as JSON, one per line, into TRACING_FILE
+func fileExporter(settings config.Tracing) (sdktrace.SpanExporter, error) {
+	file, erro := os.OpenFile(settings.File, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0o644)
+	if erro != nil {
+		return nil, erro
+	}
+
+	exporter, erro := stdouttrace.New(stdouttrace.WithWriter(file))
+	if erro != nil {
+		file.Close()
+		return nil, erro
+	}
+
+	return closingExporter{exporter, file}, nil
+}
+
+// closingExporter closes the file the spans are written into once the exporter is shut down
+type closingExporter struct {
+	sdktrace.SpanExporter
+	file *os.File
+}
+
+func (exporter closingExporter) Shutdown(ctx context.Context) error {
+	erro := exporter.SpanExporter.Shutdown(ctx)
+	if closeErro := exporter.file.Close(); erro == nil {
+		erro = closeErro
+	}
+
+	return erro
+}